@@ -1,10 +1,13 @@
 package api
 
 import (
+	"context"
+	"github.com/garugaru/knowledge/server/auth"
 	"github.com/garugaru/knowledge/server/data"
 	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	muxprom "gitlab.com/msvechla/mux-prometheus/pkg/middleware"
+	"net"
 	"net/http"
 	"time"
 )
@@ -12,6 +15,16 @@ import (
 const (
 	DefaultServeAddr     = "0.0.0.0:8000"
 	DefaultServerTimeout = 15 * time.Second
+	// DefaultMaxUploadSize is the maximum size of the uploaded contents when none is configured
+	DefaultMaxUploadSize = 64 << 20
+	// DefaultExportTimeout is the time an export can take when none is configured
+	DefaultExportTimeout = time.Hour
+	// DefaultUploadTimeout is the time a content upload can take when none is configured
+	DefaultUploadTimeout = time.Hour
+	// DefaultImportTimeout is the time a bulk insert can take when none is configured
+	DefaultImportTimeout = time.Hour
+	// DefaultStreamTimeout is the time an event stream lasts when none is configured
+	DefaultStreamTimeout = time.Hour
 )
 
 type ServeOpts struct {
@@ -21,6 +34,27 @@ type ServeOpts struct {
 
 type Config struct {
 	EnableMetrics bool
+	// Authenticator protects the catalog routes, they are open when nil
+	Authenticator auth.Authenticator
+	// DefaultRole is assigned to the users signing in for the first time
+	DefaultRole data.Role
+	// Admins are the subjects always granted the admin role
+	Admins []string
+	// MaxUploadSize is the maximum size in bytes of the uploaded contents and
+	// of the atomic bulk inserts
+	MaxUploadSize int64
+	// UploadTimeout is the time a content upload can take, replacing the
+	// server read and write timeouts for the content route
+	UploadTimeout time.Duration
+	// ExportTimeout is the time an export can take, replacing the server
+	// write timeout for the export route
+	ExportTimeout time.Duration
+	// ImportTimeout is the time a bulk insert can take, replacing the server
+	// read and write timeouts for the bulk route
+	ImportTimeout time.Duration
+	// StreamTimeout is the time an event stream lasts before the client has
+	// to resume it, the stream is not bound to the server write timeout
+	StreamTimeout time.Duration
 }
 
 type Api struct {
@@ -29,17 +63,44 @@ type Api struct {
 }
 
 func New(config Config, catalog data.Catalog) *Api {
+	if config.MaxUploadSize <= 0 {
+		config.MaxUploadSize = DefaultMaxUploadSize
+	}
+	if config.ExportTimeout <= 0 {
+		config.ExportTimeout = DefaultExportTimeout
+	}
+	if config.UploadTimeout <= 0 {
+		config.UploadTimeout = DefaultUploadTimeout
+	}
+	if config.ImportTimeout <= 0 {
+		config.ImportTimeout = DefaultImportTimeout
+	}
+	if config.StreamTimeout <= 0 {
+		config.StreamTimeout = DefaultStreamTimeout
+	}
 	return &Api{catalog: catalog, config: config}
 }
 
 func (a Api) router() *mux.Router {
 	router := mux.NewRouter()
 
-	router.PathPrefix("/catalog").Handler(a.catalogRouter())
+	router.PathPrefix("/catalog").Handler(a.authenticate(a.catalogRouter()))
+	router.PathPrefix("/admin").Handler(a.authenticate(a.adminRouter()))
 	router.HandleFunc("/healthz", a.healthz).Methods(http.MethodGet)
 
 	if a.config.EnableMetrics {
-		router.Use(muxprom.NewDefaultInstrumentation().Middleware)
+		instrument := muxprom.NewDefaultInstrumentation().Middleware
+		router.Use(func(next http.Handler) http.Handler {
+			instrumented := instrument(next)
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// the instrumented writer cannot flush the event stream
+				if r.URL.Path == eventsPath {
+					next.ServeHTTP(w, r)
+					return
+				}
+				instrumented.ServeHTTP(w, r)
+			})
+		})
 		router.Path("/metrics").Handler(promhttp.Handler())
 	}
 
@@ -60,5 +121,30 @@ func (a Api) Server(opts ServeOpts) *http.Server {
 		Addr:         opts.Addr,
 		WriteTimeout: opts.Timeout,
 		ReadTimeout:  opts.Timeout,
+		ConnContext: func(ctx context.Context, conn net.Conn) context.Context {
+			return context.WithValue(ctx, connContextKey{}, conn)
+		},
+	}
+}
+
+type connContextKey struct{}
+
+// extendWriteDeadline replaces the server write timeout of the request, the
+// server sets it again for the next request of the connection.
+func extendWriteDeadline(r *http.Request, timeout time.Duration) error {
+	conn, ok := r.Context().Value(connContextKey{}).(net.Conn)
+	if !ok {
+		return nil
+	}
+	return conn.SetWriteDeadline(time.Now().Add(timeout))
+}
+
+// extendReadDeadline replaces the server read timeout of the request body,
+// the server sets it again for the next request of the connection.
+func extendReadDeadline(r *http.Request, timeout time.Duration) error {
+	conn, ok := r.Context().Value(connContextKey{}).(net.Conn)
+	if !ok {
+		return nil
 	}
+	return conn.SetReadDeadline(time.Now().Add(timeout))
 }
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"github.com/garugaru/knowledge/server/auth"
+	"github.com/garugaru/knowledge/server/data"
+	"net/http"
+)
+
+// authenticate requires an authenticated principal for every request to next,
+// reads need the read scope and every other method the write scope.
+func (a Api) authenticate(next http.Handler) http.Handler {
+	if a.config.Authenticator == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := a.config.Authenticator.Authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="knowledge"`)
+			httpErr(w, err, http.StatusUnauthorized)
+			return
+		}
+
+		scope := auth.ScopeWrite
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			scope = auth.ScopeRead
+		}
+
+		if !principal.HasScope(scope) {
+			httpErr(w, fmt.Errorf("the %s scope is required", scope), http.StatusForbidden)
+			return
+		}
+
+		if len(principal.Subject) == 0 {
+			httpErr(w, errors.New("credentials do not identify a subject"), http.StatusUnauthorized)
+			return
+		}
+
+		user, err := a.catalog.ResolveUser(r.Context(), data.ResolveUserRequest{
+			Subject: principal.Subject,
+			Role:    a.config.DefaultRole,
+		})
+		if err != nil {
+			httpErr(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		for _, admin := range a.config.Admins {
+			if admin == principal.Subject {
+				user.Role = data.RoleAdmin
+			}
+		}
+
+		ctx := auth.ContextWithPrincipal(r.Context(), principal)
+		next.ServeHTTP(w, r.WithContext(contextWithUser(ctx, user)))
+	})
+}
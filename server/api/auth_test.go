@@ -0,0 +1,76 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/garugaru/knowledge/server/auth"
+	"github.com/garugaru/knowledge/server/data"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAPI_Authentication(t *testing.T) {
+	var principal auth.Principal
+	catalog := mockCatalog{
+		getDocument: func(ctx context.Context, request data.GetDocumentRequest) (data.Document, error) {
+			principal, _ = auth.PrincipalFromContext(ctx)
+			return data.Document{}, nil
+		},
+		deleteDocument: func(ctx context.Context, request data.DeleteDocumentRequest) error {
+			return nil
+		},
+		resolveUser: func(ctx context.Context, request data.ResolveUserRequest) (data.User, error) {
+			return data.User{Subject: request.Subject, Role: data.Role(request.Subject)}, nil
+		},
+	}
+
+	api := New(Config{
+		EnableMetrics: true,
+		Authenticator: auth.NewStaticTokens(
+			auth.StaticToken{Token: "editor-token", Subject: "editor", Scopes: []string{auth.ScopeWrite}},
+			auth.StaticToken{Token: "reader-token", Subject: "reader", Scopes: []string{auth.ScopeRead}},
+		),
+	}, catalog)
+	router := api.router()
+
+	request := func(method string, target string, token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, nil)
+		if len(token) != 0 {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, r)
+		return recorder
+	}
+
+	r := request(http.MethodGet, "/healthz", "")
+	require.Equal(t, http.StatusOK, r.Code)
+
+	r = request(http.MethodGet, "/metrics", "")
+	require.Equal(t, http.StatusOK, r.Code)
+
+	r = request(http.MethodGet, "/catalog/documents/1", "")
+	require.Equal(t, http.StatusUnauthorized, r.Code)
+	require.True(t, strings.HasPrefix(r.Header().Get("WWW-Authenticate"), "Bearer"))
+	var apiError Error
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&apiError))
+	require.Equal(t, auth.ErrNoCredentials.Error(), apiError.Message)
+
+	r = request(http.MethodGet, "/catalog/documents/1", "wrong-token")
+	require.Equal(t, http.StatusUnauthorized, r.Code)
+
+	r = request(http.MethodGet, "/catalog/documents/1", "reader-token")
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Equal(t, "reader", principal.Subject)
+
+	r = request(http.MethodDelete, "/catalog/documents/1", "reader-token")
+	require.Equal(t, http.StatusForbidden, r.Code)
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&apiError))
+	require.Equal(t, "the catalog:write scope is required", apiError.Message)
+
+	r = request(http.MethodDelete, "/catalog/documents/1", "editor-token")
+	require.Equal(t, http.StatusOK, r.Code)
+}
@@ -0,0 +1,222 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/garugaru/knowledge/server/data"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+const (
+	defaultBulkBatchSize = 100
+	// maxBulkLineSize bounds the size of a single document of a bulk insert
+	maxBulkLineSize = 4 << 20
+	// maxAtomicBulkDocuments bounds the documents of an atomic bulk insert,
+	// they are held in memory until the body is read
+	maxAtomicBulkDocuments = 10000
+)
+
+// BulkInsertResponse reports the result of each line of a bulk insert.
+type BulkInsertResponse struct {
+	Inserted int                `json:"inserted"`
+	Failed   int                `json:"failed"`
+	Results  []BulkInsertResult `json:"results"`
+	// RolledBack is set when an atomic insert failed, no document was inserted
+	RolledBack bool `json:"rolled_back,omitempty"`
+	// Error stopped the insert, the lines following the reported ones were
+	// not inserted
+	Error string `json:"error,omitempty"`
+}
+
+type BulkInsertResult struct {
+	Line  int    `json:"line"`
+	ID    int    `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// catalogInsertDocuments reads a document per line, inserting the documents
+// in batches while the body is streamed. Atomic inserts read the whole body,
+// bounded by the maximum upload size, before inserting the documents in a
+// single transaction.
+func (a Api) catalogInsertDocuments(w http.ResponseWriter, r *http.Request) {
+	if !requireRole(w, r, data.RoleEditor) {
+		return
+	}
+
+	params := r.URL.Query()
+
+	var atomic bool
+	if atomicParam := params.Get("atomic"); len(atomicParam) != 0 {
+		var err error
+		atomic, err = strconv.ParseBool(atomicParam)
+		if err != nil {
+			httpErr(w, fmt.Errorf("invalid 'atomic' parameter value: %s", atomicParam), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var upsert bool
+	if upsertParam := params.Get("upsert"); len(upsertParam) != 0 {
+		var err error
+		upsert, err = strconv.ParseBool(upsertParam)
+		if err != nil {
+			httpErr(w, fmt.Errorf("invalid 'upsert' parameter value: %s", upsertParam), http.StatusBadRequest)
+			return
+		}
+	}
+
+	batchSize := defaultBulkBatchSize
+	if batchSizeParam := params.Get("batch_size"); len(batchSizeParam) != 0 {
+		var err error
+		batchSize, err = strconv.Atoi(batchSizeParam)
+		if err != nil || batchSize <= 0 {
+			httpErr(w, fmt.Errorf("invalid 'batch_size' parameter value: %s", batchSizeParam), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// the body is streamed while the documents are inserted
+	if err := extendReadDeadline(r, a.config.ImportTimeout); err != nil {
+		httpErr(w, err, http.StatusInternalServerError)
+		return
+	}
+	if err := extendWriteDeadline(r, a.config.ImportTimeout); err != nil {
+		httpErr(w, err, http.StatusInternalServerError)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), a.config.ImportTimeout)
+	defer cancel()
+
+	if atomic {
+		if r.ContentLength > a.config.MaxUploadSize {
+			httpErr(w, fmt.Errorf("atomic inserts exceed the maximum size of %d bytes", a.config.MaxUploadSize), http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, a.config.MaxUploadSize)
+	}
+
+	var ownerID uint
+	if user, ok := requestUser(r); ok {
+		ownerID = user.ID
+	}
+
+	var (
+		response BulkInsertResponse
+		pending  []data.Document
+		lines    []int
+	)
+
+	insert := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+
+		inserted, err := a.catalog.InsertDocuments(ctx, data.InsertDocumentsRequest{
+			Documents: pending,
+			Atomic:    atomic,
+			BatchSize: batchSize,
+			Upsert:    upsert,
+		})
+
+		// the documents of a failed batch are reported with its error
+		for i, line := range lines {
+			result := BulkInsertResult{Line: line}
+			if i < len(inserted.Results) {
+				result.ID, result.Error = inserted.Results[i].ID, inserted.Results[i].Error
+			}
+			if err != nil && result.ID == 0 && len(result.Error) == 0 {
+				result.Error = err.Error()
+			}
+			response.Results = append(response.Results, result)
+		}
+		response.RolledBack = inserted.RolledBack || (err != nil && atomic)
+		pending, lines = nil, nil
+		return err
+	}
+
+	// report writes the results of the lines read so far, err stops the insert
+	report := func(err error) {
+		// the invalid lines are reported before the batch they belong to is inserted
+		sort.SliceStable(response.Results, func(i, j int) bool {
+			return response.Results[i].Line < response.Results[j].Line
+		})
+		for _, result := range response.Results {
+			if len(result.Error) != 0 {
+				response.Failed++
+			} else if result.ID != 0 {
+				response.Inserted++
+			}
+		}
+
+		if err != nil {
+			response.Error = err.Error()
+		}
+		if err != nil || response.RolledBack {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			httpErr(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 64*1024), maxBulkLineSize)
+
+	invalid := false
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		var document data.Document
+		if err := json.Unmarshal(text, &document); err != nil {
+			response.Results = append(response.Results, BulkInsertResult{Line: line, Error: err.Error()})
+			invalid = true
+			continue
+		}
+
+		if atomic && len(pending) >= maxAtomicBulkDocuments {
+			httpErr(w, fmt.Errorf("atomic inserts are limited to %d documents", maxAtomicBulkDocuments), http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		document.OwnerID = ownerID
+		pending = append(pending, document)
+		lines = append(lines, line)
+
+		if !atomic && len(pending) >= batchSize {
+			if err := insert(); err != nil {
+				report(err)
+				return
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		// the documents read are not inserted, atomic inserts insert none
+		response.RolledBack = atomic
+		report(err)
+		return
+	}
+
+	if atomic && invalid {
+		// none of the documents are inserted, the valid lines are reported too
+		for _, line := range lines {
+			response.Results = append(response.Results, BulkInsertResult{Line: line})
+		}
+		response.RolledBack = true
+	} else if err := insert(); err != nil {
+		report(err)
+		return
+	}
+
+	report(nil)
+}
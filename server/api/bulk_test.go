@@ -0,0 +1,172 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/garugaru/knowledge/server/data"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCatalogApi_InsertDocuments(t *testing.T) {
+	var received []data.InsertDocumentsRequest
+	catalog := mockCatalog{
+		insertDocuments: func(ctx context.Context, request data.InsertDocumentsRequest) (data.InsertDocumentsResponse, error) {
+			received = append(received, request)
+
+			var response data.InsertDocumentsResponse
+			for i, document := range request.Documents {
+				if document.Title == nil {
+					response.Results = append(response.Results, data.InsertDocumentResult{Error: "title is required"})
+					continue
+				}
+				response.Results = append(response.Results, data.InsertDocumentResult{ID: i + 1})
+			}
+			return response, nil
+		},
+	}
+
+	api := New(Config{}, catalog)
+	router := api.catalogRouter()
+
+	body := strings.Join([]string{
+		`{"title": "a", "uri": "a"}`,
+		`{"title": "b", "uri": "b"}`,
+		``,
+		`{"title": `,
+		`{"uri": "c"}`,
+	}, "\n")
+
+	r := httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodPost, "/catalog/documents:bulk?batch_size=2", strings.NewReader(body)))
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Len(t, received, 2, "the documents must be inserted in batches")
+	require.Len(t, received[0].Documents, 2)
+	require.Len(t, received[1].Documents, 1)
+
+	var response BulkInsertResponse
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&response))
+	require.Equal(t, 2, response.Inserted)
+	require.Equal(t, 2, response.Failed)
+	require.Len(t, response.Results, 4)
+	require.Equal(t, BulkInsertResult{Line: 1, ID: 1}, response.Results[0])
+	require.Equal(t, BulkInsertResult{Line: 2, ID: 2}, response.Results[1])
+	require.Equal(t, 4, response.Results[2].Line)
+	require.NotEmpty(t, response.Results[2].Error)
+	require.Equal(t, BulkInsertResult{Line: 5, Error: "title is required"}, response.Results[3])
+
+	received = nil
+	r = httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodPost, "/catalog/documents:bulk?atomic=true", strings.NewReader(body)))
+	require.Equal(t, http.StatusBadRequest, r.Code)
+	require.Empty(t, received, "atomic inserts with invalid lines must not insert any document")
+
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&response))
+	require.True(t, response.RolledBack)
+	require.Equal(t, 0, response.Inserted)
+
+	catalog.insertDocuments = func(ctx context.Context, request data.InsertDocumentsRequest) (data.InsertDocumentsResponse, error) {
+		return data.InsertDocumentsResponse{}, errors.New("database is unavailable")
+	}
+	router = New(Config{}, catalog).catalogRouter()
+
+	r = httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodPost, "/catalog/documents:bulk", strings.NewReader(`{"title": "a", "uri": "a"}`)))
+	require.Equal(t, http.StatusBadRequest, r.Code)
+}
+
+func TestCatalogApi_InsertDocuments_Errors(t *testing.T) {
+	catalog := mockCatalog{
+		insertDocuments: func(ctx context.Context, request data.InsertDocumentsRequest) (data.InsertDocumentsResponse, error) {
+			if *request.Documents[0].Title == "c" {
+				return data.InsertDocumentsResponse{Results: make([]data.InsertDocumentResult, len(request.Documents))}, errors.New("database is unavailable")
+			}
+
+			var response data.InsertDocumentsResponse
+			for i := range request.Documents {
+				response.Results = append(response.Results, data.InsertDocumentResult{ID: i + 1})
+			}
+			return response, nil
+		},
+	}
+
+	router := New(Config{MaxUploadSize: 64}, catalog).catalogRouter()
+
+	body := strings.Join([]string{
+		`{"title": "a", "uri": "a"}`,
+		`{"title": "b", "uri": "b"}`,
+		`{"title": "c", "uri": "c"}`,
+		`{"title": "d", "uri": "d"}`,
+		`{"title": "e", "uri": "e"}`,
+	}, "\n")
+
+	r := httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodPost, "/catalog/documents:bulk?batch_size=2", strings.NewReader(body)))
+	require.Equal(t, http.StatusBadRequest, r.Code)
+
+	var response BulkInsertResponse
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&response))
+	require.Equal(t, "database is unavailable", response.Error)
+	require.Equal(t, 2, response.Inserted, "the batches inserted before the error are reported")
+	require.Equal(t, 2, response.Failed)
+	require.Len(t, response.Results, 4)
+	require.Equal(t, BulkInsertResult{Line: 3, Error: "database is unavailable"}, response.Results[2])
+	require.False(t, response.RolledBack)
+
+	r = httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodPost, "/catalog/documents:bulk?atomic=true", strings.NewReader(body)))
+	require.Equal(t, http.StatusRequestEntityTooLarge, r.Code)
+
+	request := httptest.NewRequest(http.MethodPost, "/catalog/documents:bulk?atomic=true", strings.NewReader(body))
+	request.ContentLength = -1
+	r = httptest.NewRecorder()
+	router.ServeHTTP(r, request)
+	require.Equal(t, http.StatusBadRequest, r.Code)
+
+	response = BulkInsertResponse{}
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&response))
+	require.True(t, response.RolledBack, "atomic inserts exceeding the size are not inserted")
+	require.NotEmpty(t, response.Error)
+	require.Zero(t, response.Inserted)
+}
+
+func TestCatalogApi_InsertDocuments_SlowBody(t *testing.T) {
+	catalog := mockCatalog{
+		insertDocuments: func(ctx context.Context, request data.InsertDocumentsRequest) (data.InsertDocumentsResponse, error) {
+			response := data.InsertDocumentsResponse{}
+			for i := range request.Documents {
+				response.Results = append(response.Results, data.InsertDocumentResult{ID: i + 1})
+			}
+			return response, nil
+		},
+	}
+
+	// the body is streamed for longer than the server timeouts
+	server := httptest.NewUnstartedServer(nil)
+	server.Config = New(Config{}, catalog).Server(ServeOpts{Timeout: 100 * time.Millisecond})
+	server.Start()
+	defer server.Close()
+
+	body, writer := io.Pipe()
+	go func() {
+		_, _ = io.WriteString(writer, `{"title": "first"}`+"\n")
+		time.Sleep(300 * time.Millisecond)
+		_, _ = io.WriteString(writer, `{"title": "second"}`+"\n")
+		_ = writer.Close()
+	}()
+
+	response, err := http.Post(server.URL+"/catalog/documents:bulk", "application/x-ndjson", body)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	require.Equal(t, http.StatusOK, response.StatusCode)
+
+	var inserted BulkInsertResponse
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&inserted))
+	require.Equal(t, 2, inserted.Inserted)
+}
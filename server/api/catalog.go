@@ -7,26 +7,154 @@ import (
 	"github.com/garugaru/knowledge/server/data"
 	"github.com/gorilla/mux"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 )
 
 func (a Api) catalogRouter() *mux.Router {
 	router := mux.NewRouter()
+	router.Path("/catalog/me").Methods(http.MethodGet).HandlerFunc(a.catalogCurrentUser)
 	router.Path("/catalog/documents").Methods(http.MethodPost).HandlerFunc(a.catalogInsertDocument)
 	router.Path("/catalog/documents").Methods(http.MethodGet).HandlerFunc(a.catalogListDocument)
+	router.Path("/catalog/documents:bulk").Methods(http.MethodPost).HandlerFunc(a.catalogInsertDocuments)
+	router.Path("/catalog/export").Methods(http.MethodGet).HandlerFunc(a.catalogExport)
+	router.Path("/catalog/duplicates").Methods(http.MethodGet).HandlerFunc(a.catalogListDuplicates)
+	router.Path("/catalog/trash").Methods(http.MethodGet).HandlerFunc(a.catalogListTrash)
+	router.Path("/catalog/trash/{id:[0-9]+}").Methods(http.MethodDelete).HandlerFunc(a.catalogPurgeDocument)
+	router.Path("/catalog/trash/{id:[0-9]+}/restore").Methods(http.MethodPost).HandlerFunc(a.catalogRestoreDocument)
+	router.Path(eventsPath).Methods(http.MethodGet).HandlerFunc(a.catalogStreamEvents)
 	router.Path("/catalog/documents/{id:[0-9]+}").Methods(http.MethodGet).HandlerFunc(a.catalogGetDocument)
+	router.Path("/catalog/documents/{id:[0-9]+}").Methods(http.MethodPut).HandlerFunc(a.catalogReplaceDocument)
+	router.Path("/catalog/documents/{id:[0-9]+}").Methods(http.MethodPatch).HandlerFunc(a.catalogPatchDocument)
+	router.Path("/catalog/documents/{id:[0-9]+}").Methods(http.MethodDelete).HandlerFunc(a.catalogDeleteDocument)
+	router.Path("/catalog/documents/{id:[0-9]+}/refresh").Methods(http.MethodPost).HandlerFunc(a.catalogRefreshDocument)
+	router.Path("/catalog/documents/{id:[0-9]+}/content").Methods(http.MethodPost).HandlerFunc(a.catalogPutDocumentContent)
+	router.Path("/catalog/documents/{id:[0-9]+}/content").Methods(http.MethodGet).HandlerFunc(a.catalogGetDocumentContent)
+	router.Path("/catalog/documents/{id:[0-9]+}/revisions").Methods(http.MethodGet).HandlerFunc(a.catalogListRevisions)
+	router.Path("/catalog/documents/{id:[0-9]+}/revisions/diff").Methods(http.MethodGet).HandlerFunc(a.catalogDiffRevisions)
+	router.Path("/catalog/documents/{id:[0-9]+}/revisions/{revision:[0-9]+}/restore").Methods(http.MethodPost).HandlerFunc(a.catalogRestoreRevision)
+	router.Path("/catalog/collections").Methods(http.MethodPost).HandlerFunc(a.catalogInsertCollection)
+	router.Path("/catalog/collections").Methods(http.MethodGet).HandlerFunc(a.catalogListCollections)
+	router.Path("/catalog/collections/{id:[0-9]+}").Methods(http.MethodGet).HandlerFunc(a.catalogGetCollection)
+	router.Path("/catalog/collections/{id:[0-9]+}").Methods(http.MethodPut).HandlerFunc(a.catalogReplaceCollection)
+	router.Path("/catalog/collections/{id:[0-9]+}").Methods(http.MethodPatch).HandlerFunc(a.catalogPatchCollection)
+	router.Path("/catalog/collections/{id:[0-9]+}").Methods(http.MethodDelete).HandlerFunc(a.catalogDeleteCollection)
+	router.Path("/catalog/tags").Methods(http.MethodGet).HandlerFunc(a.catalogListTags)
+	router.Path("/catalog/tags").Methods(http.MethodPost).HandlerFunc(a.catalogInsertTag)
+	router.Path("/catalog/tags/{id:[0-9]+}").Methods(http.MethodPut, http.MethodPatch).HandlerFunc(a.catalogUpdateTag)
+	router.Path("/catalog/tags/{id:[0-9]+}").Methods(http.MethodDelete).HandlerFunc(a.catalogDeleteTag)
+	router.Path("/catalog/tags/{id:[0-9]+}/merge").Methods(http.MethodPost).HandlerFunc(a.catalogMergeTags)
+	router.Path("/catalog/tags/{id:[0-9]+}/move").Methods(http.MethodPost).HandlerFunc(a.catalogMoveTag)
+	router.Path("/catalog/tags/tree").Methods(http.MethodGet).HandlerFunc(a.catalogGetTagTree)
+	router.Path("/catalog/authors").Methods(http.MethodGet).HandlerFunc(a.catalogListAuthors)
+	router.Path("/catalog/authors").Methods(http.MethodPost).HandlerFunc(a.catalogInsertAuthor)
+	router.Path("/catalog/authors/{id:[0-9]+}").Methods(http.MethodPut, http.MethodPatch).HandlerFunc(a.catalogUpdateAuthor)
+	router.Path("/catalog/authors/{id:[0-9]+}").Methods(http.MethodDelete).HandlerFunc(a.catalogDeleteAuthor)
+	router.Path("/catalog/authors/{id:[0-9]+}/merge").Methods(http.MethodPost).HandlerFunc(a.catalogMergeAuthors)
+	router.Path("/catalog/kinds").Methods(http.MethodGet).HandlerFunc(a.catalogListKinds)
+	router.Path("/catalog/kinds").Methods(http.MethodPost).HandlerFunc(a.catalogInsertKind)
+	router.Path("/catalog/kinds/{id:[0-9]+}").Methods(http.MethodPut, http.MethodPatch).HandlerFunc(a.catalogUpdateKind)
+	router.Path("/catalog/kinds/{id:[0-9]+}").Methods(http.MethodDelete).HandlerFunc(a.catalogDeleteKind)
 	return router
 }
 
-func (a Api) catalogGetDocument(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	id, present := params["id"]
+func documentIDParam(r *http.Request) (int, error) {
+	id, present := mux.Vars(r)["id"]
 	if !present {
-		httpErr(w, errors.New("'id' parameter must be present"), http.StatusBadRequest)
-		return
+		return 0, errors.New("'id' parameter must be present")
+	}
+
+	return strconv.Atoi(id)
+}
+
+// entityIDParam reads the 'id' path parameter of the entities with unsigned ids.
+func entityIDParam(r *http.Request) (uint, error) {
+	id, present := mux.Vars(r)["id"]
+	if !present {
+		return 0, errors.New("'id' parameter must be present")
+	}
+
+	entityID, err := strconv.ParseUint(id, 10, 64)
+	return uint(entityID), err
+}
+
+// maxPageSize bounds the elements listed by a single request.
+const maxPageSize = 1000
+
+// paginationParams reads the 'page' and 'page_size' query parameters.
+func paginationParams(params url.Values) (data.PaginationRequest, error) {
+	var pagination = data.PaginationRequest{
+		Page:     1,
+		PageSize: 100,
+	}
+
+	pageParam, present := params["page"]
+	if present {
+		page, err := strconv.Atoi(pageParam[0])
+		if err != nil {
+			return pagination, fmt.Errorf("invalid 'page' parameter value: %s", pageParam)
+		}
+		if page < 1 {
+			return pagination, fmt.Errorf("'page' parameter must be at least 1: %d", page)
+		}
+		pagination.Page = page
+	}
+
+	pageSizeParam, present := params["page_size"]
+	if present {
+		pageSize, err := strconv.Atoi(pageSizeParam[0])
+		if err != nil {
+			return pagination, fmt.Errorf("invalid 'page_size' parameter value: %s", pageSizeParam)
+		}
+		if pageSize < 1 || pageSize > maxPageSize {
+			return pagination, fmt.Errorf("'page_size' parameter must be between 1 and %d: %d", maxPageSize, pageSize)
+		}
+		pagination.PageSize = pageSize
 	}
 
-	documentID, err := strconv.Atoi(id)
+	return pagination, nil
+}
+
+// sortParams reads the comma separated sort keys like -createTime,title, where
+// a leading - sorts in descending order.
+func sortParams(values []string, searching bool) ([]data.Sort, error) {
+	var sort []data.Sort
+	for _, value := range values {
+		for _, key := range strings.Split(value, ",") {
+			key = strings.TrimSpace(key)
+			descending := strings.HasPrefix(key, "-")
+			field := data.SortField(strings.TrimPrefix(key, "-"))
+
+			if !field.Valid() {
+				return nil, fmt.Errorf("invalid 'sort' parameter value: %q, expected one of title, createTime, updatedAt, kind or relevance", key)
+			}
+			if field == data.SortRelevance && !searching {
+				return nil, errors.New("invalid 'sort' parameter value: sorting by relevance requires the 'q' parameter")
+			}
+			sort = append(sort, data.Sort{Field: field, Descending: descending})
+		}
+	}
+	return sort, nil
+}
+
+// facetParams reads the comma separated facets like tags,kind.
+func facetParams(values []string) ([]data.Facet, error) {
+	var facets []data.Facet
+	for _, value := range values {
+		for _, name := range strings.Split(value, ",") {
+			facet := data.Facet(strings.TrimSpace(name))
+			if !facet.Valid() {
+				return nil, fmt.Errorf("invalid 'facets' parameter value: %q, expected tags, kind or authors", name)
+			}
+			facets = append(facets, facet)
+		}
+	}
+	return facets, nil
+}
+
+func (a Api) catalogGetDocument(w http.ResponseWriter, r *http.Request) {
+	documentID, err := documentIDParam(r)
 	if err != nil {
 		httpErr(w, err, http.StatusBadRequest)
 		return
@@ -52,41 +180,107 @@ func (a Api) catalogGetDocument(w http.ResponseWriter, r *http.Request) {
 func (a Api) catalogListDocument(w http.ResponseWriter, r *http.Request) {
 	params := r.URL.Query()
 
-	var request = data.ListDocumentsRequest{
-		Pagination: data.PaginationRequest{
-			Page:     1,
-			PageSize: 100,
-		},
+	pagination, err := paginationParams(params)
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
 	}
 
+	var request = data.ListDocumentsRequest{Pagination: pagination}
+
 	title, present := params["title"]
 	if present && len(title) > 0 {
 		request.Title = title[0]
 	}
 
+	q, present := params["q"]
+	if present && len(q) > 0 {
+		request.Query = q[0]
+	}
+
 	tags, present := params["tags"]
 	if present {
 		request.Tags = tags
 	}
 
-	pageParam, present := params["page"]
+	request.TagsAll = params["tags_all"]
+	request.TagsAny = params["tags_any"]
+	request.TagsNone = params["tags_none"]
+
+	brokenParam, present := params["broken"]
+	if present && len(brokenParam) > 0 {
+		broken, err := strconv.ParseBool(brokenParam[0])
+		if err != nil {
+			httpErr(w, fmt.Errorf("invalid 'broken' parameter value: %s", brokenParam), http.StatusBadRequest)
+			return
+		}
+		request.Broken = &broken
+	}
+
+	collectionParam, present := params["collection"]
+	if present && len(collectionParam) > 0 {
+		collectionID, err := strconv.ParseUint(collectionParam[0], 10, 64)
+		if err != nil {
+			httpErr(w, fmt.Errorf("invalid 'collection' parameter value: %s", collectionParam), http.StatusBadRequest)
+			return
+		}
+
+		// the documents of a collection are only listed to the users who can see it
+		if viewer := collectionViewer(r); viewer != 0 {
+			_, err := a.catalog.GetCollection(r.Context(), data.GetCollectionRequest{CollectionID: uint(collectionID), UserID: viewer})
+			if err != nil {
+				httpErr(w, err, http.StatusBadRequest)
+				return
+			}
+		}
+		request.Collection = uint(collectionID)
+	}
+
+	sortParam, present := params["sort"]
 	if present {
-		page, err := strconv.Atoi(pageParam[0])
+		sort, err := sortParams(sortParam, len(request.Query) != 0)
 		if err != nil {
-			httpErr(w, fmt.Errorf("invalid 'page' parameter value: %s", pageParam), http.StatusBadRequest)
+			httpErr(w, err, http.StatusBadRequest)
 			return
 		}
-		request.Pagination.Page = page
+		request.Sort = sort
 	}
 
-	pageSizeParam, present := params["page_size"]
+	facetsParam, present := params["facets"]
 	if present {
-		pageSize, err := strconv.Atoi(pageSizeParam[0])
+		facets, err := facetParams(facetsParam)
 		if err != nil {
-			httpErr(w, fmt.Errorf("invalid 'page_size' parameter value: %s", pageParam), http.StatusBadRequest)
+			httpErr(w, err, http.StatusBadRequest)
 			return
 		}
-		request.Pagination.PageSize = pageSize
+		request.Facets = facets
+	}
+
+	filterParam, present := params["filter"]
+	if present && len(filterParam) > 0 && len(strings.TrimSpace(filterParam[0])) > 0 {
+		filter, err := data.ParseFilter(filterParam[0])
+		if err != nil {
+			httpErr(w, err, http.StatusBadRequest)
+			return
+		}
+		request.Filter = filter
+	}
+
+	cursorParam, present := params["cursor"]
+	if present && len(cursorParam) > 0 {
+		request.Pagination.Cursor = cursorParam[0]
+		// the documents are not counted again while following cursors unless asked
+		request.Pagination.SkipCount = true
+	}
+
+	countParam, present := params["count"]
+	if present && len(countParam) > 0 {
+		count, err := strconv.ParseBool(countParam[0])
+		if err != nil {
+			httpErr(w, fmt.Errorf("invalid 'count' parameter value: %s", countParam), http.StatusBadRequest)
+			return
+		}
+		request.Pagination.SkipCount = !count
 	}
 
 	document, err := a.catalog.ListDocuments(r.Context(), request)
@@ -105,6 +299,10 @@ func (a Api) catalogListDocument(w http.ResponseWriter, r *http.Request) {
 }
 
 func (a Api) catalogInsertDocument(w http.ResponseWriter, r *http.Request) {
+	if !requireRole(w, r, data.RoleEditor) {
+		return
+	}
+
 	var document data.Document
 
 	if err := json.NewDecoder(r.Body).Decode(&document); err != nil {
@@ -112,8 +310,133 @@ func (a Api) catalogInsertDocument(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	document.OwnerID = 0
+	if user, ok := requestUser(r); ok {
+		document.OwnerID = user.ID
+	}
+
+	var fetch bool
+	if fetchParam := r.URL.Query().Get("fetch"); len(fetchParam) != 0 {
+		var err error
+		fetch, err = strconv.ParseBool(fetchParam)
+		if err != nil {
+			httpErr(w, fmt.Errorf("invalid 'fetch' parameter value: %s", fetchParam), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var upsert bool
+	if upsertParam := r.URL.Query().Get("upsert"); len(upsertParam) != 0 {
+		var err error
+		upsert, err = strconv.ParseBool(upsertParam)
+		if err != nil {
+			httpErr(w, fmt.Errorf("invalid 'upsert' parameter value: %s", upsertParam), http.StatusBadRequest)
+			return
+		}
+	}
+
 	err := a.catalog.InsertDocument(r.Context(), data.InsertDocumentRequest{
 		Document: document,
+		Fetch:    fetch,
+		Upsert:   upsert,
+	})
+
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a Api) catalogReplaceDocument(w http.ResponseWriter, r *http.Request) {
+	a.catalogUpdateDocument(w, r, false)
+}
+
+func (a Api) catalogPatchDocument(w http.ResponseWriter, r *http.Request) {
+	a.catalogUpdateDocument(w, r, true)
+}
+
+func (a Api) catalogUpdateDocument(w http.ResponseWriter, r *http.Request, partial bool) {
+	documentID, err := documentIDParam(r)
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if !a.requireDocumentOwner(w, r, documentID) {
+		return
+	}
+
+	var document data.Document
+	if err := json.NewDecoder(r.Body).Decode(&document); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	updated, err := a.catalog.UpdateDocument(r.Context(), data.UpdateDocumentRequest{
+		DocumentID: documentID,
+		Document:   document,
+		Partial:    partial,
+		UserID:     requestUserID(r),
+	})
+
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(updated); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a Api) catalogRefreshDocument(w http.ResponseWriter, r *http.Request) {
+	documentID, err := documentIDParam(r)
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if !a.requireDocumentOwner(w, r, documentID) {
+		return
+	}
+
+	document, err := a.catalog.RefreshDocument(r.Context(), data.RefreshDocumentRequest{
+		DocumentID: documentID,
+		UserID:     requestUserID(r),
+	})
+
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(document); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a Api) catalogDeleteDocument(w http.ResponseWriter, r *http.Request) {
+	documentID, err := documentIDParam(r)
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if !a.requireDocumentOwner(w, r, documentID) {
+		return
+	}
+
+	err = a.catalog.DeleteDocument(r.Context(), data.DeleteDocumentRequest{
+		DocumentID: documentID,
+		UserID:     requestUserID(r),
 	})
 
 	if err != nil {
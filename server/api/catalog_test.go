@@ -10,6 +10,8 @@ import (
 	"github.com/stretchr/testify/require"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 )
 
@@ -47,6 +49,39 @@ func TestCatalogApi_InsertDocument(t *testing.T) {
 	require.Equal(t, request, inserted)
 }
 
+func TestCatalogApi_InsertDocument_Duplicate(t *testing.T) {
+	var upsert bool
+	catalog := mockCatalog{
+		insertDocument: func(ctx context.Context, request data.InsertDocumentRequest) error {
+			upsert = request.Upsert
+			if request.Upsert {
+				return nil
+			}
+			return &data.DuplicateDocumentError{DocumentID: 42, Uri: *request.Document.Uri}
+		},
+	}
+
+	router := New(Config{}, catalog).catalogRouter()
+	body := `{"title": "title", "uri": "https://example.com"}`
+
+	r := httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodPost, "/catalog/documents", strings.NewReader(body)))
+	require.Equal(t, http.StatusConflict, r.Code)
+
+	var response Error
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&response))
+	require.Equal(t, 42, response.DocumentID)
+
+	r = httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodPost, "/catalog/documents?upsert=true", strings.NewReader(body)))
+	require.Equal(t, http.StatusOK, r.Code)
+	require.True(t, upsert)
+
+	r = httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodPost, "/catalog/documents?upsert=maybe", strings.NewReader(body)))
+	require.Equal(t, http.StatusBadRequest, r.Code)
+}
+
 func TestCatalogApi_GetDocument(t *testing.T) {
 	var documentID = 1
 	var inserted = data.Document{
@@ -132,12 +167,393 @@ func TestCatalogApi_ListDocuments(t *testing.T) {
 		Page:          page,
 		Pages:         10,
 	}, response.Pagination)
+
+	for _, query := range []string{"page=0", "page=-1", "page_size=0", "page_size=-5", "page_size=a"} {
+		r = httptest.NewRecorder()
+		router.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/catalog/documents?"+query, nil))
+		require.Equal(t, http.StatusBadRequest, r.Code, query)
+	}
+
+	r = httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/catalog/documents?page_size=a", nil))
+	require.Contains(t, r.Body.String(), "'page_size' parameter value: [a]")
+}
+
+func TestCatalogApi_ListDocuments_Query(t *testing.T) {
+	var received data.ListDocumentsRequest
+	catalog := mockCatalog{
+		listDocuments: func(ctx context.Context, request data.ListDocumentsRequest) (data.ListDocumentsResponse, error) {
+			received = request
+			return data.ListDocumentsResponse{
+				Items: []data.Document{
+					{Title: strptr("Time, Clocks"), Snippet: "Time, <mark>Clocks</mark>"},
+				},
+			}, nil
+		},
+	}
+
+	api := New(Config{}, catalog)
+
+	router := api.catalogRouter()
+	r := httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/catalog/documents?q=lamport+clocks", nil))
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Equal(t, "lamport clocks", received.Query)
+
+	var response data.ListDocumentsResponse
+	err := json.NewDecoder(r.Body).Decode(&response)
+	require.NoError(t, err)
+	require.Equal(t, "Time, <mark>Clocks</mark>", response.Items[0].Snippet)
+}
+
+func TestCatalogApi_ListDocuments_Broken(t *testing.T) {
+	var received data.ListDocumentsRequest
+	catalog := mockCatalog{
+		listDocuments: func(ctx context.Context, request data.ListDocumentsRequest) (data.ListDocumentsResponse, error) {
+			received = request
+			return data.ListDocumentsResponse{}, nil
+		},
+	}
+
+	api := New(Config{}, catalog)
+
+	router := api.catalogRouter()
+	r := httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/catalog/documents?broken=true", nil))
+	require.Equal(t, http.StatusOK, r.Code)
+	require.NotNil(t, received.Broken)
+	require.True(t, *received.Broken)
+
+	r = httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/catalog/documents", nil))
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Nil(t, received.Broken)
+
+	r = httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/catalog/documents?broken=sometimes", nil))
+	require.Equal(t, http.StatusBadRequest, r.Code)
+}
+
+func TestCatalogApi_ListDocuments_TagSets(t *testing.T) {
+	var received data.ListDocumentsRequest
+	catalog := mockCatalog{
+		listDocuments: func(ctx context.Context, request data.ListDocumentsRequest) (data.ListDocumentsResponse, error) {
+			received = request
+			return data.ListDocumentsResponse{}, nil
+		},
+	}
+
+	api := New(Config{}, catalog)
+
+	router := api.catalogRouter()
+	r := httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/catalog/documents?tags_all=a&tags_all=b&tags_any=c&tags_none=d", nil))
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Equal(t, []string{"a", "b"}, received.TagsAll)
+	require.Equal(t, []string{"c"}, received.TagsAny)
+	require.Equal(t, []string{"d"}, received.TagsNone)
+}
+
+func TestCatalogApi_ListDocuments_Sort(t *testing.T) {
+	var received data.ListDocumentsRequest
+	catalog := mockCatalog{
+		listDocuments: func(ctx context.Context, request data.ListDocumentsRequest) (data.ListDocumentsResponse, error) {
+			received = request
+			return data.ListDocumentsResponse{}, nil
+		},
+	}
+
+	api := New(Config{}, catalog)
+
+	router := api.catalogRouter()
+	r := httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/catalog/documents?sort=-createTime,kind&sort=title", nil))
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Equal(t, []data.Sort{
+		{Field: data.SortCreateTime, Descending: true},
+		{Field: data.SortKind},
+		{Field: data.SortTitle},
+	}, received.Sort)
+
+	r = httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/catalog/documents?q=lamport&sort=relevance", nil))
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Equal(t, []data.Sort{{Field: data.SortRelevance}}, received.Sort)
+
+	for _, sort := range []string{"relevance", "size", "title,,kind"} {
+		r = httptest.NewRecorder()
+		router.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/catalog/documents?sort="+sort, nil))
+		require.Equal(t, http.StatusBadRequest, r.Code, sort)
+	}
+}
+
+func TestCatalogApi_ListDocuments_Cursor(t *testing.T) {
+	var received data.ListDocumentsRequest
+	catalog := mockCatalog{
+		listDocuments: func(ctx context.Context, request data.ListDocumentsRequest) (data.ListDocumentsResponse, error) {
+			received = request
+			return data.ListDocumentsResponse{Pagination: data.PaginationResponse{NextCursor: "next"}}, nil
+		},
+	}
+
+	api := New(Config{}, catalog)
+
+	router := api.catalogRouter()
+	r := httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/catalog/documents?cursor=abc&page_size=10", nil))
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Equal(t, data.PaginationRequest{Page: 1, PageSize: 10, Cursor: "abc", SkipCount: true}, received.Pagination)
+
+	var response data.ListDocumentsResponse
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&response))
+	require.Equal(t, "next", response.Pagination.NextCursor)
+
+	r = httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/catalog/documents?cursor=abc&count=true", nil))
+	require.Equal(t, http.StatusOK, r.Code)
+	require.False(t, received.Pagination.SkipCount)
+
+	r = httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/catalog/documents?count=false", nil))
+	require.Equal(t, http.StatusOK, r.Code)
+	require.True(t, received.Pagination.SkipCount)
+	require.Empty(t, received.Pagination.Cursor)
+}
+
+func TestCatalogApi_ListDocuments_Facets(t *testing.T) {
+	var received data.ListDocumentsRequest
+	catalog := mockCatalog{
+		listDocuments: func(ctx context.Context, request data.ListDocumentsRequest) (data.ListDocumentsResponse, error) {
+			received = request
+			return data.ListDocumentsResponse{Facets: map[data.Facet][]data.FacetBucket{
+				data.FacetKind: {{ID: 1, Value: "paper", Count: 2}},
+			}}, nil
+		},
+	}
+
+	api := New(Config{}, catalog)
+
+	router := api.catalogRouter()
+	r := httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/catalog/documents?facets=tags,kind&facets=authors", nil))
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Equal(t, []data.Facet{data.FacetTags, data.FacetKind, data.FacetAuthors}, received.Facets)
+
+	var response data.ListDocumentsResponse
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&response))
+	require.Equal(t, []data.FacetBucket{{ID: 1, Value: "paper", Count: 2}}, response.Facets[data.FacetKind])
+
+	r = httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/catalog/documents?facets=color", nil))
+	require.Equal(t, http.StatusBadRequest, r.Code)
+}
+
+func TestCatalogApi_ListDocuments_Filter(t *testing.T) {
+	var received data.ListDocumentsRequest
+	catalog := mockCatalog{
+		listDocuments: func(ctx context.Context, request data.ListDocumentsRequest) (data.ListDocumentsResponse, error) {
+			received = request
+			return data.ListDocumentsResponse{}, nil
+		},
+	}
+
+	api := New(Config{}, catalog)
+
+	router := api.catalogRouter()
+	r := httptest.NewRecorder()
+	filter := url.QueryEscape(`kind:paper AND NOT tag:draft`)
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/catalog/documents?filter="+filter, nil))
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Equal(t, data.FilterAnd{Operands: []data.Filter{
+		data.FilterTerm{Field: "kind", Operator: ":", Value: "paper"},
+		data.FilterNot{Operand: data.FilterTerm{Field: "tag", Operator: ":", Value: "draft"}},
+	}}, received.Filter)
+
+	r = httptest.NewRecorder()
+	filter = url.QueryEscape(`kind:paper AND color:red`)
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/catalog/documents?filter="+filter, nil))
+	require.Equal(t, http.StatusBadRequest, r.Code)
+
+	var apiError Error
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&apiError))
+	require.Contains(t, apiError.Message, `position 16 near "color"`)
+}
+
+func TestCatalogApi_UpdateDocument(t *testing.T) {
+	var received data.UpdateDocumentRequest
+	catalog := mockCatalog{
+		updateDocument: func(ctx context.Context, request data.UpdateDocumentRequest) (data.Document, error) {
+			received = request
+			return request.Document, nil
+		},
+	}
+
+	api := New(Config{}, catalog)
+
+	var request = data.Document{
+		Title: strptr("title"),
+		Uri:   strptr("uri"),
+		Tags: []data.DocumentTag{
+			{Tag: "tag_0"},
+		},
+	}
+
+	body, err := json.Marshal(request)
+	require.NoError(t, err)
+
+	router := api.catalogRouter()
+	r := httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodPut, "/catalog/documents/1", bytes.NewBuffer(body)))
+
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Equal(t, data.UpdateDocumentRequest{DocumentID: 1, Document: request}, received)
+
+	var response data.Document
+	err = json.NewDecoder(r.Body).Decode(&response)
+	require.NoError(t, err)
+	require.Equal(t, request, response)
+
+	r = httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodPatch, "/catalog/documents/2", bytes.NewBufferString(`{"title": "patched"}`)))
+
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Equal(t, data.UpdateDocumentRequest{
+		DocumentID: 2,
+		Document:   data.Document{Title: strptr("patched")},
+		Partial:    true,
+	}, received)
+}
+
+func TestCatalogApi_DeleteDocument(t *testing.T) {
+	var deleted = map[int]bool{1: false}
+	catalog := mockCatalog{
+		deleteDocument: func(ctx context.Context, request data.DeleteDocumentRequest) error {
+			if _, present := deleted[request.DocumentID]; !present {
+				return errors.New("document not found")
+			}
+			deleted[request.DocumentID] = true
+			return nil
+		},
+	}
+
+	api := New(Config{}, catalog)
+
+	router := api.catalogRouter()
+	r := httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodDelete, "/catalog/documents/1", nil))
+	require.Equal(t, http.StatusOK, r.Code)
+	require.True(t, deleted[1])
+
+	r = httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodDelete, "/catalog/documents/2", nil))
+	require.Equal(t, http.StatusBadRequest, r.Code)
+}
+
+func TestCatalogApi_InsertDocument_Fetch(t *testing.T) {
+	var received data.InsertDocumentRequest
+	catalog := mockCatalog{
+		insertDocument: func(ctx context.Context, request data.InsertDocumentRequest) error {
+			received = request
+			return nil
+		},
+	}
+
+	api := New(Config{}, catalog)
+
+	router := api.catalogRouter()
+	r := httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodPost, "/catalog/documents?fetch=true", bytes.NewBufferString(`{"uri": "https://example.com"}`)))
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Equal(t, data.InsertDocumentRequest{
+		Document: data.Document{Uri: strptr("https://example.com")},
+		Fetch:    true,
+	}, received)
+
+	r = httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodPost, "/catalog/documents?fetch=maybe", bytes.NewBufferString(`{"uri": "https://example.com"}`)))
+	require.Equal(t, http.StatusBadRequest, r.Code)
+}
+
+func TestCatalogApi_RefreshDocument(t *testing.T) {
+	catalog := mockCatalog{
+		refresh: func(ctx context.Context, request data.RefreshDocumentRequest) (data.Document, error) {
+			if request.DocumentID != 1 {
+				return data.Document{}, errors.New("document not found")
+			}
+			return data.Document{
+				Title: strptr("fetched"),
+				Fetch: data.FetchState{Status: data.FetchDone, StatusCode: http.StatusOK},
+			}, nil
+		},
+	}
+
+	api := New(Config{}, catalog)
+
+	router := api.catalogRouter()
+	r := httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodPost, "/catalog/documents/1/refresh", nil))
+	require.Equal(t, http.StatusOK, r.Code)
+
+	var response data.Document
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&response))
+	require.Equal(t, "fetched", *response.Title)
+	require.Equal(t, data.FetchState{Status: data.FetchDone, StatusCode: http.StatusOK}, response.Fetch)
+
+	r = httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodPost, "/catalog/documents/2/refresh", nil))
+	require.Equal(t, http.StatusBadRequest, r.Code)
 }
 
 type mockCatalog struct {
 	insertDocument func(ctx context.Context, request data.InsertDocumentRequest) error
 	getDocument    func(ctx context.Context, request data.GetDocumentRequest) (data.Document, error)
+	updateDocument func(ctx context.Context, request data.UpdateDocumentRequest) (data.Document, error)
+	deleteDocument func(ctx context.Context, request data.DeleteDocumentRequest) error
+	refresh        func(ctx context.Context, request data.RefreshDocumentRequest) (data.Document, error)
+	putContent     func(ctx context.Context, request data.PutDocumentContentRequest) (data.Document, error)
+	getContent     func(ctx context.Context, request data.GetDocumentContentRequest) (data.GetDocumentContentResponse, error)
 	listDocuments  func(ctx context.Context, request data.ListDocumentsRequest) (data.ListDocumentsResponse, error)
+	resolveUser    func(ctx context.Context, request data.ResolveUserRequest) (data.User, error)
+	insertUser     func(ctx context.Context, request data.InsertUserRequest) (data.User, error)
+	getUser        func(ctx context.Context, request data.GetUserRequest) (data.User, error)
+	updateUser     func(ctx context.Context, request data.UpdateUserRequest) (data.User, error)
+	deleteUser     func(ctx context.Context, request data.DeleteUserRequest) error
+	listUsers      func(ctx context.Context, request data.ListUsersRequest) (data.ListUsersResponse, error)
+
+	insertCollection func(ctx context.Context, request data.InsertCollectionRequest) (data.Collection, error)
+	getCollection    func(ctx context.Context, request data.GetCollectionRequest) (data.Collection, error)
+	updateCollection func(ctx context.Context, request data.UpdateCollectionRequest) (data.Collection, error)
+	deleteCollection func(ctx context.Context, request data.DeleteCollectionRequest) error
+	listCollections  func(ctx context.Context, request data.ListCollectionsRequest) (data.ListCollectionsResponse, error)
+
+	listTags     func(ctx context.Context, request data.ListTagsRequest) (data.ListTagsResponse, error)
+	insertTag    func(ctx context.Context, request data.InsertTagRequest) (data.DocumentTag, error)
+	updateTag    func(ctx context.Context, request data.UpdateTagRequest) (data.DocumentTag, error)
+	deleteTag    func(ctx context.Context, request data.DeleteTagRequest) error
+	mergeTags    func(ctx context.Context, request data.MergeTagsRequest) (data.DocumentTag, error)
+	moveTag      func(ctx context.Context, request data.MoveTagRequest) (data.DocumentTag, error)
+	getTagTree   func(ctx context.Context, request data.GetTagTreeRequest) ([]data.DocumentTag, error)
+	listAuthors  func(ctx context.Context, request data.ListAuthorsRequest) (data.ListAuthorsResponse, error)
+	insertAuthor func(ctx context.Context, request data.InsertAuthorRequest) (data.DocumentAuthor, error)
+	updateAuthor func(ctx context.Context, request data.UpdateAuthorRequest) (data.DocumentAuthor, error)
+	deleteAuthor func(ctx context.Context, request data.DeleteAuthorRequest) error
+	mergeAuthors func(ctx context.Context, request data.MergeAuthorsRequest) (data.DocumentAuthor, error)
+	listKinds    func(ctx context.Context, request data.ListKindsRequest) (data.ListKindsResponse, error)
+	insertKind   func(ctx context.Context, request data.InsertKindRequest) (data.DocumentKind, error)
+	updateKind   func(ctx context.Context, request data.UpdateKindRequest) (data.DocumentKind, error)
+	deleteKind   func(ctx context.Context, request data.DeleteKindRequest) error
+
+	insertDocuments func(ctx context.Context, request data.InsertDocumentsRequest) (data.InsertDocumentsResponse, error)
+	exportCatalog   func(ctx context.Context, request data.ExportCatalogRequest) error
+	listDuplicates  func(ctx context.Context, request data.ListDuplicatesRequest) (data.ListDuplicatesResponse, error)
+	listRevisions   func(ctx context.Context, request data.ListRevisionsRequest) (data.ListRevisionsResponse, error)
+	diffRevisions   func(ctx context.Context, request data.DiffRevisionsRequest) (data.RevisionDiff, error)
+	restoreRevision func(ctx context.Context, request data.RestoreRevisionRequest) (data.Document, error)
+	listTrash       func(ctx context.Context, request data.ListTrashRequest) (data.ListTrashResponse, error)
+	restoreDocument func(ctx context.Context, request data.RestoreDocumentRequest) (data.Document, error)
+	purgeDocument   func(ctx context.Context, request data.PurgeDocumentRequest) error
+	listEvents      func(ctx context.Context, request data.ListEventsRequest) (data.ListEventsResponse, error)
+	subscribeEvents func(ctx context.Context) <-chan data.CatalogEvent
 }
 
 func (m mockCatalog) Init() error {
@@ -148,10 +564,203 @@ func (m mockCatalog) InsertDocument(ctx context.Context, request data.InsertDocu
 	return m.insertDocument(ctx, request)
 }
 
+func (m mockCatalog) InsertDocuments(ctx context.Context, request data.InsertDocumentsRequest) (data.InsertDocumentsResponse, error) {
+	return m.insertDocuments(ctx, request)
+}
+
+func (m mockCatalog) ExportCatalog(ctx context.Context, request data.ExportCatalogRequest) error {
+	return m.exportCatalog(ctx, request)
+}
+
+func (m mockCatalog) ListDuplicates(ctx context.Context, request data.ListDuplicatesRequest) (data.ListDuplicatesResponse, error) {
+	return m.listDuplicates(ctx, request)
+}
+
+func (m mockCatalog) ListRevisions(ctx context.Context, request data.ListRevisionsRequest) (data.ListRevisionsResponse, error) {
+	return m.listRevisions(ctx, request)
+}
+
+func (m mockCatalog) DiffRevisions(ctx context.Context, request data.DiffRevisionsRequest) (data.RevisionDiff, error) {
+	return m.diffRevisions(ctx, request)
+}
+
+func (m mockCatalog) RestoreRevision(ctx context.Context, request data.RestoreRevisionRequest) (data.Document, error) {
+	return m.restoreRevision(ctx, request)
+}
+
+func (m mockCatalog) ListTrash(ctx context.Context, request data.ListTrashRequest) (data.ListTrashResponse, error) {
+	return m.listTrash(ctx, request)
+}
+
+func (m mockCatalog) RestoreDocument(ctx context.Context, request data.RestoreDocumentRequest) (data.Document, error) {
+	return m.restoreDocument(ctx, request)
+}
+
+func (m mockCatalog) PurgeDocument(ctx context.Context, request data.PurgeDocumentRequest) error {
+	return m.purgeDocument(ctx, request)
+}
+
+func (m mockCatalog) ListEvents(ctx context.Context, request data.ListEventsRequest) (data.ListEventsResponse, error) {
+	return m.listEvents(ctx, request)
+}
+
+func (m mockCatalog) SubscribeEvents(ctx context.Context) <-chan data.CatalogEvent {
+	return m.subscribeEvents(ctx)
+}
+
 func (m mockCatalog) GetDocument(ctx context.Context, request data.GetDocumentRequest) (data.Document, error) {
 	return m.getDocument(ctx, request)
 }
 
+func (m mockCatalog) UpdateDocument(ctx context.Context, request data.UpdateDocumentRequest) (data.Document, error) {
+	return m.updateDocument(ctx, request)
+}
+
+func (m mockCatalog) DeleteDocument(ctx context.Context, request data.DeleteDocumentRequest) error {
+	return m.deleteDocument(ctx, request)
+}
+
+func (m mockCatalog) RefreshDocument(ctx context.Context, request data.RefreshDocumentRequest) (data.Document, error) {
+	return m.refresh(ctx, request)
+}
+
+func (m mockCatalog) PutDocumentContent(ctx context.Context, request data.PutDocumentContentRequest) (data.Document, error) {
+	return m.putContent(ctx, request)
+}
+
+func (m mockCatalog) GetDocumentContent(ctx context.Context, request data.GetDocumentContentRequest) (data.GetDocumentContentResponse, error) {
+	return m.getContent(ctx, request)
+}
+
 func (m mockCatalog) ListDocuments(ctx context.Context, request data.ListDocumentsRequest) (data.ListDocumentsResponse, error) {
 	return m.listDocuments(ctx, request)
 }
+
+func (m mockCatalog) ResolveUser(ctx context.Context, request data.ResolveUserRequest) (data.User, error) {
+	return m.resolveUser(ctx, request)
+}
+
+func (m mockCatalog) InsertUser(ctx context.Context, request data.InsertUserRequest) (data.User, error) {
+	return m.insertUser(ctx, request)
+}
+
+func (m mockCatalog) GetUser(ctx context.Context, request data.GetUserRequest) (data.User, error) {
+	return m.getUser(ctx, request)
+}
+
+func (m mockCatalog) UpdateUser(ctx context.Context, request data.UpdateUserRequest) (data.User, error) {
+	return m.updateUser(ctx, request)
+}
+
+func (m mockCatalog) DeleteUser(ctx context.Context, request data.DeleteUserRequest) error {
+	return m.deleteUser(ctx, request)
+}
+
+func (m mockCatalog) ListUsers(ctx context.Context, request data.ListUsersRequest) (data.ListUsersResponse, error) {
+	return m.listUsers(ctx, request)
+}
+
+func (m mockCatalog) InsertCollection(ctx context.Context, request data.InsertCollectionRequest) (data.Collection, error) {
+	return m.insertCollection(ctx, request)
+}
+
+func (m mockCatalog) GetCollection(ctx context.Context, request data.GetCollectionRequest) (data.Collection, error) {
+	return m.getCollection(ctx, request)
+}
+
+func (m mockCatalog) UpdateCollection(ctx context.Context, request data.UpdateCollectionRequest) (data.Collection, error) {
+	return m.updateCollection(ctx, request)
+}
+
+func (m mockCatalog) DeleteCollection(ctx context.Context, request data.DeleteCollectionRequest) error {
+	return m.deleteCollection(ctx, request)
+}
+
+func (m mockCatalog) ListCollections(ctx context.Context, request data.ListCollectionsRequest) (data.ListCollectionsResponse, error) {
+	return m.listCollections(ctx, request)
+}
+
+func (m mockCatalog) ListTags(ctx context.Context, request data.ListTagsRequest) (data.ListTagsResponse, error) {
+	return m.listTags(ctx, request)
+}
+
+func (m mockCatalog) InsertTag(ctx context.Context, request data.InsertTagRequest) (data.DocumentTag, error) {
+	return m.insertTag(ctx, request)
+}
+
+func (m mockCatalog) UpdateTag(ctx context.Context, request data.UpdateTagRequest) (data.DocumentTag, error) {
+	return m.updateTag(ctx, request)
+}
+
+func (m mockCatalog) DeleteTag(ctx context.Context, request data.DeleteTagRequest) error {
+	return m.deleteTag(ctx, request)
+}
+
+func (m mockCatalog) MergeTags(ctx context.Context, request data.MergeTagsRequest) (data.DocumentTag, error) {
+	return m.mergeTags(ctx, request)
+}
+
+func (m mockCatalog) MoveTag(ctx context.Context, request data.MoveTagRequest) (data.DocumentTag, error) {
+	return m.moveTag(ctx, request)
+}
+
+func (m mockCatalog) GetTagTree(ctx context.Context, request data.GetTagTreeRequest) ([]data.DocumentTag, error) {
+	return m.getTagTree(ctx, request)
+}
+
+func (m mockCatalog) ListAuthors(ctx context.Context, request data.ListAuthorsRequest) (data.ListAuthorsResponse, error) {
+	return m.listAuthors(ctx, request)
+}
+
+func (m mockCatalog) InsertAuthor(ctx context.Context, request data.InsertAuthorRequest) (data.DocumentAuthor, error) {
+	return m.insertAuthor(ctx, request)
+}
+
+func (m mockCatalog) UpdateAuthor(ctx context.Context, request data.UpdateAuthorRequest) (data.DocumentAuthor, error) {
+	return m.updateAuthor(ctx, request)
+}
+
+func (m mockCatalog) DeleteAuthor(ctx context.Context, request data.DeleteAuthorRequest) error {
+	return m.deleteAuthor(ctx, request)
+}
+
+func (m mockCatalog) MergeAuthors(ctx context.Context, request data.MergeAuthorsRequest) (data.DocumentAuthor, error) {
+	return m.mergeAuthors(ctx, request)
+}
+
+func (m mockCatalog) ListKinds(ctx context.Context, request data.ListKindsRequest) (data.ListKindsResponse, error) {
+	return m.listKinds(ctx, request)
+}
+
+func (m mockCatalog) InsertKind(ctx context.Context, request data.InsertKindRequest) (data.DocumentKind, error) {
+	return m.insertKind(ctx, request)
+}
+
+func (m mockCatalog) UpdateKind(ctx context.Context, request data.UpdateKindRequest) (data.DocumentKind, error) {
+	return m.updateKind(ctx, request)
+}
+
+func (m mockCatalog) DeleteKind(ctx context.Context, request data.DeleteKindRequest) error {
+	return m.deleteKind(ctx, request)
+}
+
+func TestPaginationParams(t *testing.T) {
+	pagination, err := paginationParams(url.Values{})
+	require.NoError(t, err)
+	require.Equal(t, data.PaginationRequest{Page: 1, PageSize: 100}, pagination)
+
+	pagination, err = paginationParams(url.Values{"page": {"3"}, "page_size": {"1000"}})
+	require.NoError(t, err)
+	require.Equal(t, data.PaginationRequest{Page: 3, PageSize: 1000}, pagination)
+
+	for _, params := range []url.Values{
+		{"page": {"0"}},
+		{"page": {"-2"}},
+		{"page_size": {"0"}},
+		{"page_size": {"-100"}},
+		{"page_size": {"1001"}},
+	} {
+		_, err := paginationParams(params)
+		require.Error(t, err, params)
+	}
+}
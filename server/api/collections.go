@@ -0,0 +1,184 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/garugaru/knowledge/server/data"
+	"net/http"
+)
+
+// collectionViewer is the user whose visible collections are returned, zero
+// when every collection is visible.
+func collectionViewer(r *http.Request) uint {
+	user, ok := requestUser(r)
+	if !ok || user.Role.Includes(data.RoleAdmin) {
+		return 0
+	}
+	return user.ID
+}
+
+// requireCollectionOwner writes an error unless the request user owns the
+// collection or is an admin.
+func (a Api) requireCollectionOwner(w http.ResponseWriter, r *http.Request, collectionID uint) bool {
+	collection, err := a.catalog.GetCollection(r.Context(), data.GetCollectionRequest{
+		CollectionID: collectionID,
+		UserID:       collectionViewer(r),
+	})
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return false
+	}
+
+	user, ok := requestUser(r)
+	if ok && !user.Role.Includes(data.RoleAdmin) && collection.OwnerID != user.ID {
+		httpErr(w, errors.New("only the owner can modify the collection"), http.StatusForbidden)
+		return false
+	}
+
+	return true
+}
+
+func (a Api) catalogListCollections(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	pagination, err := paginationParams(params)
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var request = data.ListCollectionsRequest{
+		UserID:     collectionViewer(r),
+		Pagination: pagination,
+	}
+
+	collections, err := a.catalog.ListCollections(r.Context(), request)
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(collections); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a Api) catalogInsertCollection(w http.ResponseWriter, r *http.Request) {
+	var collection data.Collection
+	if err := json.NewDecoder(r.Body).Decode(&collection); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	collection.OwnerID = 0
+	if user, ok := requestUser(r); ok {
+		collection.OwnerID = user.ID
+	}
+
+	inserted, err := a.catalog.InsertCollection(r.Context(), data.InsertCollectionRequest{
+		Collection: collection,
+	})
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(inserted); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a Api) catalogGetCollection(w http.ResponseWriter, r *http.Request) {
+	collectionID, err := entityIDParam(r)
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	collection, err := a.catalog.GetCollection(r.Context(), data.GetCollectionRequest{
+		CollectionID: collectionID,
+		UserID:       collectionViewer(r),
+	})
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(collection); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a Api) catalogReplaceCollection(w http.ResponseWriter, r *http.Request) {
+	a.catalogUpdateCollection(w, r, false)
+}
+
+func (a Api) catalogPatchCollection(w http.ResponseWriter, r *http.Request) {
+	a.catalogUpdateCollection(w, r, true)
+}
+
+func (a Api) catalogUpdateCollection(w http.ResponseWriter, r *http.Request, partial bool) {
+	collectionID, err := entityIDParam(r)
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if !a.requireCollectionOwner(w, r, collectionID) {
+		return
+	}
+
+	var collection data.Collection
+	if err := json.NewDecoder(r.Body).Decode(&collection); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	updated, err := a.catalog.UpdateCollection(r.Context(), data.UpdateCollectionRequest{
+		CollectionID: collectionID,
+		Collection:   collection,
+		Partial:      partial,
+	})
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(updated); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a Api) catalogDeleteCollection(w http.ResponseWriter, r *http.Request) {
+	collectionID, err := entityIDParam(r)
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if !a.requireCollectionOwner(w, r, collectionID) {
+		return
+	}
+
+	err = a.catalog.DeleteCollection(r.Context(), data.DeleteCollectionRequest{
+		CollectionID: collectionID,
+	})
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/garugaru/knowledge/server/auth"
+	"github.com/garugaru/knowledge/server/data"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPI_Collections(t *testing.T) {
+	users := map[string]data.User{
+		"alice": {Model: gorm.Model{ID: 1}, Subject: "alice", Role: data.RoleReader},
+		"bob":   {Model: gorm.Model{ID: 2}, Subject: "bob", Role: data.RoleReader},
+	}
+
+	var inserted data.InsertCollectionRequest
+	var updated data.UpdateCollectionRequest
+	var listed data.ListDocumentsRequest
+	catalog := mockCatalog{
+		resolveUser: func(ctx context.Context, request data.ResolveUserRequest) (data.User, error) {
+			return users[request.Subject], nil
+		},
+		insertCollection: func(ctx context.Context, request data.InsertCollectionRequest) (data.Collection, error) {
+			inserted = request
+			return request.Collection, nil
+		},
+		getCollection: func(ctx context.Context, request data.GetCollectionRequest) (data.Collection, error) {
+			if request.UserID != 1 && request.UserID != 2 {
+				return data.Collection{}, gorm.ErrRecordNotFound
+			}
+			return data.Collection{Model: gorm.Model{ID: request.CollectionID}, Name: "reading", OwnerID: 1}, nil
+		},
+		updateCollection: func(ctx context.Context, request data.UpdateCollectionRequest) (data.Collection, error) {
+			updated = request
+			return request.Collection, nil
+		},
+		listDocuments: func(ctx context.Context, request data.ListDocumentsRequest) (data.ListDocumentsResponse, error) {
+			listed = request
+			return data.ListDocumentsResponse{}, nil
+		},
+	}
+
+	api := New(Config{
+		Authenticator: auth.NewStaticTokens(
+			auth.StaticToken{Token: "alice-token", Subject: "alice", Scopes: []string{auth.ScopeWrite}},
+			auth.StaticToken{Token: "bob-token", Subject: "bob", Scopes: []string{auth.ScopeWrite}},
+		),
+	}, catalog)
+	router := api.router()
+
+	request := func(method string, target string, token string, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+		r.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, r)
+		return recorder
+	}
+
+	r := request(http.MethodPost, "/catalog/collections", "alice-token", `{"name": "reading", "owner_id": 2, "items": [{"document_id": 3, "note": "first"}]}`)
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Equal(t, uint(1), inserted.Collection.OwnerID, "the owner is the user creating the collection")
+	require.Equal(t, []data.CollectionItem{{DocumentID: 3, Note: "first"}}, inserted.Collection.Items)
+
+	r = request(http.MethodPatch, "/catalog/collections/4", "bob-token", `{"visibility": "public"}`)
+	require.Equal(t, http.StatusForbidden, r.Code)
+	var apiError Error
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&apiError))
+	require.Equal(t, "only the owner can modify the collection", apiError.Message)
+
+	r = request(http.MethodPatch, "/catalog/collections/4", "alice-token", `{"visibility": "public"}`)
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Equal(t, data.UpdateCollectionRequest{
+		CollectionID: 4,
+		Collection:   data.Collection{Visibility: data.VisibilityPublic},
+		Partial:      true,
+	}, updated)
+
+	r = request(http.MethodGet, "/catalog/documents?collection=4", "bob-token", "")
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Equal(t, uint(4), listed.Collection)
+
+	r = request(http.MethodGet, "/catalog/documents?collection=four", "bob-token", "")
+	require.Equal(t, http.StatusBadRequest, r.Code)
+}
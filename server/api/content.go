@@ -0,0 +1,109 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/garugaru/knowledge/server/data"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+)
+
+func (a Api) catalogPutDocumentContent(w http.ResponseWriter, r *http.Request) {
+	documentID, err := documentIDParam(r)
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if !a.requireDocumentOwner(w, r, documentID) {
+		return
+	}
+
+	if r.ContentLength > a.config.MaxUploadSize {
+		httpErr(w, fmt.Errorf("content exceeds the maximum size of %d bytes", a.config.MaxUploadSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+	// the bodies without length fail while being stored
+	r.Body = http.MaxBytesReader(w, r.Body, a.config.MaxUploadSize)
+
+	if err := extendReadDeadline(r, a.config.UploadTimeout); err != nil {
+		httpErr(w, err, http.StatusInternalServerError)
+		return
+	}
+	if err := extendWriteDeadline(r, a.config.UploadTimeout); err != nil {
+		httpErr(w, err, http.StatusInternalServerError)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), a.config.UploadTimeout)
+	defer cancel()
+
+	var (
+		content     io.Reader = r.Body
+		contentType           = r.Header.Get("Content-Type")
+	)
+
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && mediaType == "multipart/form-data" {
+		reader, err := r.MultipartReader()
+		if err != nil {
+			httpErr(w, err, http.StatusBadRequest)
+			return
+		}
+
+		part, err := reader.NextPart()
+		if err != nil {
+			httpErr(w, err, http.StatusBadRequest)
+			return
+		}
+		defer part.Close()
+
+		content, contentType = part, part.Header.Get("Content-Type")
+	}
+
+	document, err := a.catalog.PutDocumentContent(ctx, data.PutDocumentContentRequest{
+		DocumentID:  documentID,
+		ContentType: contentType,
+		Content:     content,
+		UserID:      requestUserID(r),
+	})
+
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(document); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+}
+
+func (a Api) catalogGetDocumentContent(w http.ResponseWriter, r *http.Request) {
+	documentID, err := documentIDParam(r)
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	content, err := a.catalog.GetDocumentContent(r.Context(), data.GetDocumentContentRequest{
+		DocumentID: documentID,
+	})
+
+	if errors.Is(err, data.ErrContentNotFound) {
+		httpErr(w, err, http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+	defer content.Content.Close()
+
+	w.Header().Set("Content-Type", content.ContentType)
+	w.Header().Set("ETag", strconv.Quote(content.SHA256))
+	http.ServeContent(w, r, "", content.LastModified, content.Content)
+}
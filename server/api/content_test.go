@@ -0,0 +1,123 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"github.com/garugaru/knowledge/server/data"
+	"github.com/stretchr/testify/require"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type nopSeekCloser struct {
+	io.ReadSeeker
+}
+
+func (nopSeekCloser) Close() error {
+	return nil
+}
+
+func TestCatalogApi_PutDocumentContent(t *testing.T) {
+	var (
+		received data.PutDocumentContentRequest
+		content  []byte
+	)
+	catalog := mockCatalog{
+		putContent: func(ctx context.Context, request data.PutDocumentContentRequest) (data.Document, error) {
+			received = request
+			var err error
+			content, err = io.ReadAll(request.Content)
+			return data.Document{Content: data.DocumentContent{Type: request.ContentType, Size: int64(len(content))}}, err
+		},
+	}
+
+	api := New(Config{}, catalog)
+	router := api.catalogRouter()
+
+	req := httptest.NewRequest(http.MethodPost, "/catalog/documents/1/content", strings.NewReader("hello world"))
+	req.Header.Set("Content-Type", "text/plain")
+	r := httptest.NewRecorder()
+	router.ServeHTTP(r, req)
+
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Equal(t, 1, received.DocumentID)
+	require.Equal(t, "text/plain", received.ContentType)
+	require.Equal(t, "hello world", string(content))
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", "paper.pdf")
+	require.NoError(t, err)
+	_, err = part.Write([]byte("%PDF-1.4"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	req = httptest.NewRequest(http.MethodPost, "/catalog/documents/2/content", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	r = httptest.NewRecorder()
+	router.ServeHTTP(r, req)
+
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Equal(t, 2, received.DocumentID)
+	require.Equal(t, "application/octet-stream", received.ContentType)
+	require.Equal(t, "%PDF-1.4", string(content))
+
+	router = New(Config{MaxUploadSize: 4}, catalog).catalogRouter()
+	received = data.PutDocumentContentRequest{}
+
+	r = httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodPost, "/catalog/documents/3/content", strings.NewReader("hello world")))
+	require.Equal(t, http.StatusRequestEntityTooLarge, r.Code)
+	require.Zero(t, received.DocumentID)
+
+	// the length of chunked bodies is only known while reading them
+	req = httptest.NewRequest(http.MethodPost, "/catalog/documents/3/content", strings.NewReader("hello world"))
+	req.ContentLength = -1
+	r = httptest.NewRecorder()
+	router.ServeHTTP(r, req)
+	require.Equal(t, http.StatusRequestEntityTooLarge, r.Code)
+}
+
+func TestCatalogApi_GetDocumentContent(t *testing.T) {
+	catalog := mockCatalog{
+		getContent: func(ctx context.Context, request data.GetDocumentContentRequest) (data.GetDocumentContentResponse, error) {
+			if request.DocumentID != 1 {
+				return data.GetDocumentContentResponse{}, data.ErrContentNotFound
+			}
+			return data.GetDocumentContentResponse{
+				Content:      nopSeekCloser{strings.NewReader("hello world")},
+				ContentType:  "text/plain",
+				Size:         11,
+				SHA256:       "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9",
+				LastModified: time.Now(),
+			}, nil
+		},
+	}
+
+	api := New(Config{}, catalog)
+	router := api.catalogRouter()
+
+	r := httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/catalog/documents/1/content", nil))
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Equal(t, "text/plain", r.Header().Get("Content-Type"))
+	require.Equal(t, "\"b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9\"", r.Header().Get("ETag"))
+	require.Equal(t, "hello world", r.Body.String())
+
+	req := httptest.NewRequest(http.MethodGet, "/catalog/documents/1/content", nil)
+	req.Header.Set("Range", "bytes=6-")
+	r = httptest.NewRecorder()
+	router.ServeHTTP(r, req)
+	require.Equal(t, http.StatusPartialContent, r.Code)
+	require.Equal(t, "bytes 6-10/11", r.Header().Get("Content-Range"))
+	require.Equal(t, "world", r.Body.String())
+
+	r = httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/catalog/documents/2/content", nil))
+	require.Equal(t, http.StatusNotFound, r.Code)
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/garugaru/knowledge/server/data"
+	"net/http"
+	"strconv"
+)
+
+func (a Api) catalogListDuplicates(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	pagination, err := paginationParams(params)
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var minScore float64
+	if minScoreParam := params.Get("min_score"); len(minScoreParam) != 0 {
+		minScore, err = strconv.ParseFloat(minScoreParam, 64)
+		if err != nil || minScore < 0 || minScore > 1 {
+			httpErr(w, fmt.Errorf("invalid 'min_score' parameter value: %s", minScoreParam), http.StatusBadRequest)
+			return
+		}
+	}
+
+	duplicates, err := a.catalog.ListDuplicates(r.Context(), data.ListDuplicatesRequest{
+		MinScore:   minScore,
+		Pagination: pagination,
+	})
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(duplicates); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/garugaru/knowledge/server/data"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCatalogApi_ListDuplicates(t *testing.T) {
+	var received data.ListDuplicatesRequest
+	catalog := mockCatalog{
+		listDuplicates: func(ctx context.Context, request data.ListDuplicatesRequest) (data.ListDuplicatesResponse, error) {
+			received = request
+			return data.ListDuplicatesResponse{
+				Items: []data.DuplicateCluster{{
+					Score:     0.9,
+					Documents: []data.Document{{ID: 1}, {ID: 2}},
+					Pairs:     []data.DuplicateCandidate{{DocumentID: 1, DuplicateID: 2, Score: 0.9, TitleScore: 0.9}},
+				}},
+				Pagination: data.PaginationResponse{TotalElements: 1, Page: 1, Pages: 1},
+			}, nil
+		},
+	}
+
+	router := New(Config{}, catalog).catalogRouter()
+
+	r := httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/catalog/duplicates?min_score=0.8&page_size=20", nil))
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Equal(t, data.ListDuplicatesRequest{
+		MinScore:   0.8,
+		Pagination: data.PaginationRequest{Page: 1, PageSize: 20},
+	}, received)
+
+	var response data.ListDuplicatesResponse
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&response))
+	require.Len(t, response.Items, 1)
+	require.Equal(t, 2, response.Items[0].Pairs[0].DuplicateID)
+
+	for _, minScore := range []string{"high", "1.5"} {
+		r = httptest.NewRecorder()
+		router.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/catalog/duplicates?min_score="+minScore, nil))
+		require.Equal(t, http.StatusBadRequest, r.Code)
+	}
+}
@@ -2,18 +2,37 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"github.com/garugaru/knowledge/server/data"
 	"github.com/prometheus/common/log"
 	"net/http"
+	"strings"
 )
 
+// bodyTooLarge is the message of the error returned by http.MaxBytesReader
+// past its limit, the error is not exported
+const bodyTooLarge = "http: request body too large"
+
 type Error struct {
 	Message string `json:"message,omitempty"`
+	// DocumentID is the existing document conflicting with the request
+	DocumentID int `json:"document_id,omitempty"`
 }
 
 func httpErr(w http.ResponseWriter, err error, status int) {
 	log.Error(err)
-	w.WriteHeader(status)
 	response := Error{Message: err.Error()}
+
+	var duplicate *data.DuplicateDocumentError
+	if errors.As(err, &duplicate) {
+		status = http.StatusConflict
+		response.DocumentID = duplicate.DocumentID
+	}
+	if strings.Contains(err.Error(), bodyTooLarge) {
+		status = http.StatusRequestEntityTooLarge
+	}
+
+	w.WriteHeader(status)
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/garugaru/knowledge/server/data"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	eventsPath      = "/catalog/events"
+	eventsBatchSize = 100
+	// eventsKeepAlive is the time between two comments keeping the stream
+	// open, the outbox is read again to catch up on the dropped events
+	eventsKeepAlive = 15 * time.Second
+	// eventsWriteTimeout is the write deadline of the stream, extended before
+	// every write so that only the dead connections reach it
+	eventsWriteTimeout = 4 * eventsKeepAlive
+	// eventsRetry is the time the clients wait before reconnecting
+	eventsRetry = 3 * time.Second
+)
+
+// catalogStreamEvents streams the events of the catalog as server sent
+// events, starting after the Last-Event-ID header or last_event_id parameter.
+// The events are read from the outbox to keep their order, the subscription
+// only signals the new ones.
+func (a Api) catalogStreamEvents(w http.ResponseWriter, r *http.Request) {
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if len(lastEventID) == 0 {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+
+	var after uint
+	if len(lastEventID) != 0 {
+		id, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			httpErr(w, fmt.Errorf("invalid last event id: %s", lastEventID), http.StatusBadRequest)
+			return
+		}
+		after = uint(id)
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		httpErr(w, errors.New("streaming is not supported"), http.StatusInternalServerError)
+		return
+	}
+
+	// the clients resume after the last event once the stream ends
+	ctx, cancel := context.WithTimeout(r.Context(), a.config.StreamTimeout)
+	defer cancel()
+
+	// the subscription starts before reading the outbox to miss no event
+	events := a.catalog.SubscribeEvents(ctx)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	if err := extendWriteDeadline(r, eventsWriteTimeout); err != nil {
+		httpErr(w, err, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", eventsRetry.Milliseconds()); err != nil {
+		return
+	}
+
+	keepAlive := time.NewTicker(eventsKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		// the status is already sent, the clients reconnect on errors
+		if err := extendWriteDeadline(r, eventsWriteTimeout); err != nil {
+			return
+		}
+		var err error
+		if after, err = a.writeEvents(ctx, w, after); err != nil {
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := io.WriteString(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// writeEvents writes the events of the outbox following after, returning the
+// sequence of the last one written.
+func (a Api) writeEvents(ctx context.Context, w io.Writer, after uint) (uint, error) {
+	for {
+		response, err := a.catalog.ListEvents(ctx, data.ListEventsRequest{After: after, Limit: eventsBatchSize})
+		if err != nil {
+			return after, err
+		}
+
+		for _, event := range response.Items {
+			payload, err := json.Marshal(event)
+			if err != nil {
+				return after, err
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", *event.Sequence, event.Type, payload); err != nil {
+				return after, err
+			}
+			after = *event.Sequence
+		}
+
+		if len(response.Items) < eventsBatchSize {
+			return after, nil
+		}
+	}
+}
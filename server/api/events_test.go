@@ -0,0 +1,86 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"github.com/garugaru/knowledge/server/data"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCatalogApi_StreamEvents(t *testing.T) {
+	var mu sync.Mutex
+	sequence := func(s uint) *uint { return &s }
+	stored := []data.CatalogEvent{
+		{ID: 1, Sequence: sequence(1), Type: data.EventDocumentCreated, DocumentID: 1},
+		{ID: 2, Sequence: sequence(2), Type: data.EventDocumentUpdated, DocumentID: 1},
+	}
+	published := make(chan data.CatalogEvent, 1)
+
+	catalog := mockCatalog{
+		listEvents: func(ctx context.Context, request data.ListEventsRequest) (data.ListEventsResponse, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			response := data.ListEventsResponse{Items: []data.CatalogEvent{}}
+			for _, event := range stored {
+				if *event.Sequence > request.After && len(response.Items) < request.Limit {
+					response.Items = append(response.Items, event)
+				}
+			}
+			return response, nil
+		},
+		subscribeEvents: func(ctx context.Context) <-chan data.CatalogEvent {
+			return published
+		},
+	}
+
+	server := httptest.NewServer(New(Config{}, catalog).catalogRouter())
+	defer server.Close()
+
+	request, err := http.NewRequest(http.MethodGet, server.URL+"/catalog/events", nil)
+	require.NoError(t, err)
+	request.Header.Set("Last-Event-ID", "1")
+
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	require.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(response.Body)
+	readEvent := func() string {
+		var lines []string
+		for {
+			line, err := reader.ReadString('\n')
+			require.NoError(t, err)
+			if line == "\n" {
+				if len(lines) == 0 || strings.HasPrefix(lines[0], "retry:") {
+					lines = nil
+					continue
+				}
+				return strings.Join(lines, "")
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	event := readEvent()
+	require.Contains(t, event, "id: 2\nevent: document.updated\n", "the stream must resume after the last event id")
+	require.Contains(t, event, `"document_id":1`)
+
+	mu.Lock()
+	stored = append(stored, data.CatalogEvent{ID: 3, Sequence: sequence(3), Type: data.EventDocumentDeleted, DocumentID: 1})
+	mu.Unlock()
+	published <- stored[2]
+
+	require.Contains(t, readEvent(), "id: 3\nevent: document.deleted\n")
+
+	r := httptest.NewRecorder()
+	New(Config{}, catalog).catalogRouter().ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/catalog/events?last_event_id=a", nil))
+	require.Equal(t, http.StatusBadRequest, r.Code)
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"github.com/garugaru/knowledge/server/data"
+	"github.com/prometheus/common/log"
+	"net/http"
+)
+
+// catalogExport streams the whole catalog, errors can only be reported
+// before the first record is written. The export is not bound to the server
+// write timeout, it is stopped after the export timeout instead.
+func (a Api) catalogExport(w http.ResponseWriter, r *http.Request) {
+	if !requireRole(w, r, data.RoleAdmin) {
+		return
+	}
+
+	if err := extendWriteDeadline(r, a.config.ExportTimeout); err != nil {
+		httpErr(w, err, http.StatusInternalServerError)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), a.config.ExportTimeout)
+	defer cancel()
+
+	format := data.ExportNDJSON
+	if formatParam := r.URL.Query().Get("format"); len(formatParam) != 0 {
+		format = data.ExportFormat(formatParam)
+		if !format.Valid() {
+			httpErr(w, fmt.Errorf("invalid 'format' parameter value: %s", formatParam), http.StatusBadRequest)
+			return
+		}
+	}
+
+	writer := &exportWriter{ResponseWriter: w, format: format}
+	err := a.catalog.ExportCatalog(ctx, data.ExportCatalogRequest{
+		Writer: writer,
+		Format: format,
+	})
+
+	if err != nil && !writer.started {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Error(err)
+	}
+}
+
+// exportWriter sets the export headers on the first write, leaving the
+// response untouched when the export fails upfront.
+type exportWriter struct {
+	http.ResponseWriter
+	format  data.ExportFormat
+	started bool
+}
+
+func (w *exportWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.started = true
+
+		contentType := "application/x-ndjson"
+		if w.format == data.ExportTarGz {
+			contentType = "application/gzip"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"catalog.%s\"", w.format))
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(p)
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"github.com/garugaru/knowledge/server/data"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCatalogApi_Export(t *testing.T) {
+	var received data.ExportCatalogRequest
+	catalog := mockCatalog{
+		exportCatalog: func(ctx context.Context, request data.ExportCatalogRequest) error {
+			received = request
+			_, err := request.Writer.Write([]byte("{\"kind\":{\"name\":\"paper\"}}\n"))
+			return err
+		},
+	}
+
+	router := New(Config{}, catalog).catalogRouter()
+
+	r := httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/catalog/export", nil))
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Equal(t, data.ExportNDJSON, received.Format)
+	require.Equal(t, "application/x-ndjson", r.Header().Get("Content-Type"))
+	require.Equal(t, "{\"kind\":{\"name\":\"paper\"}}\n", r.Body.String())
+
+	r = httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/catalog/export?format=tar.gz", nil))
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Equal(t, data.ExportTarGz, received.Format)
+	require.Equal(t, "application/gzip", r.Header().Get("Content-Type"))
+	require.Contains(t, r.Header().Get("Content-Disposition"), "catalog.tar.gz")
+
+	r = httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/catalog/export?format=zip", nil))
+	require.Equal(t, http.StatusBadRequest, r.Code)
+
+	catalog.exportCatalog = func(ctx context.Context, request data.ExportCatalogRequest) error {
+		return errors.New("document content storage is not configured")
+	}
+	router = New(Config{}, catalog).catalogRouter()
+
+	r = httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/catalog/export?format=tar.gz", nil))
+	require.Equal(t, http.StatusBadRequest, r.Code)
+	require.Empty(t, r.Header().Get("Content-Disposition"))
+}
+
+func TestCatalogApi_Export_WriteTimeout(t *testing.T) {
+	catalog := mockCatalog{
+		exportCatalog: func(ctx context.Context, request data.ExportCatalogRequest) error {
+			for i := 0; i < 3; i++ {
+				if _, err := request.Writer.Write([]byte("{\"kind\":{\"name\":\"paper\"}}\n")); err != nil {
+					return err
+				}
+				if flusher, ok := request.Writer.(http.Flusher); ok {
+					flusher.Flush()
+				}
+				time.Sleep(100 * time.Millisecond)
+			}
+			return nil
+		},
+	}
+
+	server := httptest.NewUnstartedServer(nil)
+	server.Config = New(Config{}, catalog).Server(ServeOpts{Timeout: 50 * time.Millisecond})
+	server.Start()
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/catalog/export")
+	require.NoError(t, err)
+	defer response.Body.Close()
+	require.Equal(t, http.StatusOK, response.StatusCode)
+
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err, "the export outlives the server write timeout")
+	require.Equal(t, 3, strings.Count(string(body), "\n"))
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/garugaru/knowledge/server/data"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// revisionParam reads the revision id from the params, zero when missing.
+func revisionParam(params url.Values, name string) (uint, error) {
+	value := params.Get(name)
+	if len(value) == 0 {
+		return 0, nil
+	}
+
+	revision, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid '%s' parameter value: %s", name, value)
+	}
+	return uint(revision), nil
+}
+
+func (a Api) catalogListRevisions(w http.ResponseWriter, r *http.Request) {
+	documentID, err := documentIDParam(r)
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	pagination, err := paginationParams(r.URL.Query())
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	revisions, err := a.catalog.ListRevisions(r.Context(), data.ListRevisionsRequest{
+		DocumentID: documentID,
+		Pagination: pagination,
+	})
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(revisions); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a Api) catalogDiffRevisions(w http.ResponseWriter, r *http.Request) {
+	documentID, err := documentIDParam(r)
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	params := r.URL.Query()
+	from, err := revisionParam(params, "from")
+	if err == nil && from == 0 {
+		err = errors.New("'from' parameter must be present")
+	}
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	to, err := revisionParam(params, "to")
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	diff, err := a.catalog.DiffRevisions(r.Context(), data.DiffRevisionsRequest{
+		DocumentID: documentID,
+		From:       from,
+		To:         to,
+	})
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(diff); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a Api) catalogRestoreRevision(w http.ResponseWriter, r *http.Request) {
+	documentID, err := documentIDParam(r)
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	revision, err := strconv.ParseUint(mux.Vars(r)["revision"], 10, 64)
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if !a.requireDocumentOwner(w, r, documentID) {
+		return
+	}
+
+	document, err := a.catalog.RestoreRevision(r.Context(), data.RestoreRevisionRequest{
+		DocumentID: documentID,
+		RevisionID: uint(revision),
+		UserID:     requestUserID(r),
+	})
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(document); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/garugaru/knowledge/server/data"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCatalogApi_Revisions(t *testing.T) {
+	var (
+		listed   data.ListRevisionsRequest
+		diffed   data.DiffRevisionsRequest
+		restored data.RestoreRevisionRequest
+	)
+	catalog := mockCatalog{
+		listRevisions: func(ctx context.Context, request data.ListRevisionsRequest) (data.ListRevisionsResponse, error) {
+			listed = request
+			return data.ListRevisionsResponse{Items: []data.DocumentRevision{{ID: 2, DocumentID: 1, Action: data.RevisionUpdate}}}, nil
+		},
+		diffRevisions: func(ctx context.Context, request data.DiffRevisionsRequest) (data.RevisionDiff, error) {
+			diffed = request
+			return data.RevisionDiff{From: request.From, To: 3, Changes: []data.FieldChange{{Field: "title", From: "a", To: "b"}}}, nil
+		},
+		restoreRevision: func(ctx context.Context, request data.RestoreRevisionRequest) (data.Document, error) {
+			restored = request
+			return data.Document{ID: request.DocumentID, Title: strptr("a")}, nil
+		},
+	}
+
+	router := New(Config{}, catalog).catalogRouter()
+
+	r := httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/catalog/documents/1/revisions?page_size=5", nil))
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Equal(t, data.ListRevisionsRequest{DocumentID: 1, Pagination: data.PaginationRequest{Page: 1, PageSize: 5}}, listed)
+
+	var revisions data.ListRevisionsResponse
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&revisions))
+	require.Len(t, revisions.Items, 1)
+	require.Equal(t, data.RevisionUpdate, revisions.Items[0].Action)
+
+	r = httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/catalog/documents/1/revisions/diff?from=1", nil))
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Equal(t, data.DiffRevisionsRequest{DocumentID: 1, From: 1}, diffed)
+
+	var diff data.RevisionDiff
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&diff))
+	require.Equal(t, uint(3), diff.To)
+	require.Len(t, diff.Changes, 1)
+
+	for _, query := range []string{"", "?from=a", "?from=1&to=b"} {
+		r = httptest.NewRecorder()
+		router.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/catalog/documents/1/revisions/diff"+query, nil))
+		require.Equal(t, http.StatusBadRequest, r.Code, query)
+	}
+
+	r = httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodPost, "/catalog/documents/1/revisions/2/restore", nil))
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Equal(t, data.RestoreRevisionRequest{DocumentID: 1, RevisionID: 2}, restored)
+}
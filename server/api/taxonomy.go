@@ -0,0 +1,425 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/garugaru/knowledge/server/data"
+	"net/http"
+	"strconv"
+)
+
+// MergeRequest lists the tags or authors merged into the one of the path.
+type MergeRequest struct {
+	IDs []uint `json:"ids"`
+}
+
+func (a Api) catalogListTags(w http.ResponseWriter, r *http.Request) {
+	pagination, err := paginationParams(r.URL.Query())
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	tags, err := a.catalog.ListTags(r.Context(), data.ListTagsRequest{Pagination: pagination})
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(tags); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a Api) catalogInsertTag(w http.ResponseWriter, r *http.Request) {
+	if !requireRole(w, r, data.RoleEditor) {
+		return
+	}
+
+	var tag data.DocumentTag
+	if err := json.NewDecoder(r.Body).Decode(&tag); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	inserted, err := a.catalog.InsertTag(r.Context(), data.InsertTagRequest{Tag: tag})
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(inserted); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a Api) catalogUpdateTag(w http.ResponseWriter, r *http.Request) {
+	if !requireRole(w, r, data.RoleEditor) {
+		return
+	}
+
+	tagID, err := entityIDParam(r)
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var tag data.DocumentTag
+	if err := json.NewDecoder(r.Body).Decode(&tag); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	updated, err := a.catalog.UpdateTag(r.Context(), data.UpdateTagRequest{TagID: tagID, Tag: tag, UserID: requestUserID(r)})
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(updated); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a Api) catalogDeleteTag(w http.ResponseWriter, r *http.Request) {
+	if !requireRole(w, r, data.RoleEditor) {
+		return
+	}
+
+	tagID, err := entityIDParam(r)
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := a.catalog.DeleteTag(r.Context(), data.DeleteTagRequest{TagID: tagID, UserID: requestUserID(r)}); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a Api) catalogMergeTags(w http.ResponseWriter, r *http.Request) {
+	if !requireRole(w, r, data.RoleEditor) {
+		return
+	}
+
+	tagID, err := entityIDParam(r)
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var request MergeRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	tag, err := a.catalog.MergeTags(r.Context(), data.MergeTagsRequest{TagID: tagID, MergedIDs: request.IDs, UserID: requestUserID(r)})
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(tag); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// MoveRequest is the new parent of a tag, a zero ParentID makes it a root.
+type MoveRequest struct {
+	ParentID uint `json:"parent_id"`
+}
+
+func (a Api) catalogMoveTag(w http.ResponseWriter, r *http.Request) {
+	if !requireRole(w, r, data.RoleEditor) {
+		return
+	}
+
+	tagID, err := entityIDParam(r)
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var request MoveRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	tag, err := a.catalog.MoveTag(r.Context(), data.MoveTagRequest{TagID: tagID, ParentID: request.ParentID, UserID: requestUserID(r)})
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(tag); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a Api) catalogGetTagTree(w http.ResponseWriter, r *http.Request) {
+	var request data.GetTagTreeRequest
+	if rootParam := r.URL.Query().Get("root"); len(rootParam) != 0 {
+		root, err := strconv.ParseUint(rootParam, 10, 64)
+		if err != nil {
+			httpErr(w, fmt.Errorf("invalid 'root' parameter value: %s", rootParam), http.StatusBadRequest)
+			return
+		}
+		request.TagID = uint(root)
+	}
+
+	tree, err := a.catalog.GetTagTree(r.Context(), request)
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(tree); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a Api) catalogListAuthors(w http.ResponseWriter, r *http.Request) {
+	pagination, err := paginationParams(r.URL.Query())
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	authors, err := a.catalog.ListAuthors(r.Context(), data.ListAuthorsRequest{Pagination: pagination})
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(authors); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a Api) catalogInsertAuthor(w http.ResponseWriter, r *http.Request) {
+	if !requireRole(w, r, data.RoleEditor) {
+		return
+	}
+
+	var author data.DocumentAuthor
+	if err := json.NewDecoder(r.Body).Decode(&author); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	inserted, err := a.catalog.InsertAuthor(r.Context(), data.InsertAuthorRequest{Author: author})
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(inserted); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a Api) catalogUpdateAuthor(w http.ResponseWriter, r *http.Request) {
+	if !requireRole(w, r, data.RoleEditor) {
+		return
+	}
+
+	authorID, err := entityIDParam(r)
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var author data.DocumentAuthor
+	if err := json.NewDecoder(r.Body).Decode(&author); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	updated, err := a.catalog.UpdateAuthor(r.Context(), data.UpdateAuthorRequest{AuthorID: authorID, Author: author, UserID: requestUserID(r)})
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(updated); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a Api) catalogDeleteAuthor(w http.ResponseWriter, r *http.Request) {
+	if !requireRole(w, r, data.RoleEditor) {
+		return
+	}
+
+	authorID, err := entityIDParam(r)
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := a.catalog.DeleteAuthor(r.Context(), data.DeleteAuthorRequest{AuthorID: authorID, UserID: requestUserID(r)}); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a Api) catalogMergeAuthors(w http.ResponseWriter, r *http.Request) {
+	if !requireRole(w, r, data.RoleEditor) {
+		return
+	}
+
+	authorID, err := entityIDParam(r)
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var request MergeRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	author, err := a.catalog.MergeAuthors(r.Context(), data.MergeAuthorsRequest{AuthorID: authorID, MergedIDs: request.IDs, UserID: requestUserID(r)})
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(author); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a Api) catalogListKinds(w http.ResponseWriter, r *http.Request) {
+	pagination, err := paginationParams(r.URL.Query())
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	kinds, err := a.catalog.ListKinds(r.Context(), data.ListKindsRequest{Pagination: pagination})
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(kinds); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a Api) catalogInsertKind(w http.ResponseWriter, r *http.Request) {
+	if !requireRole(w, r, data.RoleEditor) {
+		return
+	}
+
+	var kind data.DocumentKind
+	if err := json.NewDecoder(r.Body).Decode(&kind); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	inserted, err := a.catalog.InsertKind(r.Context(), data.InsertKindRequest{Kind: kind})
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(inserted); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a Api) catalogUpdateKind(w http.ResponseWriter, r *http.Request) {
+	if !requireRole(w, r, data.RoleEditor) {
+		return
+	}
+
+	kindID, err := entityIDParam(r)
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var kind data.DocumentKind
+	if err := json.NewDecoder(r.Body).Decode(&kind); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	updated, err := a.catalog.UpdateKind(r.Context(), data.UpdateKindRequest{KindID: kindID, Kind: kind, UserID: requestUserID(r)})
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(updated); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a Api) catalogDeleteKind(w http.ResponseWriter, r *http.Request) {
+	if !requireRole(w, r, data.RoleEditor) {
+		return
+	}
+
+	kindID, err := entityIDParam(r)
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := a.catalog.DeleteKind(r.Context(), data.DeleteKindRequest{KindID: kindID, UserID: requestUserID(r)}); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/garugaru/knowledge/server/data"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCatalogApi_ListTags(t *testing.T) {
+	var listed data.ListTagsRequest
+	catalog := mockCatalog{
+		listTags: func(ctx context.Context, request data.ListTagsRequest) (data.ListTagsResponse, error) {
+			listed = request
+			return data.ListTagsResponse{Items: []data.DocumentTag{{Tag: "golang", DocumentCount: 2}}}, nil
+		},
+	}
+
+	api := New(Config{}, catalog)
+	router := api.catalogRouter()
+	r := httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/catalog/tags?page=2&page_size=10", nil))
+
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Equal(t, data.PaginationRequest{Page: 2, PageSize: 10}, listed.Pagination)
+
+	var response data.ListTagsResponse
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&response))
+	require.Equal(t, int64(2), response.Items[0].DocumentCount)
+
+	r = httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/catalog/tags?page=first", nil))
+	require.Equal(t, http.StatusBadRequest, r.Code)
+}
+
+func TestCatalogApi_UpdateAuthor(t *testing.T) {
+	var updated data.UpdateAuthorRequest
+	catalog := mockCatalog{
+		updateAuthor: func(ctx context.Context, request data.UpdateAuthorRequest) (data.DocumentAuthor, error) {
+			updated = request
+			return request.Author, nil
+		},
+	}
+
+	api := New(Config{}, catalog)
+	router := api.catalogRouter()
+	r := httptest.NewRecorder()
+	body := bytes.NewBufferString(`{"name": "Leslie", "surname": "Lamport"}`)
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodPatch, "/catalog/authors/3", body))
+
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Equal(t, data.UpdateAuthorRequest{
+		AuthorID: 3,
+		Author:   data.DocumentAuthor{Name: "Leslie", Surname: "Lamport"},
+	}, updated)
+}
+
+func TestCatalogApi_DeleteKind(t *testing.T) {
+	catalog := mockCatalog{
+		deleteKind: func(ctx context.Context, request data.DeleteKindRequest) error {
+			if request.KindID != 1 {
+				return gorm.ErrRecordNotFound
+			}
+			return nil
+		},
+	}
+
+	api := New(Config{}, catalog)
+	router := api.catalogRouter()
+	r := httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodDelete, "/catalog/kinds/1", nil))
+	require.Equal(t, http.StatusOK, r.Code)
+
+	r = httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodDelete, "/catalog/kinds/2", nil))
+	require.Equal(t, http.StatusBadRequest, r.Code)
+}
+
+func TestCatalogApi_MergeTags(t *testing.T) {
+	var merged data.MergeTagsRequest
+	catalog := mockCatalog{
+		mergeTags: func(ctx context.Context, request data.MergeTagsRequest) (data.DocumentTag, error) {
+			merged = request
+			return data.DocumentTag{Tag: "golang"}, nil
+		},
+	}
+
+	api := New(Config{}, catalog)
+	router := api.catalogRouter()
+	r := httptest.NewRecorder()
+	body := bytes.NewBufferString(`{"ids": [2, 3]}`)
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodPost, "/catalog/tags/1/merge", body))
+
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Equal(t, data.MergeTagsRequest{TagID: 1, MergedIDs: []uint{2, 3}}, merged)
+
+	var tag data.DocumentTag
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&tag))
+	require.Equal(t, "golang", tag.Tag)
+}
+
+func TestCatalogApi_TagTree(t *testing.T) {
+	var moved data.MoveTagRequest
+	var requested data.GetTagTreeRequest
+	parentID := uint(1)
+	catalog := mockCatalog{
+		moveTag: func(ctx context.Context, request data.MoveTagRequest) (data.DocumentTag, error) {
+			moved = request
+			return data.DocumentTag{Tag: "ops/postgres", ParentID: &request.ParentID}, nil
+		},
+		getTagTree: func(ctx context.Context, request data.GetTagTreeRequest) ([]data.DocumentTag, error) {
+			requested = request
+			return []data.DocumentTag{
+				{Tag: "databases", Children: []data.DocumentTag{{Tag: "databases/postgres", ParentID: &parentID}}},
+			}, nil
+		},
+	}
+
+	api := New(Config{}, catalog)
+	router := api.catalogRouter()
+	r := httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodPost, "/catalog/tags/2/move", bytes.NewBufferString(`{"parent_id": 3}`)))
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Equal(t, data.MoveTagRequest{TagID: 2, ParentID: 3}, moved)
+
+	r = httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/catalog/tags/tree?root=1", nil))
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Equal(t, uint(1), requested.TagID)
+
+	var tree []data.DocumentTag
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&tree))
+	require.Len(t, tree, 1)
+	require.Equal(t, "databases/postgres", tree[0].Children[0].Tag)
+}
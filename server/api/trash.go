@@ -0,0 +1,80 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/garugaru/knowledge/server/data"
+	"net/http"
+)
+
+func (a Api) catalogListTrash(w http.ResponseWriter, r *http.Request) {
+	if !requireRole(w, r, data.RoleEditor) {
+		return
+	}
+
+	pagination, err := paginationParams(r.URL.Query())
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	trash, err := a.catalog.ListTrash(r.Context(), data.ListTrashRequest{Pagination: pagination})
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(trash); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a Api) catalogRestoreDocument(w http.ResponseWriter, r *http.Request) {
+	documentID, err := documentIDParam(r)
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if !requireRole(w, r, data.RoleEditor) {
+		return
+	}
+
+	document, err := a.catalog.RestoreDocument(r.Context(), data.RestoreDocumentRequest{
+		DocumentID: documentID,
+		UserID:     requestUserID(r),
+	})
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(document); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a Api) catalogPurgeDocument(w http.ResponseWriter, r *http.Request) {
+	documentID, err := documentIDParam(r)
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if !requireRole(w, r, data.RoleAdmin) {
+		return
+	}
+
+	err = a.catalog.PurgeDocument(r.Context(), data.PurgeDocumentRequest{DocumentID: documentID})
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/garugaru/knowledge/server/data"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCatalogApi_Trash(t *testing.T) {
+	var (
+		listed   data.ListTrashRequest
+		restored data.RestoreDocumentRequest
+		purged   data.PurgeDocumentRequest
+	)
+	catalog := mockCatalog{
+		listTrash: func(ctx context.Context, request data.ListTrashRequest) (data.ListTrashResponse, error) {
+			listed = request
+			return data.ListTrashResponse{Items: []data.Document{{ID: 1, Title: strptr("a")}}}, nil
+		},
+		restoreDocument: func(ctx context.Context, request data.RestoreDocumentRequest) (data.Document, error) {
+			restored = request
+			if request.DocumentID == 2 {
+				return data.Document{}, &data.DuplicateDocumentError{DocumentID: 3, Uri: "a"}
+			}
+			return data.Document{ID: request.DocumentID, Title: strptr("a")}, nil
+		},
+		purgeDocument: func(ctx context.Context, request data.PurgeDocumentRequest) error {
+			purged = request
+			if request.DocumentID == 2 {
+				return gorm.ErrRecordNotFound
+			}
+			return nil
+		},
+	}
+
+	router := New(Config{}, catalog).catalogRouter()
+
+	r := httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/catalog/trash?page_size=5", nil))
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Equal(t, data.ListTrashRequest{Pagination: data.PaginationRequest{Page: 1, PageSize: 5}}, listed)
+
+	var trash data.ListTrashResponse
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&trash))
+	require.Len(t, trash.Items, 1)
+
+	r = httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodPost, "/catalog/trash/1/restore", nil))
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Equal(t, 1, restored.DocumentID)
+
+	var document data.Document
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&document))
+	require.Equal(t, 1, document.ID)
+
+	r = httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodPost, "/catalog/trash/2/restore", nil))
+	require.Equal(t, http.StatusConflict, r.Code, "the uri of the document can be used again while in the trash")
+
+	r = httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodDelete, "/catalog/trash/1", nil))
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Equal(t, data.PurgeDocumentRequest{DocumentID: 1}, purged)
+
+	r = httptest.NewRecorder()
+	router.ServeHTTP(r, httptest.NewRequest(http.MethodDelete, "/catalog/trash/2", nil))
+	require.Equal(t, http.StatusBadRequest, r.Code)
+}
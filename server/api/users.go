@@ -0,0 +1,214 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/garugaru/knowledge/server/data"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+type userKey struct{}
+
+func contextWithUser(ctx context.Context, user data.User) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// requestUser returns the user authenticated for the request, ok is false
+// when authentication is disabled.
+func requestUser(r *http.Request) (data.User, bool) {
+	user, ok := r.Context().Value(userKey{}).(data.User)
+	return user, ok
+}
+
+// requestUserID is the id of the request user, zero without authentication.
+func requestUserID(r *http.Request) uint {
+	user, _ := requestUser(r)
+	return user.ID
+}
+
+// requireRole writes a forbidden error when the request user lacks role.
+func requireRole(w http.ResponseWriter, r *http.Request, role data.Role) bool {
+	user, ok := requestUser(r)
+	if ok && !user.Role.Includes(role) {
+		httpErr(w, fmt.Errorf("the %s role is required", role), http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// requireDocumentOwner writes a forbidden error unless the request user owns
+// the document or is an editor.
+func (a Api) requireDocumentOwner(w http.ResponseWriter, r *http.Request, documentID int) bool {
+	user, ok := requestUser(r)
+	if !ok || user.Role.Includes(data.RoleEditor) {
+		return true
+	}
+
+	document, err := a.catalog.GetDocument(r.Context(), data.GetDocumentRequest{DocumentID: documentID})
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return false
+	}
+
+	if document.OwnerID == 0 || document.OwnerID != user.ID {
+		httpErr(w, errors.New("only the owner or an editor can modify the document"), http.StatusForbidden)
+		return false
+	}
+
+	return true
+}
+
+func (a Api) adminRouter() *mux.Router {
+	router := mux.NewRouter()
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// the users cannot be managed when authentication is disabled
+			if _, ok := requestUser(r); !ok {
+				httpErr(w, errors.New("the admin routes require authentication"), http.StatusForbidden)
+				return
+			}
+			if requireRole(w, r, data.RoleAdmin) {
+				next.ServeHTTP(w, r)
+			}
+		})
+	})
+	router.Path("/admin/users").Methods(http.MethodGet).HandlerFunc(a.adminListUsers)
+	router.Path("/admin/users").Methods(http.MethodPost).HandlerFunc(a.adminInsertUser)
+	router.Path("/admin/users/{id:[0-9]+}").Methods(http.MethodGet).HandlerFunc(a.adminGetUser)
+	router.Path("/admin/users/{id:[0-9]+}").Methods(http.MethodPatch).HandlerFunc(a.adminUpdateUser)
+	router.Path("/admin/users/{id:[0-9]+}").Methods(http.MethodDelete).HandlerFunc(a.adminDeleteUser)
+	return router
+}
+
+func (a Api) catalogCurrentUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := requestUser(r)
+	if !ok {
+		httpErr(w, errors.New("authentication is disabled"), http.StatusNotFound)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(user); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a Api) adminListUsers(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	pagination, err := paginationParams(params)
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var request = data.ListUsersRequest{
+		Pagination: pagination,
+	}
+
+	users, err := a.catalog.ListUsers(r.Context(), request)
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(users); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a Api) adminInsertUser(w http.ResponseWriter, r *http.Request) {
+	var user data.User
+	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	inserted, err := a.catalog.InsertUser(r.Context(), data.InsertUserRequest{User: user})
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(inserted); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a Api) adminGetUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := entityIDParam(r)
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	user, err := a.catalog.GetUser(r.Context(), data.GetUserRequest{UserID: userID})
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(user); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a Api) adminUpdateUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := entityIDParam(r)
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var user data.User
+	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	updated, err := a.catalog.UpdateUser(r.Context(), data.UpdateUserRequest{
+		UserID: userID,
+		Role:   user.Role,
+		Name:   user.Name,
+	})
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(updated); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (a Api) adminDeleteUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := entityIDParam(r)
+	if err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := a.catalog.DeleteUser(r.Context(), data.DeleteUserRequest{UserID: userID}); err != nil {
+		httpErr(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
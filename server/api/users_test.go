@@ -0,0 +1,141 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/garugaru/knowledge/server/auth"
+	"github.com/garugaru/knowledge/server/data"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPI_DocumentOwnership(t *testing.T) {
+	users := map[string]data.User{
+		"owner":  {Model: gorm.Model{ID: 1}, Subject: "owner", Role: data.RoleReader},
+		"other":  {Model: gorm.Model{ID: 2}, Subject: "other", Role: data.RoleReader},
+		"editor": {Model: gorm.Model{ID: 3}, Subject: "editor", Role: data.RoleEditor},
+	}
+
+	var inserted data.Document
+	var deleted []int
+	catalog := mockCatalog{
+		resolveUser: func(ctx context.Context, request data.ResolveUserRequest) (data.User, error) {
+			return users[request.Subject], nil
+		},
+		getDocument: func(ctx context.Context, request data.GetDocumentRequest) (data.Document, error) {
+			return data.Document{ID: request.DocumentID, OwnerID: 1}, nil
+		},
+		insertDocument: func(ctx context.Context, request data.InsertDocumentRequest) error {
+			inserted = request.Document
+			return nil
+		},
+		deleteDocument: func(ctx context.Context, request data.DeleteDocumentRequest) error {
+			deleted = append(deleted, request.DocumentID)
+			return nil
+		},
+	}
+
+	api := New(Config{
+		Authenticator: auth.NewStaticTokens(
+			auth.StaticToken{Token: "owner-token", Subject: "owner", Scopes: []string{auth.ScopeWrite}},
+			auth.StaticToken{Token: "other-token", Subject: "other", Scopes: []string{auth.ScopeWrite}},
+			auth.StaticToken{Token: "editor-token", Subject: "editor", Scopes: []string{auth.ScopeWrite}},
+			auth.StaticToken{Token: "admin-token", Subject: "admin", Scopes: []string{auth.ScopeWrite}},
+		),
+		Admins: []string{"admin"},
+	}, catalog)
+	router := api.router()
+
+	request := func(method string, target string, token string, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+		r.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, r)
+		return recorder
+	}
+
+	r := request(http.MethodGet, "/catalog/documents/1", "other-token", "")
+	require.Equal(t, http.StatusOK, r.Code, "readers see every document")
+
+	r = request(http.MethodDelete, "/catalog/documents/1", "other-token", "")
+	require.Equal(t, http.StatusForbidden, r.Code)
+	var apiError Error
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&apiError))
+	require.Equal(t, "only the owner or an editor can modify the document", apiError.Message)
+
+	r = request(http.MethodDelete, "/catalog/documents/1", "owner-token", "")
+	require.Equal(t, http.StatusOK, r.Code)
+
+	r = request(http.MethodDelete, "/catalog/documents/2", "editor-token", "")
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Equal(t, []int{1, 2}, deleted)
+
+	r = request(http.MethodPost, "/catalog/documents", "owner-token", `{"title": "paxos"}`)
+	require.Equal(t, http.StatusForbidden, r.Code)
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&apiError))
+	require.Equal(t, "the editor role is required", apiError.Message)
+
+	r = request(http.MethodPost, "/catalog/documents", "editor-token", `{"title": "paxos", "owner_id": 1}`)
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Equal(t, uint(3), inserted.OwnerID, "the owner is the user inserting the document")
+
+	r = request(http.MethodGet, "/catalog/me", "editor-token", "")
+	require.Equal(t, http.StatusOK, r.Code)
+	var me data.User
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&me))
+	require.Equal(t, "editor", me.Subject)
+}
+
+func TestAPI_AdminUsers(t *testing.T) {
+	var updated data.UpdateUserRequest
+	catalog := mockCatalog{
+		resolveUser: func(ctx context.Context, request data.ResolveUserRequest) (data.User, error) {
+			return data.User{Subject: request.Subject, Role: data.RoleEditor}, nil
+		},
+		listUsers: func(ctx context.Context, request data.ListUsersRequest) (data.ListUsersResponse, error) {
+			return data.ListUsersResponse{Items: []data.User{{Subject: "admin", Role: data.RoleAdmin}}}, nil
+		},
+		updateUser: func(ctx context.Context, request data.UpdateUserRequest) (data.User, error) {
+			updated = request
+			return data.User{Subject: "editor", Role: request.Role}, nil
+		},
+	}
+
+	api := New(Config{
+		Authenticator: auth.NewStaticTokens(
+			auth.StaticToken{Token: "editor-token", Subject: "editor", Scopes: []string{auth.ScopeWrite}},
+			auth.StaticToken{Token: "admin-token", Subject: "admin", Scopes: []string{auth.ScopeWrite}},
+		),
+		Admins: []string{"admin"},
+	}, catalog)
+	router := api.router()
+
+	request := func(method string, target string, token string, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+		r.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, r)
+		return recorder
+	}
+
+	r := request(http.MethodGet, "/admin/users", "editor-token", "")
+	require.Equal(t, http.StatusForbidden, r.Code)
+
+	r = request(http.MethodGet, "/admin/users", "admin-token", "")
+	require.Equal(t, http.StatusOK, r.Code)
+	var users data.ListUsersResponse
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&users))
+	require.Len(t, users.Items, 1)
+
+	r = request(http.MethodPatch, "/admin/users/7", "admin-token", `{"role": "admin"}`)
+	require.Equal(t, http.StatusOK, r.Code)
+	require.Equal(t, data.UpdateUserRequest{UserID: 7, Role: data.RoleAdmin}, updated)
+
+	r = httptest.NewRecorder()
+	New(Config{}, catalog).router().ServeHTTP(r, httptest.NewRequest(http.MethodGet, "/admin/users", nil))
+	require.Equal(t, http.StatusForbidden, r.Code, "the admin routes are closed without authentication")
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const (
+	ScopeRead  = "catalog:read"
+	ScopeWrite = "catalog:write"
+)
+
+var (
+	// ErrNoCredentials is returned when the request carries no credentials
+	// supported by the authenticator.
+	ErrNoCredentials = errors.New("missing credentials")
+	// ErrInvalidCredentials is returned when the credentials are rejected.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Principal is the identity authenticated for a request.
+type Principal struct {
+	Subject string
+	// Scopes restrict what the principal can do, an empty list grants nothing
+	Scopes []string
+}
+
+// HasScope reports whether the principal was granted scope, the write scope
+// implies the read one.
+func (p Principal) HasScope(scope string) bool {
+	for _, granted := range p.Scopes {
+		if granted == scope || (scope == ScopeRead && granted == ScopeWrite) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type Authenticator interface {
+	Authenticate(r *http.Request) (Principal, error)
+}
+
+// Chain tries every authenticator in order, returning the first principal
+// authenticated.
+type Chain []Authenticator
+
+func (c Chain) Authenticate(r *http.Request) (Principal, error) {
+	err := ErrNoCredentials
+	for _, authenticator := range c {
+		principal, authErr := authenticator.Authenticate(r)
+		if authErr == nil {
+			return principal, nil
+		}
+
+		// rejected credentials are more relevant than missing ones
+		if !errors.Is(authErr, ErrNoCredentials) {
+			err = authErr
+		}
+	}
+
+	return Principal{}, err
+}
+
+// BearerToken returns the token of the Authorization header.
+func BearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	const prefix = "bearer "
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", false
+	}
+
+	return strings.TrimSpace(header[len(prefix):]), true
+}
+
+type principalKey struct{}
+
+func ContextWithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
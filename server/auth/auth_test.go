@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func bearerRequest(token string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/catalog/documents", nil)
+	if len(token) != 0 {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	return r
+}
+
+func TestPrincipal_HasScope(t *testing.T) {
+	require.False(t, Principal{}.HasScope(ScopeRead), "principals without scopes are granted nothing")
+	require.True(t, Principal{Scopes: []string{ScopeWrite}}.HasScope(ScopeRead))
+	require.True(t, Principal{Scopes: []string{ScopeRead}}.HasScope(ScopeRead))
+	require.False(t, Principal{Scopes: []string{ScopeRead}}.HasScope(ScopeWrite))
+	require.False(t, Principal{Scopes: []string{"other"}}.HasScope(ScopeRead))
+}
+
+func TestBearerToken(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	_, ok := BearerToken(r)
+	require.False(t, ok)
+
+	r.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+	_, ok = BearerToken(r)
+	require.False(t, ok)
+
+	r.Header.Set("Authorization", "bearer  token ")
+	token, ok := BearerToken(r)
+	require.True(t, ok)
+	require.Equal(t, "token", token)
+}
+
+func TestStaticTokens(t *testing.T) {
+	tokens := NewStaticTokens(
+		StaticToken{Token: "ci-token", Subject: "ci"},
+		StaticToken{Token: "reader-token", Subject: "reader", Scopes: []string{ScopeRead}},
+	)
+
+	principal, err := tokens.Authenticate(bearerRequest("reader-token"))
+	require.NoError(t, err)
+	require.Equal(t, Principal{Subject: "reader", Scopes: []string{ScopeRead}}, principal)
+
+	_, err = tokens.Authenticate(bearerRequest("ci-token-but-longer"))
+	require.ErrorIs(t, err, ErrInvalidCredentials)
+
+	_, err = tokens.Authenticate(bearerRequest(""))
+	require.ErrorIs(t, err, ErrNoCredentials)
+}
+
+func TestChain(t *testing.T) {
+	jwt, err := NewJWT(JWTConfig{Algorithm: AlgorithmHS256, Secret: []byte("secret")})
+	require.NoError(t, err)
+	chain := Chain{NewStaticTokens(StaticToken{Token: "ci-token", Subject: "ci"}), jwt}
+
+	principal, err := chain.Authenticate(bearerRequest("ci-token"))
+	require.NoError(t, err)
+	require.Equal(t, "ci", principal.Subject)
+
+	principal, err = chain.Authenticate(bearerRequest(signHS256(t, []byte("secret"), map[string]interface{}{"sub": "alice"})))
+	require.NoError(t, err)
+	require.Equal(t, "alice", principal.Subject)
+
+	_, err = chain.Authenticate(bearerRequest("unknown"))
+	require.ErrorIs(t, err, ErrInvalidCredentials)
+
+	_, err = chain.Authenticate(bearerRequest(""))
+	require.ErrorIs(t, err, ErrNoCredentials)
+}
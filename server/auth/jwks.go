@@ -0,0 +1,165 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// jwksMinRefresh limits how often an unknown key id triggers a refresh of a remote key set
+const jwksMinRefresh = time.Minute
+
+// KeySet resolves the public keys verifying RS256 tokens.
+type KeySet interface {
+	Key(ctx context.Context, keyID string) (*rsa.PublicKey, error)
+}
+
+type jwk struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+}
+
+// parseJWKS reads the RSA signing keys of a JSON Web Key Set.
+func parseJWKS(content io.Reader) (map[string]*rsa.PublicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(content).Decode(&set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, key := range set.Keys {
+		if key.KeyType != "RSA" || (len(key.Use) != 0 && key.Use != "sig") {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus of key %s: %w", key.KeyID, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent of key %s: %w", key.KeyID, err)
+		}
+
+		keys[key.KeyID] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	return keys, nil
+}
+
+func lookupKey(keys map[string]*rsa.PublicKey, keyID string) (*rsa.PublicKey, error) {
+	if key, present := keys[keyID]; present {
+		return key, nil
+	}
+
+	// tokens without key id are accepted when there is a single key
+	if len(keyID) == 0 && len(keys) == 1 {
+		for _, key := range keys {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown key %q", keyID)
+}
+
+// StaticKeySet is a key set loaded once, typically from a file.
+type StaticKeySet struct {
+	keys map[string]*rsa.PublicKey
+}
+
+func LoadJWKSFile(path string) (*StaticKeySet, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	keys, err := parseJWKS(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StaticKeySet{keys: keys}, nil
+}
+
+func (s *StaticKeySet) Key(_ context.Context, keyID string) (*rsa.PublicKey, error) {
+	return lookupKey(s.keys, keyID)
+}
+
+// RemoteKeySet downloads the key set from a url, refreshing it when a token
+// is signed with an unknown key to follow key rotations.
+type RemoteKeySet struct {
+	url    string
+	client *http.Client
+
+	mu      sync.Mutex
+	keys    map[string]*rsa.PublicKey
+	fetched time.Time
+}
+
+func NewRemoteKeySet(url string) *RemoteKeySet {
+	return &RemoteKeySet{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (s *RemoteKeySet) Key(ctx context.Context, keyID string) (*rsa.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if key, err := lookupKey(s.keys, keyID); err == nil {
+		return key, nil
+	}
+
+	if !s.fetched.IsZero() && time.Since(s.fetched) < jwksMinRefresh {
+		return nil, fmt.Errorf("unknown key %q", keyID)
+	}
+
+	if err := s.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	return lookupKey(s.keys, keyID)
+}
+
+func (s *RemoteKeySet) refresh(ctx context.Context) error {
+	s.fetched = time.Now()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New("fetching the key set failed with status " + resp.Status)
+	}
+
+	keys, err := parseJWKS(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	s.keys = keys
+	return nil
+}
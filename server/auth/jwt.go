@@ -0,0 +1,201 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+)
+
+type JWTConfig struct {
+	// Algorithm is the only signing algorithm accepted, HS256 or RS256
+	Algorithm string
+	// Secret verifies HS256 tokens
+	Secret []byte
+	// Keys verify RS256 tokens
+	Keys KeySet
+	// Issuer and Audience are checked when defined
+	Issuer   string
+	Audience string
+	// Leeway tolerates clock skew checking the token expiration
+	Leeway time.Duration
+	// DefaultScopes are granted to the tokens without scope claims, they are
+	// granted nothing when empty
+	DefaultScopes []string
+}
+
+// JWT authenticates bearer JSON Web Tokens signed with HS256 or RS256, the
+// scopes are read from the scope or scp claims.
+type JWT struct {
+	config JWTConfig
+	now    func() time.Time
+}
+
+func NewJWT(config JWTConfig) (*JWT, error) {
+	switch config.Algorithm {
+	case AlgorithmHS256:
+		if len(config.Secret) == 0 {
+			return nil, errors.New("HS256 tokens require a secret")
+		}
+	case AlgorithmRS256:
+		if config.Keys == nil {
+			return nil, errors.New("RS256 tokens require a key set")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported jwt algorithm %s", config.Algorithm)
+	}
+
+	return &JWT{config: config, now: time.Now}, nil
+}
+
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+func (j *JWT) Authenticate(r *http.Request) (Principal, error) {
+	token, ok := BearerToken(r)
+	if !ok {
+		return Principal{}, ErrNoCredentials
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Principal{}, ErrInvalidCredentials
+	}
+
+	var header jwtHeader
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return Principal{}, err
+	}
+
+	// the algorithm is fixed by the configuration, never chosen by the token
+	if header.Algorithm != j.config.Algorithm {
+		return Principal{}, fmt.Errorf("%w: unexpected algorithm %s", ErrInvalidCredentials, header.Algorithm)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: malformed signature", ErrInvalidCredentials)
+	}
+
+	if err := j.verify(r, header, parts[0]+"."+parts[1], signature); err != nil {
+		return Principal{}, err
+	}
+
+	var claims map[string]interface{}
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return Principal{}, err
+	}
+
+	if err := j.validate(claims); err != nil {
+		return Principal{}, err
+	}
+
+	subject, _ := claims["sub"].(string)
+	scopes := jwtScopes(claims)
+	if len(scopes) == 0 {
+		scopes = j.config.DefaultScopes
+	}
+	return Principal{Subject: subject, Scopes: scopes}, nil
+}
+
+func (j *JWT) verify(r *http.Request, header jwtHeader, signed string, signature []byte) error {
+	switch j.config.Algorithm {
+	case AlgorithmHS256:
+		mac := hmac.New(sha256.New, j.config.Secret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return fmt.Errorf("%w: invalid signature", ErrInvalidCredentials)
+		}
+	case AlgorithmRS256:
+		key, err := j.config.Keys.Key(r.Context(), header.KeyID)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidCredentials, err)
+		}
+		digest := sha256.Sum256([]byte(signed))
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+			return fmt.Errorf("%w: invalid signature", ErrInvalidCredentials)
+		}
+	}
+
+	return nil
+}
+
+func (j *JWT) validate(claims map[string]interface{}) error {
+	now := j.now()
+
+	if exp, ok := claims["exp"].(float64); ok && now.After(time.Unix(int64(exp), 0).Add(j.config.Leeway)) {
+		return fmt.Errorf("%w: token expired", ErrInvalidCredentials)
+	}
+
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(j.config.Leeway).Before(time.Unix(int64(nbf), 0)) {
+		return fmt.Errorf("%w: token not valid yet", ErrInvalidCredentials)
+	}
+
+	if len(j.config.Issuer) != 0 && claims["iss"] != j.config.Issuer {
+		return fmt.Errorf("%w: unexpected issuer", ErrInvalidCredentials)
+	}
+
+	if len(j.config.Audience) != 0 && !jwtAudience(claims, j.config.Audience) {
+		return fmt.Errorf("%w: unexpected audience", ErrInvalidCredentials)
+	}
+
+	return nil
+}
+
+func decodeJWTPart(part string, v interface{}) error {
+	decoded, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return fmt.Errorf("%w: malformed token", ErrInvalidCredentials)
+	}
+
+	if err := json.Unmarshal(decoded, v); err != nil {
+		return fmt.Errorf("%w: malformed token", ErrInvalidCredentials)
+	}
+
+	return nil
+}
+
+// jwtAudience reports whether the aud claim, a string or a list, contains audience.
+func jwtAudience(claims map[string]interface{}, audience string) bool {
+	switch aud := claims["aud"].(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, value := range aud {
+			if value == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func jwtScopes(claims map[string]interface{}) []string {
+	if scope, ok := claims["scope"].(string); ok {
+		return strings.Fields(scope)
+	}
+
+	var scopes []string
+	if scp, ok := claims["scp"].([]interface{}); ok {
+		for _, value := range scp {
+			if scope, ok := value.(string); ok {
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+	return scopes
+}
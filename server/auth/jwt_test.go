@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func encodeJWTPart(t *testing.T, v interface{}) string {
+	encoded, err := json.Marshal(v)
+	require.NoError(t, err)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+func signHS256(t *testing.T, secret []byte, claims map[string]interface{}) string {
+	signed := encodeJWTPart(t, map[string]string{"alg": "HS256", "typ": "JWT"}) + "." + encodeJWTPart(t, claims)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, keyID string, claims map[string]interface{}) string {
+	signed := encodeJWTPart(t, map[string]string{"alg": "RS256", "typ": "JWT", "kid": keyID}) + "." + encodeJWTPart(t, claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func jwks(keys map[string]*rsa.PrivateKey) string {
+	var entries []string
+	for keyID, key := range keys {
+		entries = append(entries, fmt.Sprintf(`{"kty": "RSA", "use": "sig", "kid": %q, "n": %q, "e": %q}`,
+			keyID,
+			base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		))
+	}
+	return `{"keys": [` + strings.Join(entries, ",") + `, {"kty": "EC", "kid": "ignored"}]}`
+}
+
+func TestJWT_HS256(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Unix(1600000000, 0)
+
+	jwt, err := NewJWT(JWTConfig{
+		Algorithm: AlgorithmHS256,
+		Secret:    secret,
+		Issuer:    "https://issuer.example.com",
+		Audience:  "knowledge",
+		Leeway:    time.Minute,
+	})
+	require.NoError(t, err)
+	jwt.now = func() time.Time { return now }
+
+	valid := map[string]interface{}{
+		"sub":   "alice",
+		"iss":   "https://issuer.example.com",
+		"aud":   []string{"other", "knowledge"},
+		"exp":   now.Add(time.Hour).Unix(),
+		"scope": "catalog:read catalog:write",
+	}
+
+	principal, err := jwt.Authenticate(bearerRequest(signHS256(t, secret, valid)))
+	require.NoError(t, err)
+	require.Equal(t, Principal{Subject: "alice", Scopes: []string{ScopeRead, ScopeWrite}}, principal)
+
+	withClaims := func(mutate func(map[string]interface{})) string {
+		claims := make(map[string]interface{})
+		for key, value := range valid {
+			claims[key] = value
+		}
+		mutate(claims)
+		return signHS256(t, secret, claims)
+	}
+
+	_, err = jwt.Authenticate(bearerRequest(withClaims(func(claims map[string]interface{}) {
+		claims["exp"] = now.Add(-30 * time.Second).Unix()
+	})))
+	require.NoError(t, err, "expiration tolerates the leeway")
+
+	unscoped := withClaims(func(claims map[string]interface{}) { delete(claims, "scope") })
+	principal, err = jwt.Authenticate(bearerRequest(unscoped))
+	require.NoError(t, err)
+	require.Empty(t, principal.Scopes, "tokens without scopes are granted nothing")
+
+	jwt.config.DefaultScopes = []string{ScopeRead}
+	principal, err = jwt.Authenticate(bearerRequest(unscoped))
+	require.NoError(t, err)
+	require.Equal(t, []string{ScopeRead}, principal.Scopes)
+
+	invalid := map[string]func(map[string]interface{}){
+		"expired":     func(claims map[string]interface{}) { claims["exp"] = now.Add(-2 * time.Minute).Unix() },
+		"not before":  func(claims map[string]interface{}) { claims["nbf"] = now.Add(2 * time.Minute).Unix() },
+		"issuer":      func(claims map[string]interface{}) { claims["iss"] = "https://evil.example.com" },
+		"audience":    func(claims map[string]interface{}) { claims["aud"] = "other" },
+		"no audience": func(claims map[string]interface{}) { delete(claims, "aud") },
+	}
+	for name, mutate := range invalid {
+		_, err := jwt.Authenticate(bearerRequest(withClaims(mutate)))
+		require.ErrorIs(t, err, ErrInvalidCredentials, name)
+	}
+
+	_, err = jwt.Authenticate(bearerRequest(signHS256(t, []byte("wrong"), valid)))
+	require.ErrorIs(t, err, ErrInvalidCredentials)
+
+	unsigned := encodeJWTPart(t, map[string]string{"alg": "none"}) + "." + encodeJWTPart(t, valid) + "."
+	_, err = jwt.Authenticate(bearerRequest(unsigned))
+	require.ErrorIs(t, err, ErrInvalidCredentials)
+
+	_, err = jwt.Authenticate(bearerRequest("not-a-jwt"))
+	require.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestJWT_RS256(t *testing.T) {
+	first, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	second, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var (
+		rotated  int32
+		requests int32
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if atomic.LoadInt32(&rotated) == 1 {
+			fmt.Fprint(w, jwks(map[string]*rsa.PrivateKey{"first": first, "second": second}))
+			return
+		}
+		fmt.Fprint(w, jwks(map[string]*rsa.PrivateKey{"first": first}))
+	}))
+	defer server.Close()
+
+	keys := NewRemoteKeySet(server.URL)
+	jwt, err := NewJWT(JWTConfig{Algorithm: AlgorithmRS256, Keys: keys})
+	require.NoError(t, err)
+
+	claims := map[string]interface{}{"sub": "bob", "scp": []string{ScopeRead}}
+
+	principal, err := jwt.Authenticate(bearerRequest(signRS256(t, first, "first", claims)))
+	require.NoError(t, err)
+	require.Equal(t, Principal{Subject: "bob", Scopes: []string{ScopeRead}}, principal)
+
+	_, err = jwt.Authenticate(bearerRequest(signRS256(t, second, "first", claims)))
+	require.ErrorIs(t, err, ErrInvalidCredentials)
+
+	atomic.StoreInt32(&rotated, 1)
+	_, err = jwt.Authenticate(bearerRequest(signRS256(t, second, "second", claims)))
+	require.ErrorIs(t, err, ErrInvalidCredentials, "the key set is not refreshed more than once a minute")
+
+	keys.fetched = time.Now().Add(-2 * jwksMinRefresh)
+	_, err = jwt.Authenticate(bearerRequest(signRS256(t, second, "second", claims)))
+	require.NoError(t, err, "unknown keys refresh the key set")
+	require.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	hs256, err := NewJWT(JWTConfig{Algorithm: AlgorithmHS256, Secret: []byte("secret")})
+	require.NoError(t, err)
+	_, err = hs256.Authenticate(bearerRequest(signRS256(t, first, "first", claims)))
+	require.ErrorIs(t, err, ErrInvalidCredentials, "the algorithm cannot be chosen by the token")
+}
+
+func TestLoadJWKSFile(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	jwksPath := path.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(jwksPath, []byte(jwks(map[string]*rsa.PrivateKey{"only": key})), 0600))
+
+	keys, err := LoadJWKSFile(jwksPath)
+	require.NoError(t, err)
+
+	jwt, err := NewJWT(JWTConfig{Algorithm: AlgorithmRS256, Keys: keys})
+	require.NoError(t, err)
+
+	_, err = jwt.Authenticate(bearerRequest(signRS256(t, key, "", map[string]interface{}{"sub": "carol"})))
+	require.NoError(t, err, "tokens without key id use the single key of the set")
+
+	_, err = LoadJWKSFile(path.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+}
@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+)
+
+type StaticToken struct {
+	Token   string
+	Subject string
+	Scopes  []string
+}
+
+// StaticTokens authenticates bearer tokens against a fixed list of api tokens.
+type StaticTokens struct {
+	tokens []StaticToken
+}
+
+func NewStaticTokens(tokens ...StaticToken) *StaticTokens {
+	return &StaticTokens{tokens: tokens}
+}
+
+func (s *StaticTokens) Authenticate(r *http.Request) (Principal, error) {
+	token, ok := BearerToken(r)
+	if !ok {
+		return Principal{}, ErrNoCredentials
+	}
+
+	// tokens are compared by hash, in constant time, to avoid leaking their length
+	received := sha256.Sum256([]byte(token))
+	for _, static := range s.tokens {
+		expected := sha256.Sum256([]byte(static.Token))
+		if subtle.ConstantTimeCompare(received[:], expected[:]) == 1 {
+			return Principal{Subject: static.Subject, Scopes: static.Scopes}, nil
+		}
+	}
+
+	return Principal{}, ErrInvalidCredentials
+}
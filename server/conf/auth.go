@@ -0,0 +1,23 @@
+package conf
+
+type AuthProviderType string
+
+const (
+	AuthProviderStatic AuthProviderType = "static"
+	AuthProviderJWT    AuthProviderType = "jwt"
+)
+
+// Auth configures the authentication of the catalog api, it stays open when
+// no provider is defined.
+type Auth struct {
+	Providers []AuthProvider `json:"providers" yaml:"providers"`
+	// DefaultRole is given to the users on their first request, reader by default
+	DefaultRole string `json:"default_role" yaml:"default_role"`
+	// Admins lists the subjects always granted the admin role
+	Admins []string `json:"admins" yaml:"admins"`
+}
+
+type AuthProvider struct {
+	Type   AuthProviderType       `json:"type" yaml:"type"`
+	Params map[string]interface{} `json:"params" yaml:"params"`
+}
@@ -1,5 +1,7 @@
 package conf
 
+import "time"
+
 type DatabaseType string
 
 const (
@@ -8,11 +10,96 @@ const (
 	DatabaseTypeMySql    DatabaseType = "mysql"
 )
 
+type SearchType string
+
+const (
+	SearchTypeDatabase SearchType = "database"
+	SearchTypeEmbedded SearchType = "embedded"
+)
+
 type Catalog struct {
-	Database Database `json:"database" yaml:"database"`
+	Database   Database   `json:"database" yaml:"database"`
+	Search     Search     `json:"search" yaml:"search"`
+	Extraction Extraction `json:"extraction" yaml:"extraction"`
+	Fetch      Fetch      `json:"fetch" yaml:"fetch"`
+	LinkCheck  LinkCheck  `json:"link_check" yaml:"link_check"`
+	Duplicates Duplicates `json:"duplicates" yaml:"duplicates"`
+	Trash      Trash      `json:"trash" yaml:"trash"`
+	Events     Events     `json:"events" yaml:"events"`
+	Export     Export     `json:"export" yaml:"export"`
+	Import     Import     `json:"import" yaml:"import"`
 }
 
 type Database struct {
 	Type   DatabaseType           `json:"type" yaml:"type"`
 	Params map[string]interface{} `json:"params" yaml:"params"`
 }
+
+type Search struct {
+	Type   SearchType             `json:"type" yaml:"type"`
+	Params map[string]interface{} `json:"params" yaml:"params"`
+	// SaveInterval is the time between two saves of the embedded index, a
+	// default is used when 0
+	SaveInterval time.Duration `json:"save_interval" yaml:"save_interval"`
+}
+
+// Extraction configures the background text extraction, it is disabled when
+// no workers are defined.
+type Extraction struct {
+	Workers int `json:"workers" yaml:"workers"`
+	// MaxSize is the maximum size in bytes of the extracted contents, a default is used when 0
+	MaxSize int64 `json:"max_size" yaml:"max_size"`
+}
+
+// Fetch configures the requests to the document uris, made for their
+// metadata, extraction and link checks.
+type Fetch struct {
+	// AllowPrivate allows the uris resolving to loopback, private or link-local
+	// addresses, only meant for catalogs of internal documents
+	AllowPrivate bool `json:"allow_private" yaml:"allow_private"`
+}
+
+// LinkCheck configures the periodic check of the document uris, it is
+// disabled when no interval is defined.
+type LinkCheck struct {
+	Interval  time.Duration `json:"interval" yaml:"interval"`
+	Workers   int           `json:"workers" yaml:"workers"`
+	HostDelay time.Duration `json:"host_delay" yaml:"host_delay"`
+}
+
+// Duplicates configures the periodic detection of similar documents, it is
+// disabled when no interval is defined.
+type Duplicates struct {
+	Interval  time.Duration `json:"interval" yaml:"interval"`
+	Threshold float64       `json:"threshold" yaml:"threshold"`
+}
+
+// Trash configures the periodic purge of the deleted documents, it is
+// disabled when no interval is defined.
+type Trash struct {
+	Retention time.Duration `json:"retention" yaml:"retention"`
+	Interval  time.Duration `json:"interval" yaml:"interval"`
+}
+
+// Events configures the relay of the stored events to the event streams, the
+// defaults are used for the durations left to 0.
+type Events struct {
+	Interval  time.Duration `json:"interval" yaml:"interval"`
+	Retention time.Duration `json:"retention" yaml:"retention"`
+	// StreamTimeout is the time an event stream lasts, one hour when 0
+	StreamTimeout time.Duration `json:"stream_timeout" yaml:"stream_timeout"`
+}
+
+// Export configures the catalog exports served by the api, they are not
+// bound to the server write timeout.
+type Export struct {
+	// Timeout is the time an export can take, one hour when 0
+	Timeout time.Duration `json:"timeout" yaml:"timeout"`
+}
+
+// Import configures the bulk inserts served by the api, they are not bound to
+// the server read and write timeouts.
+type Import struct {
+	// Timeout is the time a bulk insert can take, one hour when 0
+	Timeout time.Duration `json:"timeout" yaml:"timeout"`
+}
@@ -2,4 +2,6 @@ package conf
 
 type Conf struct {
 	Catalog Catalog `json:"catalog" yaml:"catalog"`
+	Storage Storage `json:"storage" yaml:"storage"`
+	Auth    Auth    `json:"auth" yaml:"auth"`
 }
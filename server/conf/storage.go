@@ -0,0 +1,19 @@
+package conf
+
+import "time"
+
+type StorageType string
+
+const (
+	StorageTypeLocal StorageType = "local"
+	StorageTypeS3    StorageType = "s3"
+)
+
+type Storage struct {
+	Type   StorageType            `json:"type" yaml:"type"`
+	Params map[string]interface{} `json:"params" yaml:"params"`
+	// MaxSize is the maximum size in bytes of the uploaded contents, a default is used when 0
+	MaxSize int64 `json:"max_size" yaml:"max_size"`
+	// Timeout is the time an upload can take, one hour when 0
+	Timeout time.Duration `json:"timeout" yaml:"timeout"`
+}
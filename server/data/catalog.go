@@ -1,38 +1,354 @@
 package data
 
-import "context"
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+var (
+	ErrContentNotFound   = errors.New("document has no content")
+	ErrInvalidPagination = errors.New("page and page size must be positive")
+)
 
 type Catalog interface {
 	Init() error
 	InsertDocument(context.Context, InsertDocumentRequest) error
+	InsertDocuments(context.Context, InsertDocumentsRequest) (InsertDocumentsResponse, error)
 	GetDocument(context.Context, GetDocumentRequest) (Document, error)
+	UpdateDocument(context.Context, UpdateDocumentRequest) (Document, error)
+	DeleteDocument(context.Context, DeleteDocumentRequest) error
+	ListTrash(context.Context, ListTrashRequest) (ListTrashResponse, error)
+	RestoreDocument(context.Context, RestoreDocumentRequest) (Document, error)
+	PurgeDocument(context.Context, PurgeDocumentRequest) error
+	ListEvents(context.Context, ListEventsRequest) (ListEventsResponse, error)
+	SubscribeEvents(context.Context) <-chan CatalogEvent
+	RefreshDocument(context.Context, RefreshDocumentRequest) (Document, error)
+	PutDocumentContent(context.Context, PutDocumentContentRequest) (Document, error)
+	GetDocumentContent(context.Context, GetDocumentContentRequest) (GetDocumentContentResponse, error)
 	ListDocuments(context.Context, ListDocumentsRequest) (ListDocumentsResponse, error)
+	ExportCatalog(context.Context, ExportCatalogRequest) error
+	ListDuplicates(context.Context, ListDuplicatesRequest) (ListDuplicatesResponse, error)
+	ListRevisions(context.Context, ListRevisionsRequest) (ListRevisionsResponse, error)
+	DiffRevisions(context.Context, DiffRevisionsRequest) (RevisionDiff, error)
+	RestoreRevision(context.Context, RestoreRevisionRequest) (Document, error)
+	ResolveUser(context.Context, ResolveUserRequest) (User, error)
+	InsertUser(context.Context, InsertUserRequest) (User, error)
+	GetUser(context.Context, GetUserRequest) (User, error)
+	UpdateUser(context.Context, UpdateUserRequest) (User, error)
+	DeleteUser(context.Context, DeleteUserRequest) error
+	ListUsers(context.Context, ListUsersRequest) (ListUsersResponse, error)
+	InsertCollection(context.Context, InsertCollectionRequest) (Collection, error)
+	GetCollection(context.Context, GetCollectionRequest) (Collection, error)
+	UpdateCollection(context.Context, UpdateCollectionRequest) (Collection, error)
+	DeleteCollection(context.Context, DeleteCollectionRequest) error
+	ListCollections(context.Context, ListCollectionsRequest) (ListCollectionsResponse, error)
+	ListTags(context.Context, ListTagsRequest) (ListTagsResponse, error)
+	InsertTag(context.Context, InsertTagRequest) (DocumentTag, error)
+	UpdateTag(context.Context, UpdateTagRequest) (DocumentTag, error)
+	DeleteTag(context.Context, DeleteTagRequest) error
+	MergeTags(context.Context, MergeTagsRequest) (DocumentTag, error)
+	MoveTag(context.Context, MoveTagRequest) (DocumentTag, error)
+	GetTagTree(context.Context, GetTagTreeRequest) ([]DocumentTag, error)
+	ListAuthors(context.Context, ListAuthorsRequest) (ListAuthorsResponse, error)
+	InsertAuthor(context.Context, InsertAuthorRequest) (DocumentAuthor, error)
+	UpdateAuthor(context.Context, UpdateAuthorRequest) (DocumentAuthor, error)
+	DeleteAuthor(context.Context, DeleteAuthorRequest) error
+	MergeAuthors(context.Context, MergeAuthorsRequest) (DocumentAuthor, error)
+	ListKinds(context.Context, ListKindsRequest) (ListKindsResponse, error)
+	InsertKind(context.Context, InsertKindRequest) (DocumentKind, error)
+	UpdateKind(context.Context, UpdateKindRequest) (DocumentKind, error)
+	DeleteKind(context.Context, DeleteKindRequest) error
 }
 
 type InsertDocumentRequest struct {
 	Document Document
+	// Fetch reads the metadata of the document uri, filling the title, authors and tags left empty
+	Fetch bool
+	// Upsert merges the tags and authors into the document already using the uri
+	Upsert bool
+}
+
+type InsertDocumentsRequest struct {
+	Documents []Document
+	// Atomic inserts all the documents or none of them
+	Atomic bool
+	// BatchSize is the number of documents inserted by each transaction when
+	// not atomic, a default is used when 0
+	BatchSize int
+	// Upsert merges the documents into the ones already using their uri
+	Upsert bool
+}
+
+type InsertDocumentsResponse struct {
+	// Results are in the same order as the inserted documents
+	Results []InsertDocumentResult `json:"results"`
+	// RolledBack is set when an atomic insert failed, no document was inserted
+	RolledBack bool `json:"rolled_back,omitempty"`
+}
+
+type InsertDocumentResult struct {
+	ID    int    `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+type UpdateDocumentRequest struct {
+	DocumentID int
+	Document   Document
+	// Partial only updates the fields set on Document, leaving the others untouched
+	Partial bool
+	// UserID is the user making the change, recorded in the revisions
+	UserID uint
+}
+
+type DeleteDocumentRequest struct {
+	DocumentID int
+	UserID     uint
+}
+
+type ListTrashRequest struct {
+	Pagination PaginationRequest
+}
+
+type ListTrashResponse struct {
+	// Items are the deleted documents, the last deleted first
+	Items      []Document         `json:"items"`
+	Pagination PaginationResponse `json:"pagination"`
+}
+
+type RestoreDocumentRequest struct {
+	DocumentID int
+	UserID     uint
+}
+
+type PurgeDocumentRequest struct {
+	DocumentID int
+}
+
+type ListEventsRequest struct {
+	// After is the sequence of the last event received, zero lists from the oldest
+	After uint
+	Limit int
+}
+
+type ListEventsResponse struct {
+	// Items are the events following After, the oldest first
+	Items []CatalogEvent `json:"items"`
+}
+
+type RefreshDocumentRequest struct {
+	DocumentID int
+	UserID     uint
+}
+
+type PutDocumentContentRequest struct {
+	DocumentID int
+	// ContentType is detected from the content when empty
+	ContentType string
+	Content     io.Reader
+	UserID      uint
+}
+
+type GetDocumentContentRequest struct {
+	DocumentID int
+}
+
+type GetDocumentContentResponse struct {
+	Content      io.ReadSeekCloser
+	ContentType  string
+	Size         int64
+	SHA256       string
+	LastModified time.Time
 }
 
 type ListDocumentsRequest struct {
-	Title      string
-	Tags       []string
+	Title string
+	// Query is a full text search over title, authors, tags and kind, results are sorted by relevance unless Sort is set
+	Query string
+	// Tags matches the documents with one of the tags or of the tags below them
+	Tags []string
+	// TagsAll matches the documents having every tag, or a tag below it
+	TagsAll []string
+	// TagsAny matches the documents having at least one of the tags, like Tags
+	TagsAny []string
+	// TagsNone excludes the documents having any of the tags
+	TagsNone []string
+	// Broken filters the documents by the state of their uri, see RunLinkChecker
+	Broken *bool
+	// Collection only lists the documents of a collection, sorted by their position
+	Collection uint
+	// Filter is an expression over the document fields, see ParseFilter
+	Filter Filter
+	// Sort lists the sort keys by priority, when empty the documents are sorted
+	// by relevance when searching, by position in collections and then by title
+	Sort []Sort
+	// Facets counts the matching documents by the values of the facets
+	Facets     []Facet
 	Pagination PaginationRequest
 }
 
+type SortField string
+
+const (
+	SortTitle      SortField = "title"
+	SortCreateTime SortField = "createTime"
+	SortUpdatedAt  SortField = "updatedAt"
+	SortKind       SortField = "kind"
+	// SortRelevance sorts the most relevant documents first, it requires a query
+	SortRelevance SortField = "relevance"
+)
+
+func (f SortField) Valid() bool {
+	return f == SortTitle || f == SortCreateTime || f == SortUpdatedAt || f == SortKind || f == SortRelevance
+}
+
+type Sort struct {
+	Field      SortField
+	Descending bool
+}
+
 type ListDocumentsResponse struct {
 	Items      []Document         `json:"items"`
 	Pagination PaginationResponse `json:"pagination"`
+	// Facets are the buckets of the requested facets, the most frequent first
+	Facets map[Facet][]FacetBucket `json:"facets,omitempty"`
+}
+
+type Facet string
+
+const (
+	FacetTags    Facet = "tags"
+	FacetKind    Facet = "kind"
+	FacetAuthors Facet = "authors"
+)
+
+func (f Facet) Valid() bool {
+	return f == FacetTags || f == FacetKind || f == FacetAuthors
+}
+
+// FacetBucket counts the matching documents with a tag, kind or author.
+type FacetBucket struct {
+	ID    uint   `json:"id"`
+	Value string `json:"value"`
+	Count int64  `json:"count"`
 }
 
 type PaginationRequest struct {
 	Page     int
 	PageSize int
+	// Cursor continues a documents listing from the NextCursor of a previous
+	// page, replacing Page
+	Cursor string
+	// SkipCount does not count the matching elements, leaving TotalElements
+	// and Pages empty
+	SkipCount bool
 }
 
 func (p PaginationRequest) Offset() int {
 	return (p.Page - 1) * p.PageSize
 }
 
+// validate rejects the empty pages and the pages before the first one, the
+// page is ignored when following a cursor.
+func (p PaginationRequest) validate() error {
+	if p.PageSize < 1 || (p.Page < 1 && len(p.Cursor) == 0) {
+		return ErrInvalidPagination
+	}
+	return nil
+}
+
+type ListRevisionsRequest struct {
+	DocumentID int
+	Pagination PaginationRequest
+}
+
+type ListRevisionsResponse struct {
+	Items      []DocumentRevision `json:"items"`
+	Pagination PaginationResponse `json:"pagination"`
+}
+
+type DiffRevisionsRequest struct {
+	DocumentID int
+	From       uint
+	// To is the latest revision when 0
+	To uint
+}
+
+// RevisionDiff lists the changes made to a document between two revisions.
+type RevisionDiff struct {
+	From           uint             `json:"from"`
+	To             uint             `json:"to"`
+	Changes        []FieldChange    `json:"changes"`
+	AddedTags      []string         `json:"added_tags,omitempty"`
+	RemovedTags    []string         `json:"removed_tags,omitempty"`
+	AddedAuthors   []RevisionAuthor `json:"added_authors,omitempty"`
+	RemovedAuthors []RevisionAuthor `json:"removed_authors,omitempty"`
+}
+
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+type RestoreRevisionRequest struct {
+	DocumentID int
+	RevisionID uint
+	UserID     uint
+}
+
+type ListDuplicatesRequest struct {
+	// MinScore ignores the pairs of documents less similar than it
+	MinScore   float64
+	Pagination PaginationRequest
+}
+
+type ListDuplicatesResponse struct {
+	Items      []DuplicateCluster `json:"items"`
+	Pagination PaginationResponse `json:"pagination"`
+}
+
+// DuplicateCluster groups the documents linked by similar pairs, the
+// documents are sorted by id.
+type DuplicateCluster struct {
+	// Score is the highest similarity of the pairs of the cluster
+	Score     float64              `json:"score"`
+	Documents []Document           `json:"documents"`
+	Pairs     []DuplicateCandidate `json:"pairs"`
+}
+
+type ExportFormat string
+
+const (
+	// ExportNDJSON writes a record per line, see ExportRecord
+	ExportNDJSON ExportFormat = "ndjson"
+	// ExportTarGz archives the records along with the document contents
+	ExportTarGz ExportFormat = "tar.gz"
+)
+
+func (f ExportFormat) Valid() bool {
+	return f == ExportNDJSON || f == ExportTarGz
+}
+
+type ExportCatalogRequest struct {
+	Writer io.Writer
+	Format ExportFormat
+}
+
+type RestoreCatalogRequest struct {
+	Reader io.Reader
+	Format ExportFormat
+}
+
+type RestoreCatalogResponse struct {
+	Kinds     int
+	Tags      int
+	Authors   int
+	Documents int
+	Contents  int
+}
+
 type GetDocumentRequest struct {
 	DocumentID int
 }
@@ -41,4 +357,174 @@ type PaginationResponse struct {
 	TotalElements int64 `json:"total_elements"`
 	Page          int   `json:"page"`
 	Pages         int   `json:"pages"`
+	// NextCursor lists the next page of documents, it is empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+type ResolveUserRequest struct {
+	Subject string
+	// Role is assigned to the user when it is created
+	Role Role
+}
+
+type InsertUserRequest struct {
+	User User
+}
+
+type GetUserRequest struct {
+	UserID uint
+}
+
+type UpdateUserRequest struct {
+	UserID uint
+	// Role and Name are left untouched when empty
+	Role Role
+	Name string
+}
+
+type DeleteUserRequest struct {
+	UserID uint
+}
+
+type ListUsersRequest struct {
+	Pagination PaginationRequest
+}
+
+type ListUsersResponse struct {
+	Items      []User             `json:"items"`
+	Pagination PaginationResponse `json:"pagination"`
+}
+
+type InsertCollectionRequest struct {
+	Collection Collection
+}
+
+type GetCollectionRequest struct {
+	CollectionID uint
+	// UserID hides the collections not visible to the user, zero skips the check
+	UserID uint
+}
+
+type UpdateCollectionRequest struct {
+	CollectionID uint
+	Collection   Collection
+	// Partial only updates the fields set on Collection, leaving the others untouched
+	Partial bool
+}
+
+type DeleteCollectionRequest struct {
+	CollectionID uint
+}
+
+type ListCollectionsRequest struct {
+	// UserID only lists the collections visible to the user, zero lists them all
+	UserID     uint
+	Pagination PaginationRequest
+}
+
+type ListCollectionsResponse struct {
+	Items      []Collection       `json:"items"`
+	Pagination PaginationResponse `json:"pagination"`
+}
+
+type ListTagsRequest struct {
+	Pagination PaginationRequest
+}
+
+type ListTagsResponse struct {
+	Items      []DocumentTag      `json:"items"`
+	Pagination PaginationResponse `json:"pagination"`
+}
+
+type InsertTagRequest struct {
+	Tag DocumentTag
+}
+
+type UpdateTagRequest struct {
+	TagID uint
+	// Tag is the new path of the tag, its parent is created when missing
+	Tag DocumentTag
+	// UserID is the user making the change, recorded in the revisions
+	UserID uint
+}
+
+type DeleteTagRequest struct {
+	TagID  uint
+	UserID uint
+}
+
+type MergeTagsRequest struct {
+	// TagID is the tag surviving the merge
+	TagID uint
+	// MergedIDs are the tags merged into TagID and deleted, their spelling is kept as alias
+	MergedIDs []uint
+	UserID    uint
+}
+
+type MoveTagRequest struct {
+	TagID uint
+	// ParentID is the new parent of the tag, zero makes it a root
+	ParentID uint
+	UserID   uint
+}
+
+type GetTagTreeRequest struct {
+	// TagID only returns the subtree of a tag when defined
+	TagID uint
+}
+
+type ListAuthorsRequest struct {
+	Pagination PaginationRequest
+}
+
+type ListAuthorsResponse struct {
+	Items      []DocumentAuthor   `json:"items"`
+	Pagination PaginationResponse `json:"pagination"`
+}
+
+type InsertAuthorRequest struct {
+	Author DocumentAuthor
+}
+
+type UpdateAuthorRequest struct {
+	AuthorID uint
+	Author   DocumentAuthor
+	UserID   uint
+}
+
+type DeleteAuthorRequest struct {
+	AuthorID uint
+	UserID   uint
+}
+
+type MergeAuthorsRequest struct {
+	// AuthorID is the author surviving the merge
+	AuthorID uint
+	// MergedIDs are the authors merged into AuthorID and deleted, their spelling is kept as alias
+	MergedIDs []uint
+	UserID    uint
+}
+
+type ListKindsRequest struct {
+	Pagination PaginationRequest
+}
+
+type ListKindsResponse struct {
+	Items      []DocumentKind     `json:"items"`
+	Pagination PaginationResponse `json:"pagination"`
+}
+
+type InsertKindRequest struct {
+	Kind DocumentKind
+}
+
+type UpdateKindRequest struct {
+	KindID uint
+	Kind   DocumentKind
+	UserID uint
+}
+
+type DeleteKindRequest struct {
+	KindID uint
+	UserID uint
 }
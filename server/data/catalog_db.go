@@ -2,65 +2,798 @@ package data
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"github.com/garugaru/knowledge/server/extract"
+	"github.com/garugaru/knowledge/server/search"
+	"github.com/garugaru/knowledge/server/storage"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 )
 
+var searchIndexFields = []search.Field{
+	{Name: "title", Weight: 10},
+	{Name: "authors", Weight: 5},
+	{Name: "tags", Weight: 2},
+	{Name: "kind", Weight: 1},
+	{Name: "body", Weight: 1},
+}
+
 type DBCatalog struct {
-	db *gorm.DB
+	db     *gorm.DB
+	search fullTextIndex
+	index  *search.Index
+	blobs  storage.BlobStore
+	// client fetches the document uris
+	client *http.Client
+
+	extractors *extract.Registry
+	extraction chan int
+	// queued holds the documents waiting in the extraction queue
+	queued   map[int]bool
+	queuedMu sync.Mutex
+
+	events *eventBus
+	// published is the sequence of the last event published to the bus
+	published   uint
+	publishedMu sync.Mutex
+}
+
+type DBCatalogOption func(*DBCatalog)
+
+// WithSearchIndex replaces the database full text search with an embedded
+// index persisted at path.
+func WithSearchIndex(path string) DBCatalogOption {
+	return func(d *DBCatalog) {
+		d.index = search.NewIndex(path, searchIndexFields...)
+		d.search = nil
+	}
 }
 
-func NewDBCatalog(db *gorm.DB) *DBCatalog {
-	return &DBCatalog{db: db}
+// WithBlobStore enables document content uploads, storing them in store.
+func WithBlobStore(store storage.BlobStore) DBCatalogOption {
+	return func(d *DBCatalog) {
+		d.blobs = store
+	}
+}
+
+// WithPrivateFetch allows the document uris to point to loopback, private or
+// link-local addresses, meant for the catalogs of internal documents.
+func WithPrivateFetch() DBCatalogOption {
+	return func(d *DBCatalog) {
+		d.client = newFetchClient(true)
+	}
+}
+
+// WithExtraction enables the extraction of the text of the document content
+// using the extractors of registry, see RunExtraction.
+func WithExtraction(registry *extract.Registry) DBCatalogOption {
+	return func(d *DBCatalog) {
+		d.extractors = registry
+		d.extraction = make(chan int, extractionQueueSize)
+		d.queued = make(map[int]bool)
+	}
+}
+
+func NewDBCatalog(db *gorm.DB, opts ...DBCatalogOption) *DBCatalog {
+	catalog := &DBCatalog{
+		db:     db,
+		search: newFullTextIndex(db),
+		client: newFetchClient(false),
+		events: newEventBus(),
+	}
+	for _, opt := range opts {
+		opt(catalog)
+	}
+	return catalog
 }
 
 func (d *DBCatalog) Init() error {
-	return d.db.AutoMigrate(&Document{}, &DocumentKind{}, &DocumentTag{}, &DocumentAuthor{})
+	if err := mergeDuplicateEntities(d.db); err != nil {
+		return err
+	}
+
+	if err := d.db.AutoMigrate(&Document{}, &DocumentKind{}, &DocumentTag{}, &DocumentAuthor{}, &DocumentText{}, &User{},
+		&Collection{}, &CollectionItem{}, &CollectionShare{}, &TagAlias{}, &AuthorAlias{}, &TagClosure{}, &DuplicateCandidate{},
+		&DocumentRevision{}, &CatalogEvent{}); err != nil {
+		return err
+	}
+
+	if err := initTagClosure(d.db); err != nil {
+		return err
+	}
+
+	if err := initUriKeys(d.db); err != nil {
+		return err
+	}
+
+	if err := d.initEvents(); err != nil {
+		return err
+	}
+
+	var rebuild bool
+
+	if d.search != nil {
+		created, err := d.search.migrate(d.db)
+		if err != nil {
+			return err
+		}
+		rebuild = created
+	}
+
+	if d.index != nil {
+		err := d.index.Load()
+		outdated := os.IsNotExist(err) || errors.Is(err, search.ErrFieldsMismatch) || errors.Is(err, search.ErrStale)
+		if err != nil && !outdated {
+			return err
+		}
+		rebuild = rebuild || outdated
+	}
+
+	if rebuild {
+		return d.Reindex(context.Background())
+	}
+
+	return nil
+}
+
+// Reindex rebuilds the search index from the documents stored in the catalog.
+func (d *DBCatalog) Reindex(ctx context.Context) error {
+	tx := d.db.WithContext(ctx)
+
+	if d.search != nil {
+		if err := d.search.clear(tx); err != nil {
+			return err
+		}
+	}
+
+	if d.index != nil {
+		d.index.Reset()
+	}
+
+	const batchSize = 100
+	for lastID := 0; ; {
+		var documents []Document
+		err := tx.Preload("DocumentKind").Preload("Tags").Preload("Authors").Preload("Text").
+			Where("id > ?", lastID).Order("id").Limit(batchSize).Find(&documents).Error
+		if err != nil {
+			return err
+		}
+
+		for _, document := range documents {
+			if d.search != nil {
+				if err := d.search.index(tx, newSearchDocument(document)); err != nil {
+					return err
+				}
+			}
+
+			if d.index != nil {
+				d.index.Add(newSearchDocument(document).indexDocument())
+			}
+		}
+
+		if len(documents) < batchSize {
+			break
+		}
+		lastID = documents[len(documents)-1].ID
+	}
+
+	if d.index != nil {
+		return d.index.Save()
+	}
+
+	return nil
+}
+
+// defaultSearchIndexInterval is the default time between two saves of the
+// embedded search index
+const defaultSearchIndexInterval = time.Minute
+
+type SearchIndexOptions struct {
+	// Interval is the time between two saves of the changed index, a default
+	// is used when 0
+	Interval time.Duration
+}
+
+// RunSearchIndexSave periodically saves the changes of the embedded search
+// index until ctx is done, saving them a last time before returning.
+func (d *DBCatalog) RunSearchIndexSave(ctx context.Context, opts SearchIndexOptions) {
+	if d.index == nil {
+		return
+	}
+
+	if opts.Interval <= 0 {
+		opts.Interval = defaultSearchIndexInterval
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			// the last save follows the cancellation, its errors are still logged
+			logBackgroundError(context.Background(), "search_index", d.SaveSearchIndex())
+			return
+		case <-time.After(opts.Interval):
+			// the changes left are saved on the next run, or rebuilt on start
+			logBackgroundError(ctx, "search_index", d.SaveSearchIndex())
+		}
+	}
+}
+
+// SaveSearchIndex saves the changes of the embedded search index made since
+// the last save.
+func (d *DBCatalog) SaveSearchIndex() error {
+	if d.index == nil {
+		return nil
+	}
+	return d.index.Flush()
+}
+
+// loadIndexedDocument loads a document with all the fields covered by search,
+// updating the database full text index when enabled.
+func (d *DBCatalog) loadIndexedDocument(tx *gorm.DB, documentID int) (Document, error) {
+	var document Document
+	if err := tx.Preload("DocumentKind").Preload("Tags").Preload("Authors").Preload("Text").First(&document, documentID).Error; err != nil {
+		return document, err
+	}
+
+	if d.search == nil {
+		return document, nil
+	}
+
+	return document, d.search.index(tx, newSearchDocument(document))
+}
+
+// syncSearchIndex applies a committed change to the embedded search index,
+// a nil document removes documentID from the index. The index is saved by
+// RunSearchIndexSave.
+func (d *DBCatalog) syncSearchIndex(documentID int, document *Document) {
+	if d.index == nil {
+		return
+	}
+
+	if document == nil {
+		d.index.Remove(documentID)
+	} else {
+		d.index.Add(newSearchDocument(*document).indexDocument())
+	}
+}
+
+// insertDocument creates the document, clearing the state managed by the
+// catalog, and returns it loaded for the search index.
+func (d *DBCatalog) insertDocument(tx *gorm.DB, document Document) (Document, error) {
+	if err := resolveDocumentEntities(tx, &document); err != nil {
+		return Document{}, err
+	}
+
+	document.UriKey = uriKey(document.Uri)
+	document.Content = DocumentContent{}
+	document.Link = LinkState{}
+	document.Extraction = ExtractionState{}
+	document.Text = nil
+	if d.extractors != nil && isFetchable(document.Uri) {
+		document.Extraction.Status = ExtractionPending
+	}
+	// the unique index rejects the documents using an existing uri, the
+	// savepoint keeps the transaction usable to find the existing one
+	err := tx.Transaction(func(tx *gorm.DB) error {
+		return tx.Create(&document).Error
+	})
+	if err != nil {
+		if duplicateErr := checkDuplicateUri(tx, document.Uri, 0); duplicateErr != nil {
+			return Document{}, duplicateErr
+		}
+		return Document{}, err
+	}
+
+	inserted, err := d.loadIndexedDocument(tx, document.ID)
+	if err != nil {
+		return Document{}, err
+	}
+	return inserted, recordRevision(tx, inserted, RevisionInsert, document.OwnerID)
+}
+
+// upsertDocument inserts the document, merging it into the document already
+// using its uri when upsert is set.
+func (d *DBCatalog) upsertDocument(tx *gorm.DB, document Document, upsert bool) (Document, error) {
+	inserted, err := d.insertDocument(tx, document)
+
+	var duplicate *DuplicateDocumentError
+	if !upsert || !errors.As(err, &duplicate) {
+		return inserted, err
+	}
+
+	if err := resolveDocumentEntities(tx, &document); err != nil {
+		return Document{}, err
+	}
+
+	existing := Document{ID: duplicate.DocumentID}
+	if len(document.Tags) != 0 {
+		if err := tx.Model(&existing).Association("Tags").Append(document.Tags); err != nil {
+			return Document{}, err
+		}
+	}
+	if len(document.Authors) != 0 {
+		if err := tx.Model(&existing).Association("Authors").Append(document.Authors); err != nil {
+			return Document{}, err
+		}
+	}
+
+	merged, err := d.loadIndexedDocument(tx, duplicate.DocumentID)
+	if err != nil {
+		return Document{}, err
+	}
+	return merged, recordRevision(tx, merged, RevisionUpdate, document.OwnerID)
 }
 
 func (d *DBCatalog) InsertDocument(ctx context.Context, req InsertDocumentRequest) error {
-	result := d.db.WithContext(ctx).Create(&req.Document)
-	return result.Error
+	req.Document.Fetch = FetchState{}
+	if req.Fetch {
+		metadata, state := d.fetchMetadata(ctx, req.Document.Uri)
+		applyMetadata(&req.Document, metadata, false)
+		req.Document.Fetch = state
+
+		if req.Document.Title == nil && req.Document.Uri != nil {
+			req.Document.Title = req.Document.Uri
+		}
+	}
+
+	var document Document
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		document, err = d.upsertDocument(tx, req.Document, req.Upsert)
+		return err
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if document.Extraction.Status == ExtractionPending {
+		d.enqueueExtraction(document.ID)
+	}
+
+	d.syncSearchIndex(document.ID, &document)
+	return nil
 }
 
 func (d *DBCatalog) ListDocuments(ctx context.Context, request ListDocumentsRequest) (ListDocumentsResponse, error) {
-	query := d.db.WithContext(ctx)
+	if err := request.Pagination.validate(); err != nil {
+		return ListDocumentsResponse{}, err
+	}
 
-	query = query.Preload("Tags").Preload("Authors")
+	query := d.db.WithContext(ctx)
 
 	if len(request.Title) != 0 {
-		query = query.Where("title LIKE ?", fmt.Sprintf("%%%s%%", request.Title))
+		query = query.Where("documents.title LIKE ? ESCAPE '"+likeEscape+"'", containsPattern(request.Title))
+	}
+
+	if tags := append(append([]string{}, request.Tags...), request.TagsAny...); len(tags) != 0 {
+		query = query.Where("documents.id IN (?)", d.taggedDocuments(tags))
+	}
+
+	if tags := distinctStrings(request.TagsAll); len(tags) != 0 {
+		// a document has all the tags when it matches as many distinct tags as requested
+		tagged := d.taggedDocuments(tags).Group("document_document_tags.document_id").
+			Having("COUNT(DISTINCT document_tags.id) = ?", len(tags))
+		query = query.Where("documents.id IN (?)", tagged)
+	}
+
+	if len(request.TagsNone) != 0 {
+		query = query.Where("documents.id NOT IN (?)", d.taggedDocuments(request.TagsNone))
 	}
 
-	if len(request.Tags) != 0 {
-		query = query.Joins("LEFT JOIN document_document_tags on document_document_tags.document_id = documents.id")
-		query = query.Joins("LEFT JOIN document_tags on document_tags.id = document_document_tags.document_tag_id", request.Tags)
-		query = query.Where("document_tags.tag IN ?", request.Tags)
+	if request.Broken != nil {
+		query = query.Where("documents.link_broken = ?", *request.Broken)
+	}
+
+	if request.Collection != 0 {
+		query = query.Joins("JOIN collection_items ON collection_items.document_id = documents.id AND collection_items.collection_id = ?", request.Collection)
+	}
+
+	if request.Filter != nil {
+		query = query.Where(request.Filter.expression())
+	}
+
+	for _, sort := range request.Sort {
+		if sort.Field == SortRelevance && len(request.Query) == 0 {
+			return ListDocumentsResponse{}, errors.New("sorting by relevance requires a search query")
+		}
+	}
+
+	columns := sortColumns(request)
+	signature := sortSignature(request, columns)
+
+	var cursor documentCursor
+	if len(request.Pagination.Cursor) != 0 {
+		var err error
+		if cursor, err = decodeCursor(request.Pagination.Cursor, signature); err != nil {
+			return ListDocumentsResponse{}, err
+		}
+	}
+
+	if len(request.Query) != 0 {
+		switch {
+		case d.index != nil:
+			return d.searchIndexedDocuments(ctx, query, request, cursor)
+		case d.search != nil:
+			query = d.search.match(query, request.Query)
+		default:
+			return ListDocumentsResponse{}, errors.New("full text search is not supported by the database")
+		}
 	}
 
 	var totalElements int64
-	cntQuery := query.Model(&Document{}).Count(&totalElements)
-	if err := cntQuery.Error; err != nil {
+	if !request.Pagination.SkipCount {
+		cntQuery := query.Model(&Document{}).Count(&totalElements)
+		if err := cntQuery.Error; err != nil {
+			return ListDocumentsResponse{}, err
+		}
+	}
+
+	facets, err := d.documentFacets(ctx, request.Facets, query, nil)
+	if err != nil {
 		return ListDocumentsResponse{}, err
 	}
 
-	query = query.Order("title")
-	query = query.Offset(request.Pagination.Offset()).Limit(request.Pagination.PageSize)
+	if len(request.Query) != 0 {
+		query = d.search.rank(query, request.Query)
+	} else {
+		query = query.Select("documents.*")
+	}
+
+	offset := cursorOffset(request.Pagination, cursor)
+	if cursor.Keys != nil {
+		query = query.Where(keysetCondition(columns, *cursor.Keys))
+	}
+
+	// one more document tells if there is a next page
+	query = orderDocuments(query, columns)
+	query = query.Offset(offset).Limit(request.Pagination.PageSize + 1)
 
 	var documents []Document
-	query = query.Find(&documents)
+	query = query.Preload("Tags").Preload("Authors").Find(&documents)
+	if err := query.Error; err != nil {
+		return ListDocumentsResponse{}, err
+	}
+
+	var next *documentCursor
+	if len(documents) > request.Pagination.PageSize {
+		documents = documents[:request.Pagination.PageSize]
+		next = &documentCursor{Sort: signature, Offset: offset + len(documents)}
+		if len(request.Query) == 0 {
+			keys, err := d.documentSortKeys(ctx, request, documents[len(documents)-1].ID)
+			if err != nil {
+				return ListDocumentsResponse{}, err
+			}
+			next = &documentCursor{Sort: signature, Keys: &keys}
+		}
+	}
+
+	if len(request.Query) != 0 {
+		d.search.highlight(documents, request.Query)
+	}
 
 	var response ListDocumentsResponse
 
 	response.Items = documents
-	response.Pagination = PaginationResponse{
+	response.Pagination = newCursorPaginationResponse(totalElements, request.Pagination, next)
+	response.Facets = facets
+
+	return response, nil
+}
+
+// documentSortKeys reads the sort keys of a listed document to continue the
+// listing after it.
+func (d *DBCatalog) documentSortKeys(ctx context.Context, request ListDocumentsRequest, documentID int) (documentSortKeys, error) {
+	query := d.db.WithContext(ctx).Model(&Document{}).Where("documents.id = ?", documentID)
+
+	columns := "documents.id, documents.title, documents.create_time, documents.updated_at, " + kindSortColumn + " AS kind"
+	if request.Collection != 0 {
+		columns += ", collection_items.position"
+		query = query.Joins("JOIN collection_items ON collection_items.document_id = documents.id AND collection_items.collection_id = ?", request.Collection)
+	}
+
+	var keys documentSortKeys
+	return keys, query.Select(columns).Scan(&keys).Error
+}
+
+// cursorOffset is the offset of the page, the documents after a keyset cursor
+// are filtered so they start from 0.
+func cursorOffset(pagination PaginationRequest, cursor documentCursor) int {
+	switch {
+	case len(pagination.Cursor) == 0:
+		return pagination.Offset()
+	case cursor.Keys != nil:
+		return 0
+	default:
+		return cursor.Offset
+	}
+}
+
+// kindSortColumn is the name of the document kind, empty without a kind.
+const kindSortColumn = "COALESCE((SELECT document_kinds.name FROM document_kinds WHERE document_kinds.id = documents.document_kind_id), '')"
+
+// sortColumn is a column the documents are sorted by, value reads it from
+// the sort keys of a document.
+type sortColumn struct {
+	column     string
+	descending bool
+	value      func(keys documentSortKeys) interface{}
+}
+
+// sortColumns lists the columns sorting the documents, the id is always the
+// last column so that pages never overlap.
+func sortColumns(request ListDocumentsRequest) []sortColumn {
+	var columns []sortColumn
+
+	sorts := request.Sort
+	if len(sorts) == 0 {
+		if len(request.Query) != 0 {
+			sorts = append(sorts, Sort{Field: SortRelevance})
+		} else if request.Collection != 0 {
+			columns = append(columns, sortColumn{
+				column: "collection_items.position",
+				value:  func(keys documentSortKeys) interface{} { return keys.Position },
+			})
+		}
+		sorts = append(sorts, Sort{Field: SortTitle})
+	}
+
+	for _, sort := range sorts {
+		column := sortColumn{descending: sort.Descending}
+		switch sort.Field {
+		case SortTitle:
+			column.column = "documents.title"
+			column.value = func(keys documentSortKeys) interface{} { return keys.Title }
+		case SortCreateTime:
+			column.column = "documents.create_time"
+			column.value = func(keys documentSortKeys) interface{} { return keys.CreateTime }
+		case SortUpdatedAt:
+			column.column = "documents.updated_at"
+			column.value = func(keys documentSortKeys) interface{} { return keys.UpdatedAt }
+		case SortKind:
+			column.column = kindSortColumn
+			column.value = func(keys documentSortKeys) interface{} { return keys.Kind }
+		case SortRelevance:
+			// the most relevant documents come first unless descending
+			column.column = "search_rank"
+			column.descending = !sort.Descending
+		default:
+			continue
+		}
+		columns = append(columns, column)
+	}
+
+	return append(columns, sortColumn{
+		column: "documents.id",
+		value:  func(keys documentSortKeys) interface{} { return keys.ID },
+	})
+}
+
+func orderDocuments(query *gorm.DB, columns []sortColumn) *gorm.DB {
+	for _, column := range columns {
+		if column.descending {
+			query = query.Order(column.column + " DESC")
+		} else {
+			query = query.Order(column.column)
+		}
+	}
+	return query
+}
+
+// documentSortKeys are the sorted columns of a document.
+type documentSortKeys struct {
+	ID         int
+	Title      string
+	CreateTime int
+	UpdatedAt  time.Time
+	Kind       string
+	Position   int
+}
+
+// sortHits sorts the index hits by the requested keys like orderDocuments,
+// loading the sorted columns of the documents hit.
+func (d *DBCatalog) sortHits(ctx context.Context, hits []search.Hit, sorts []Sort) error {
+	if len(hits) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+
+	var keys []documentSortKeys
+	err := d.db.WithContext(ctx).Model(&Document{}).
+		Select("documents.id, documents.title, documents.create_time, documents.updated_at, "+kindSortColumn+" AS kind").
+		Where("documents.id IN ?", ids).Scan(&keys).Error
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[int]documentSortKeys, len(keys))
+	for _, key := range keys {
+		byID[key.ID] = key
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		a, b := byID[hits[i].ID], byID[hits[j].ID]
+		for _, key := range sorts {
+			var compared int
+			switch key.Field {
+			case SortTitle:
+				compared = strings.Compare(a.Title, b.Title)
+			case SortCreateTime:
+				compared = a.CreateTime - b.CreateTime
+			case SortUpdatedAt:
+				if a.UpdatedAt.Before(b.UpdatedAt) {
+					compared = -1
+				} else if a.UpdatedAt.After(b.UpdatedAt) {
+					compared = 1
+				}
+			case SortKind:
+				compared = strings.Compare(a.Kind, b.Kind)
+			case SortRelevance:
+				if hits[i].Score > hits[j].Score {
+					compared = -1
+				} else if hits[i].Score < hits[j].Score {
+					compared = 1
+				}
+			}
+
+			if key.Descending {
+				compared = -compared
+			}
+			if compared != 0 {
+				return compared < 0
+			}
+		}
+		return hits[i].ID < hits[j].ID
+	})
+
+	return nil
+}
+
+// taggedDocuments selects the ids of the documents tagged with any of the tags
+// or with a tag below them.
+func (d *DBCatalog) taggedDocuments(tags []string) *gorm.DB {
+	return d.db.Table("document_document_tags").Select("document_document_tags.document_id").
+		Joins("JOIN tag_closures ON tag_closures.descendant_id = document_document_tags.document_tag_id").
+		Joins("JOIN document_tags ON document_tags.id = tag_closures.ancestor_id").
+		Where("document_tags.tag IN ?", tags)
+}
+
+func distinctStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var distinct []string
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			distinct = append(distinct, value)
+		}
+	}
+	return distinct
+}
+
+// searchIndexedDocuments intersects the embedded index hits with the documents
+// matching the other filters, paginating over the hits sorted by relevance or
+// by the requested keys.
+func (d *DBCatalog) searchIndexedDocuments(ctx context.Context, query *gorm.DB, request ListDocumentsRequest, cursor documentCursor) (ListDocumentsResponse, error) {
+	hits := d.index.Search(request.Query)
+
+	var ids []int
+	if err := query.Model(&Document{}).Distinct().Pluck("documents.id", &ids).Error; err != nil {
+		return ListDocumentsResponse{}, err
+	}
+
+	filtered := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		filtered[id] = true
+	}
+
+	matching := make([]search.Hit, 0, len(hits))
+	for _, hit := range hits {
+		if filtered[hit.ID] {
+			matching = append(matching, hit)
+		}
+	}
+
+	if len(request.Sort) != 0 {
+		if err := d.sortHits(ctx, matching, request.Sort); err != nil {
+			return ListDocumentsResponse{}, err
+		}
+	}
+
+	matchingIDs := make([]int, len(matching))
+	for i, hit := range matching {
+		matchingIDs[i] = hit.ID
+	}
+
+	facets, err := d.documentFacets(ctx, request.Facets, nil, matchingIDs)
+	if err != nil {
+		return ListDocumentsResponse{}, err
+	}
+
+	start := cursorOffset(request.Pagination, cursor)
+	if start > len(matching) {
+		start = len(matching)
+	}
+	end := start + request.Pagination.PageSize
+	if end > len(matching) {
+		end = len(matching)
+	}
+	page := matching[start:end]
+	d.index.Snippets(page, request.Query)
+
+	pageIDs := make([]int, len(page))
+	for i, hit := range page {
+		pageIDs[i] = hit.ID
+	}
+
+	var found []Document
+	if len(pageIDs) != 0 {
+		err := d.db.WithContext(ctx).Preload("Tags").Preload("Authors").Find(&found, pageIDs).Error
+		if err != nil {
+			return ListDocumentsResponse{}, err
+		}
+	}
+
+	byID := make(map[int]Document, len(found))
+	for _, document := range found {
+		byID[document.ID] = document
+	}
+
+	documents := make([]Document, 0, len(page))
+	for _, hit := range page {
+		document, present := byID[hit.ID]
+		if !present {
+			continue
+		}
+		document.Snippet = hit.Snippet
+		document.SearchRank = hit.Score
+		documents = append(documents, document)
+	}
+
+	var next *documentCursor
+	if end < len(matching) {
+		next = &documentCursor{Sort: sortSignature(request, sortColumns(request)), Offset: end}
+	}
+
+	return ListDocumentsResponse{
+		Items:      documents,
+		Pagination: newCursorPaginationResponse(int64(len(matching)), request.Pagination, next),
+		Facets:     facets,
+	}, nil
+}
+
+func newPaginationResponse(totalElements int64, pagination PaginationRequest) PaginationResponse {
+	return PaginationResponse{
 		TotalElements: totalElements,
-		Page:          request.Pagination.Page,
-		Pages:         int(math.Ceil(float64(totalElements) / float64(request.Pagination.PageSize))),
+		Page:          pagination.Page,
+		Pages:         int(math.Ceil(float64(totalElements) / float64(pagination.PageSize))),
 	}
+}
 
-	return response, query.Error
+// newCursorPaginationResponse leaves the page empty for the cursor pages.
+func newCursorPaginationResponse(totalElements int64, pagination PaginationRequest, next *documentCursor) PaginationResponse {
+	response := newPaginationResponse(totalElements, pagination)
+	if pagination.SkipCount {
+		response.TotalElements = 0
+		response.Pages = 0
+	}
+	if len(pagination.Cursor) != 0 {
+		response.Page = 0
+	}
+	if next != nil {
+		response.NextCursor = next.encode()
+	}
+	return response
 }
 
 func (d *DBCatalog) GetDocument(ctx context.Context, request GetDocumentRequest) (Document, error) {
@@ -68,3 +801,120 @@ func (d *DBCatalog) GetDocument(ctx context.Context, request GetDocumentRequest)
 	query := d.db.WithContext(ctx).Preload("Tags").Preload("Authors").First(&document, request.DocumentID)
 	return document, query.Error
 }
+
+func (d *DBCatalog) UpdateDocument(ctx context.Context, request UpdateDocumentRequest) (Document, error) {
+	return d.updateDocument(ctx, request, RevisionUpdate)
+}
+
+// updateDocument applies the update, recording it as a revision of action.
+func (d *DBCatalog) updateDocument(ctx context.Context, request UpdateDocumentRequest, action RevisionAction) (Document, error) {
+	var document Document
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&document, request.DocumentID).Error; err != nil {
+			return err
+		}
+
+		update := request.Document
+		if err := resolveDocumentEntities(tx, &update); err != nil {
+			return err
+		}
+
+		if !request.Partial || update.Title != nil {
+			document.Title = update.Title
+		}
+
+		if !request.Partial || update.Uri != nil {
+			// documents without uploaded content are extracted again from the new uri
+			uriChanged := !strEqual(document.Uri, update.Uri)
+			if uriChanged && d.extractors != nil && len(document.Content.SHA256) == 0 && isFetchable(update.Uri) {
+				document.Extraction = ExtractionState{Status: ExtractionPending}
+			}
+			if uriChanged {
+				document.Link = LinkState{}
+			}
+			if err := checkDuplicateUri(tx, update.Uri, document.ID); err != nil {
+				return err
+			}
+			document.Uri = update.Uri
+			document.UriKey = uriKey(update.Uri)
+		}
+
+		switch {
+		case !update.DocumentKind.isZero():
+			document.DocumentKindID = int(update.DocumentKind.ID)
+		case update.DocumentKindID != 0:
+			document.DocumentKindID = update.DocumentKindID
+		case !request.Partial:
+			document.DocumentKindID = 0
+		}
+
+		if err := tx.Omit(clause.Associations).Save(&document).Error; err != nil {
+			return err
+		}
+
+		if !request.Partial || update.Tags != nil {
+			if err := tx.Model(&document).Association("Tags").Replace(update.Tags); err != nil {
+				return err
+			}
+		}
+
+		if !request.Partial || update.Authors != nil {
+			if err := tx.Model(&document).Association("Authors").Replace(update.Authors); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		if document, err = d.loadIndexedDocument(tx, request.DocumentID); err != nil {
+			return err
+		}
+		return recordRevision(tx, document, action, request.UserID)
+	})
+
+	if err != nil {
+		return document, err
+	}
+
+	if document.Extraction.Status == ExtractionPending {
+		d.enqueueExtraction(document.ID)
+	}
+
+	d.syncSearchIndex(document.ID, &document)
+	return document, nil
+}
+
+func (d *DBCatalog) DeleteDocument(ctx context.Context, request DeleteDocumentRequest) error {
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&Document{}, request.DocumentID)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		// the uri of a deleted document can be used again
+		err := tx.Unscoped().Model(&Document{}).Where("id = ?", request.DocumentID).UpdateColumn("uri_key", nil).Error
+		if err != nil {
+			return err
+		}
+
+		if err := recordStoredRevision(tx, request.DocumentID, RevisionDelete, request.UserID); err != nil {
+			return err
+		}
+
+		if d.search == nil {
+			return nil
+		}
+
+		return d.search.remove(tx, request.DocumentID)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	d.syncSearchIndex(request.DocumentID, nil)
+	return nil
+}
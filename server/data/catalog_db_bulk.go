@@ -0,0 +1,95 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+)
+
+const defaultInsertBatchSize = 100
+
+var errInsertRolledBack = errors.New("atomic insert rolled back")
+
+// InsertDocuments inserts the documents in batches, each one in its own
+// savepoint so that a failing document does not discard the others unless
+// the insert is atomic.
+func (d *DBCatalog) InsertDocuments(ctx context.Context, request InsertDocumentsRequest) (InsertDocumentsResponse, error) {
+	response := InsertDocumentsResponse{Results: make([]InsertDocumentResult, len(request.Documents))}
+
+	batchSize := request.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultInsertBatchSize
+	}
+	if request.Atomic {
+		batchSize = len(request.Documents)
+	}
+
+	var inserted []Document
+	for start := 0; start < len(request.Documents); start += batchSize {
+		end := start + batchSize
+		if end > len(request.Documents) {
+			end = len(request.Documents)
+		}
+
+		var batch []Document
+		err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			failed := false
+			for i, document := range request.Documents[start:end] {
+				document.Fetch = FetchState{}
+
+				var created Document
+				err := tx.Transaction(func(tx *gorm.DB) error {
+					var err error
+					created, err = d.upsertDocument(tx, document, request.Upsert)
+					return err
+				})
+				if err != nil {
+					response.Results[start+i].Error = err.Error()
+					failed = true
+					continue
+				}
+
+				response.Results[start+i].ID = created.ID
+				batch = append(batch, created)
+			}
+
+			if failed && request.Atomic {
+				return errInsertRolledBack
+			}
+			return nil
+		})
+
+		if errors.Is(err, errInsertRolledBack) {
+			for i := range response.Results {
+				response.Results[i].ID = 0
+			}
+			response.RolledBack = true
+			return response, nil
+		}
+		if err != nil {
+			// the batch is rolled back, the batches committed before are kept
+			for i := start; i < end; i++ {
+				response.Results[i].ID = 0
+			}
+			response.RolledBack = request.Atomic
+			d.insertedDocuments(inserted)
+			return response, err
+		}
+
+		inserted = append(inserted, batch...)
+	}
+
+	d.insertedDocuments(inserted)
+	return response, nil
+}
+
+// insertedDocuments queues the extraction and indexes the committed documents.
+func (d *DBCatalog) insertedDocuments(inserted []Document) {
+	for _, document := range inserted {
+		if document.Extraction.Status == ExtractionPending {
+			d.enqueueExtraction(document.ID)
+		}
+	}
+
+	d.syncSearchIndexes(inserted)
+}
@@ -0,0 +1,70 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestDBCatalog_InsertDocuments(t *testing.T) {
+	catalog, _ := newTestCatalog(t)
+
+	var documents []Document
+	for i := 0; i < 5; i++ {
+		documents = append(documents, Document{
+			Title: strptr(fmt.Sprintf("title %d", i)),
+			Uri:   strptr(fmt.Sprintf("uri %d", i)),
+			Tags:  []DocumentTag{{Tag: "bulk"}},
+		})
+	}
+	documents[2].Title = nil
+
+	response, err := catalog.InsertDocuments(context.TODO(), InsertDocumentsRequest{Documents: documents, BatchSize: 2})
+	require.NoError(t, err)
+	require.False(t, response.RolledBack)
+	require.Len(t, response.Results, 5)
+	for i, result := range response.Results {
+		if i == 2 {
+			require.Zero(t, result.ID)
+			require.NotEmpty(t, result.Error)
+			continue
+		}
+		require.NotZero(t, result.ID)
+		require.Empty(t, result.Error)
+
+		document, err := catalog.GetDocument(context.TODO(), GetDocumentRequest{DocumentID: result.ID})
+		require.NoError(t, err)
+		require.Equal(t, *documents[i].Title, *document.Title)
+	}
+
+	listed, err := catalog.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Tags:       []string{"bulk"},
+		Pagination: PaginationRequest{Page: 1, PageSize: 10},
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 4, listed.Pagination.TotalElements, "the tags must be shared by the documents")
+
+	response, err = catalog.InsertDocuments(context.TODO(), InsertDocumentsRequest{Documents: documents, Atomic: true})
+	require.NoError(t, err)
+	require.True(t, response.RolledBack)
+	require.NotEmpty(t, response.Results[2].Error)
+	for _, result := range response.Results {
+		require.Zero(t, result.ID)
+	}
+
+	listed, err = catalog.ListDocuments(context.TODO(), ListDocumentsRequest{Pagination: PaginationRequest{Page: 1, PageSize: 10}})
+	require.NoError(t, err)
+	require.EqualValues(t, 4, listed.Pagination.TotalElements, "atomic inserts must not insert any document on failure")
+
+	documents[2].Title = strptr("title 2")
+	upserted, err := catalog.InsertDocuments(context.TODO(), InsertDocumentsRequest{Documents: documents, Atomic: true, Upsert: true})
+	require.NoError(t, err)
+	require.False(t, upserted.RolledBack)
+	require.Equal(t, 1, upserted.Results[0].ID, "upserts must reuse the documents with the same uri")
+	require.NotZero(t, upserted.Results[2].ID)
+
+	listed, err = catalog.ListDocuments(context.TODO(), ListDocumentsRequest{Pagination: PaginationRequest{Page: 1, PageSize: 10}})
+	require.NoError(t, err)
+	require.EqualValues(t, 5, listed.Pagination.TotalElements)
+}
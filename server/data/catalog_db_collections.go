@@ -0,0 +1,220 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var errInvalidVisibility = errors.New("visibility must be one of private, shared or public")
+
+// visibleCollections restricts query to the collections visible to userID.
+func visibleCollections(query *gorm.DB, userID uint) *gorm.DB {
+	if userID == 0 {
+		return query
+	}
+
+	return query.Where("collections.visibility = ? OR collections.owner_id = ? OR (collections.visibility = ? AND collections.id IN (?))",
+		VisibilityPublic, userID, VisibilityShared,
+		query.Session(&gorm.Session{NewDB: true}).Model(&CollectionShare{}).Select("collection_id").Where("user_id = ?", userID))
+}
+
+// preloadCollection loads the items of the documents not deleted, sorted by position.
+func preloadCollection(tx *gorm.DB) *gorm.DB {
+	return tx.Preload("SharedWith").Preload("Items", func(db *gorm.DB) *gorm.DB {
+		documents := tx.Session(&gorm.Session{NewDB: true}).Model(&Document{}).Select("id")
+		return db.Where("document_id IN (?)", documents).Order("position")
+	})
+}
+
+// replaceCollectionMembers stores the items and shares of a collection, the
+// items are numbered following their order.
+func replaceCollectionMembers(tx *gorm.DB, collectionID uint, items []CollectionItem, shares []CollectionShare) error {
+	if items != nil {
+		seen := make(map[int]bool, len(items))
+		for i := range items {
+			if seen[items[i].DocumentID] {
+				return fmt.Errorf("document %d is repeated in the collection", items[i].DocumentID)
+			}
+			seen[items[i].DocumentID] = true
+			items[i].CollectionID = collectionID
+			items[i].Position = i
+		}
+
+		if len(items) != 0 {
+			ids := make([]int, 0, len(items))
+			for id := range seen {
+				ids = append(ids, id)
+			}
+
+			var existing int64
+			if err := tx.Model(&Document{}).Where("id IN ?", ids).Count(&existing).Error; err != nil {
+				return err
+			}
+			if existing != int64(len(ids)) {
+				return errors.New("collection items must reference existing documents")
+			}
+		}
+
+		if err := tx.Where("collection_id = ?", collectionID).Delete(&CollectionItem{}).Error; err != nil {
+			return err
+		}
+		if len(items) != 0 {
+			if err := tx.Create(&items).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	if shares != nil {
+		for i := range shares {
+			shares[i].CollectionID = collectionID
+		}
+
+		if err := tx.Where("collection_id = ?", collectionID).Delete(&CollectionShare{}).Error; err != nil {
+			return err
+		}
+		if len(shares) != 0 {
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&shares).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (d *DBCatalog) InsertCollection(ctx context.Context, request InsertCollectionRequest) (Collection, error) {
+	collection := Collection{
+		Name:        request.Collection.Name,
+		Description: request.Collection.Description,
+		OwnerID:     request.Collection.OwnerID,
+		Visibility:  request.Collection.Visibility,
+	}
+
+	if len(collection.Name) == 0 {
+		return Collection{}, errors.New("collection name must be defined")
+	}
+
+	if len(collection.Visibility) == 0 {
+		collection.Visibility = VisibilityPrivate
+	}
+
+	if !collection.Visibility.Valid() {
+		return Collection{}, errInvalidVisibility
+	}
+
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&collection).Error; err != nil {
+			return err
+		}
+
+		items := request.Collection.Items
+		if items == nil {
+			items = []CollectionItem{}
+		}
+		if err := replaceCollectionMembers(tx, collection.ID, items, request.Collection.SharedWith); err != nil {
+			return err
+		}
+
+		return preloadCollection(tx).First(&collection, collection.ID).Error
+	})
+
+	return collection, err
+}
+
+func (d *DBCatalog) GetCollection(ctx context.Context, request GetCollectionRequest) (Collection, error) {
+	tx := d.db.WithContext(ctx)
+
+	var collection Collection
+	return collection, preloadCollection(visibleCollections(tx, request.UserID)).First(&collection, request.CollectionID).Error
+}
+
+func (d *DBCatalog) UpdateCollection(ctx context.Context, request UpdateCollectionRequest) (Collection, error) {
+	update := request.Collection
+	if len(update.Visibility) != 0 && !update.Visibility.Valid() {
+		return Collection{}, errInvalidVisibility
+	}
+
+	var collection Collection
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&collection, request.CollectionID).Error; err != nil {
+			return err
+		}
+
+		if !request.Partial || len(update.Name) != 0 {
+			if len(update.Name) == 0 {
+				return errors.New("collection name must be defined")
+			}
+			collection.Name = update.Name
+		}
+
+		if !request.Partial || len(update.Description) != 0 {
+			collection.Description = update.Description
+		}
+
+		switch {
+		case len(update.Visibility) != 0:
+			collection.Visibility = update.Visibility
+		case !request.Partial:
+			collection.Visibility = VisibilityPrivate
+		}
+
+		if err := tx.Omit(clause.Associations).Save(&collection).Error; err != nil {
+			return err
+		}
+
+		items, shares := update.Items, update.SharedWith
+		if !request.Partial {
+			if items == nil {
+				items = []CollectionItem{}
+			}
+			if shares == nil {
+				shares = []CollectionShare{}
+			}
+		}
+		if err := replaceCollectionMembers(tx, collection.ID, items, shares); err != nil {
+			return err
+		}
+
+		return preloadCollection(tx).First(&collection, collection.ID).Error
+	})
+
+	return collection, err
+}
+
+func (d *DBCatalog) DeleteCollection(ctx context.Context, request DeleteCollectionRequest) error {
+	return d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&Collection{}, request.CollectionID)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return replaceCollectionMembers(tx, request.CollectionID, []CollectionItem{}, []CollectionShare{})
+	})
+}
+
+func (d *DBCatalog) ListCollections(ctx context.Context, request ListCollectionsRequest) (ListCollectionsResponse, error) {
+	query := visibleCollections(d.db.WithContext(ctx).Model(&Collection{}), request.UserID)
+
+	var totalElements int64
+	if err := query.Count(&totalElements).Error; err != nil {
+		return ListCollectionsResponse{}, err
+	}
+
+	var collections []Collection
+	err := preloadCollection(query).Order("collections.name").
+		Offset(request.Pagination.Offset()).Limit(request.Pagination.PageSize).
+		Find(&collections).Error
+
+	return ListCollectionsResponse{
+		Items:      collections,
+		Pagination: newPaginationResponse(totalElements, request.Pagination),
+	}, err
+}
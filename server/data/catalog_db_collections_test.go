@@ -0,0 +1,127 @@
+package data
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"testing"
+)
+
+func TestDBCatalog_Collections(t *testing.T) {
+	catalog, _ := newTestCatalog(t)
+
+	for _, title := range []string{"a", "b", "c"} {
+		err := catalog.InsertDocument(context.TODO(), InsertDocumentRequest{
+			Document: Document{Title: strptr(title), Uri: strptr(title)},
+		})
+		require.NoError(t, err)
+	}
+
+	const alice, bob, carol = 1, 2, 3
+
+	reading, err := catalog.InsertCollection(context.TODO(), InsertCollectionRequest{
+		Collection: Collection{
+			Name:    "reading",
+			OwnerID: alice,
+			Items: []CollectionItem{
+				{DocumentID: 3, Note: "first"},
+				{DocumentID: 1},
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, VisibilityPrivate, reading.Visibility, "collections are private by default")
+	require.Len(t, reading.Items, 2)
+	require.Equal(t, CollectionItem{CollectionID: reading.ID, DocumentID: 3, Position: 0, Note: "first"}, reading.Items[0])
+	require.Equal(t, 1, reading.Items[1].DocumentID)
+
+	shared, err := catalog.InsertCollection(context.TODO(), InsertCollectionRequest{
+		Collection: Collection{
+			Name:       "shared",
+			OwnerID:    alice,
+			Visibility: VisibilityShared,
+			SharedWith: []CollectionShare{{UserID: bob}},
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = catalog.InsertCollection(context.TODO(), InsertCollectionRequest{
+		Collection: Collection{Name: "public", OwnerID: carol, Visibility: VisibilityPublic},
+	})
+	require.NoError(t, err)
+
+	_, err = catalog.InsertCollection(context.TODO(), InsertCollectionRequest{
+		Collection: Collection{Name: "invalid", Items: []CollectionItem{{DocumentID: 42}}},
+	})
+	require.Error(t, err)
+
+	_, err = catalog.InsertCollection(context.TODO(), InsertCollectionRequest{
+		Collection: Collection{Name: "invalid", Visibility: "secret"},
+	})
+	require.ErrorIs(t, err, errInvalidVisibility)
+
+	visible := func(userID uint) []string {
+		response, err := catalog.ListCollections(context.TODO(), ListCollectionsRequest{
+			UserID:     userID,
+			Pagination: PaginationRequest{Page: 1, PageSize: 10},
+		})
+		require.NoError(t, err)
+
+		var names []string
+		for _, collection := range response.Items {
+			names = append(names, collection.Name)
+		}
+		return names
+	}
+	require.Equal(t, []string{"public", "reading", "shared"}, visible(alice))
+	require.Equal(t, []string{"public", "shared"}, visible(bob))
+	require.Equal(t, []string{"public"}, visible(carol))
+	require.Equal(t, []string{"public", "reading", "shared"}, visible(0), "without user every collection is listed")
+
+	_, err = catalog.GetCollection(context.TODO(), GetCollectionRequest{CollectionID: reading.ID, UserID: bob})
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	got, err := catalog.GetCollection(context.TODO(), GetCollectionRequest{CollectionID: shared.ID, UserID: bob})
+	require.NoError(t, err)
+	require.Equal(t, []CollectionShare{{CollectionID: shared.ID, UserID: bob}}, got.SharedWith)
+
+	response, err := catalog.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Collection: reading.ID,
+		Pagination: PaginationRequest{Page: 1, PageSize: 10},
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Items, 2)
+	require.Equal(t, "c", *response.Items[0].Title, "documents are sorted by position")
+	require.Equal(t, "a", *response.Items[1].Title)
+
+	updated, err := catalog.UpdateCollection(context.TODO(), UpdateCollectionRequest{
+		CollectionID: reading.ID,
+		Collection: Collection{
+			Visibility: VisibilityPublic,
+			Items:      []CollectionItem{{DocumentID: 1}, {DocumentID: 2}, {DocumentID: 3}},
+		},
+		Partial: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "reading", updated.Name)
+	require.Equal(t, VisibilityPublic, updated.Visibility)
+	require.Len(t, updated.Items, 3)
+	require.Empty(t, updated.Items[2].Note, "items are replaced")
+
+	_, err = catalog.UpdateCollection(context.TODO(), UpdateCollectionRequest{
+		CollectionID: reading.ID,
+		Collection:   Collection{Items: []CollectionItem{{DocumentID: 1}, {DocumentID: 1}}},
+		Partial:      true,
+	})
+	require.Error(t, err)
+
+	require.NoError(t, catalog.DeleteDocument(context.TODO(), DeleteDocumentRequest{DocumentID: 2}))
+	got, err = catalog.GetCollection(context.TODO(), GetCollectionRequest{CollectionID: reading.ID, UserID: carol})
+	require.NoError(t, err)
+	require.Len(t, got.Items, 2, "deleted documents are hidden")
+
+	require.NoError(t, catalog.DeleteCollection(context.TODO(), DeleteCollectionRequest{CollectionID: reading.ID}))
+	_, err = catalog.GetCollection(context.TODO(), GetCollectionRequest{CollectionID: reading.ID})
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	require.ErrorIs(t, catalog.DeleteCollection(context.TODO(), DeleteCollectionRequest{CollectionID: reading.ID}), gorm.ErrRecordNotFound)
+}
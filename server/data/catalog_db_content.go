@@ -0,0 +1,120 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/garugaru/knowledge/server/storage"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"os"
+)
+
+var errContentStorageDisabled = errors.New("document content storage is not configured")
+
+func documentContentKey(documentID int) string {
+	return fmt.Sprintf("documents/%d/content", documentID)
+}
+
+func (d *DBCatalog) PutDocumentContent(ctx context.Context, request PutDocumentContentRequest) (Document, error) {
+	if d.blobs == nil {
+		return Document{}, errContentStorageDisabled
+	}
+
+	var document Document
+	if err := d.db.WithContext(ctx).First(&document, request.DocumentID).Error; err != nil {
+		return Document{}, err
+	}
+
+	// the content is spooled to disk to know its size and checksum before storing it
+	spool, err := os.CreateTemp("", "knowledge-content-*")
+	if err != nil {
+		return Document{}, err
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(spool, hash), request.Content)
+	if err != nil {
+		return Document{}, err
+	}
+
+	contentType := request.ContentType
+	if len(contentType) == 0 {
+		head := make([]byte, 512)
+		n, err := spool.ReadAt(head, 0)
+		if err != nil && err != io.EOF {
+			return Document{}, err
+		}
+		contentType = http.DetectContentType(head[:n])
+	}
+
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return Document{}, err
+	}
+
+	if err := d.blobs.Put(ctx, documentContentKey(document.ID), spool, size); err != nil {
+		return Document{}, err
+	}
+
+	updates := map[string]interface{}{
+		"content_type":   contentType,
+		"content_size":   size,
+		"content_sha256": hex.EncodeToString(hash.Sum(nil)),
+	}
+	if d.extractors != nil {
+		updates["extraction_status"] = ExtractionPending
+		updates["extraction_error"] = ""
+	}
+
+	err = d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&document).Updates(updates).Error; err != nil {
+			return err
+		}
+		return recordStoredRevision(tx, document.ID, RevisionContent, request.UserID)
+	})
+	if err != nil {
+		return Document{}, err
+	}
+
+	if d.extractors != nil {
+		d.enqueueExtraction(document.ID)
+	}
+
+	return d.GetDocument(ctx, GetDocumentRequest{DocumentID: document.ID})
+}
+
+func (d *DBCatalog) GetDocumentContent(ctx context.Context, request GetDocumentContentRequest) (GetDocumentContentResponse, error) {
+	if d.blobs == nil {
+		return GetDocumentContentResponse{}, errContentStorageDisabled
+	}
+
+	var document Document
+	if err := d.db.WithContext(ctx).First(&document, request.DocumentID).Error; err != nil {
+		return GetDocumentContentResponse{}, err
+	}
+
+	if len(document.Content.SHA256) == 0 {
+		return GetDocumentContentResponse{}, ErrContentNotFound
+	}
+
+	content, err := d.blobs.Get(ctx, documentContentKey(document.ID))
+	if errors.Is(err, storage.ErrNotFound) {
+		return GetDocumentContentResponse{}, ErrContentNotFound
+	}
+	if err != nil {
+		return GetDocumentContentResponse{}, err
+	}
+
+	return GetDocumentContentResponse{
+		Content:      content,
+		ContentType:  document.Content.Type,
+		Size:         document.Content.Size,
+		SHA256:       document.Content.SHA256,
+		LastModified: document.UpdatedAt,
+	}, nil
+}
@@ -0,0 +1,71 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"github.com/garugaru/knowledge/server/storage"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDBCatalog_DocumentContent(t *testing.T) {
+	catalog, db := newTestCatalog(t, WithBlobStore(storage.NewLocalBlobStore(t.TempDir())))
+
+	err := catalog.InsertDocument(context.TODO(), InsertDocumentRequest{
+		Document: Document{
+			Title:   strptr("Test Title"),
+			Uri:     strptr("file://test.txt"),
+			Content: DocumentContent{SHA256: "client provided"},
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = catalog.GetDocumentContent(context.TODO(), GetDocumentContentRequest{DocumentID: 1})
+	require.ErrorIs(t, err, ErrContentNotFound, "content cannot be set on insert")
+
+	document, err := catalog.PutDocumentContent(context.TODO(), PutDocumentContentRequest{
+		DocumentID: 1,
+		Content:    strings.NewReader("hello world"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, DocumentContent{
+		Type:   "text/plain; charset=utf-8",
+		Size:   11,
+		SHA256: "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9",
+	}, document.Content)
+	require.Equal(t, "Test Title", *document.Title)
+
+	document, err = catalog.PutDocumentContent(context.TODO(), PutDocumentContentRequest{
+		DocumentID:  1,
+		ContentType: "application/pdf",
+		Content:     bytes.NewReader([]byte("%PDF-1.4")),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "application/pdf", document.Content.Type)
+	require.Equal(t, int64(8), document.Content.Size)
+
+	content, err := catalog.GetDocumentContent(context.TODO(), GetDocumentContentRequest{DocumentID: 1})
+	require.NoError(t, err)
+	defer content.Content.Close()
+	require.Equal(t, "application/pdf", content.ContentType)
+	require.Equal(t, document.Content.SHA256, content.SHA256)
+	read, err := io.ReadAll(content.Content)
+	require.NoError(t, err)
+	require.Equal(t, "%PDF-1.4", string(read))
+
+	_, err = catalog.PutDocumentContent(context.TODO(), PutDocumentContentRequest{
+		DocumentID: 9999,
+		Content:    strings.NewReader("hello world"),
+	})
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	disabled := NewDBCatalog(db)
+	_, err = disabled.PutDocumentContent(context.TODO(), PutDocumentContentRequest{
+		DocumentID: 1,
+		Content:    strings.NewReader("hello world"),
+	})
+	require.Error(t, err)
+}
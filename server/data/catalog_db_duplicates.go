@@ -0,0 +1,365 @@
+package data
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"gorm.io/gorm"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	defaultDuplicateThreshold = 0.6
+	// the signatures are split in bands of rows, the documents sharing a band
+	// are compared: pairs with a title similarity of 0.4 are found 3 times out of 4
+	minHashBands = 20
+	minHashRows  = 3
+	// titleWeight is the share of the title in the score of the documents with authors
+	titleWeight = 0.75
+)
+
+var (
+	duplicatePairs = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "knowledge_catalog_duplicate_pairs",
+		Help: "Number of pairs of similar documents found by the last duplicate detection.",
+	})
+	duplicateDetectionTimestamp = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "knowledge_catalog_duplicate_detection_timestamp_seconds",
+		Help: "Unix time of the last completed duplicate detection.",
+	})
+)
+
+type DuplicateOptions struct {
+	// Interval is the time between two detections
+	Interval time.Duration
+	// Threshold is the minimum score of the reported pairs, a default is used when 0
+	Threshold float64
+}
+
+// RunDuplicateDetection periodically looks for similar documents until ctx is done.
+func (d *DBCatalog) RunDuplicateDetection(ctx context.Context, opts DuplicateOptions) {
+	for {
+		// the last report is kept until a detection completes
+		logBackgroundError(ctx, "duplicates", d.FindDuplicates(ctx, opts))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(opts.Interval):
+		}
+	}
+}
+
+// duplicateDocument holds what the detection compares of a document.
+type duplicateDocument struct {
+	id        int
+	shingles  map[string]bool
+	surnames  map[string]bool
+	signature []uint64
+}
+
+// FindDuplicates compares the documents of the catalog, replacing the
+// reported pairs. The titles are compared by their shingles, the candidate
+// pairs being found through their MinHash signatures, and the documents
+// sharing authors score higher.
+func (d *DBCatalog) FindDuplicates(ctx context.Context, opts DuplicateOptions) error {
+	threshold := opts.Threshold
+	if threshold <= 0 {
+		threshold = defaultDuplicateThreshold
+	}
+
+	documents, err := d.duplicateDocuments(ctx)
+	if err != nil {
+		return err
+	}
+
+	buckets := make(map[uint64][]int)
+	for i, document := range documents {
+		for band := 0; band < minHashBands; band++ {
+			key := uint64(band)
+			for _, value := range document.signature[band*minHashRows : (band+1)*minHashRows] {
+				key = key*1099511628211 ^ value
+			}
+			buckets[key] = append(buckets[key], i)
+		}
+	}
+
+	compared := make(map[[2]int]bool)
+	var candidates []DuplicateCandidate
+	for _, bucket := range buckets {
+		for i := 0; i < len(bucket); i++ {
+			for j := i + 1; j < len(bucket); j++ {
+				pair := [2]int{bucket[i], bucket[j]}
+				if compared[pair] {
+					continue
+				}
+				compared[pair] = true
+
+				candidate := scoreDuplicates(documents[bucket[i]], documents[bucket[j]])
+				if candidate.Score >= threshold {
+					candidates = append(candidates, candidate)
+				}
+			}
+		}
+	}
+
+	err = d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&DuplicateCandidate{}).Error; err != nil {
+			return err
+		}
+		if len(candidates) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(candidates, 500).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	duplicatePairs.Set(float64(len(candidates)))
+	duplicateDetectionTimestamp.Set(float64(time.Now().Unix()))
+	return nil
+}
+
+// duplicateDocuments loads the titles and authors of the documents, the
+// documents are sorted by id.
+func (d *DBCatalog) duplicateDocuments(ctx context.Context) ([]duplicateDocument, error) {
+	const batchSize = 500
+
+	var documents []duplicateDocument
+	for lastID := 0; ; {
+		var batch []Document
+		err := d.db.WithContext(ctx).Select("id", "title").Preload("Authors").
+			Where("id > ?", lastID).Order("id").Limit(batchSize).Find(&batch).Error
+		if err != nil {
+			return nil, err
+		}
+
+		for _, document := range batch {
+			var title string
+			if document.Title != nil {
+				title = *document.Title
+			}
+
+			shingles := titleShingles(title)
+			if len(shingles) == 0 {
+				continue
+			}
+
+			surnames := make(map[string]bool, len(document.Authors))
+			for _, author := range document.Authors {
+				if surname := strings.ToLower(strings.TrimSpace(author.Surname)); len(surname) != 0 {
+					surnames[surname] = true
+				}
+			}
+
+			documents = append(documents, duplicateDocument{
+				id:        document.ID,
+				shingles:  shingles,
+				surnames:  surnames,
+				signature: minHashSignature(shingles),
+			})
+		}
+
+		if len(batch) < batchSize {
+			return documents, nil
+		}
+		lastID = batch[len(batch)-1].ID
+	}
+}
+
+// titleShingles returns the words of the title along with each pair of
+// consecutive words, ignoring case and punctuation.
+func titleShingles(title string) map[string]bool {
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	shingles := make(map[string]bool, 2*len(words))
+	for i, word := range words {
+		shingles[word] = true
+		if i > 0 {
+			shingles[words[i-1]+" "+word] = true
+		}
+	}
+	return shingles
+}
+
+// minHashSignature keeps the minimum of each hash function over the
+// shingles, two signatures share a value with the probability of the
+// shingles being shared.
+func minHashSignature(shingles map[string]bool) []uint64 {
+	signature := make([]uint64, minHashBands*minHashRows)
+	for i := range signature {
+		signature[i] = ^uint64(0)
+	}
+
+	for shingle := range shingles {
+		hash := fnv.New64a()
+		hash.Write([]byte(shingle))
+		value := hash.Sum64()
+
+		for i := range signature {
+			if h := mixHash(value ^ uint64(i+1)*0x9e3779b97f4a7c15); h < signature[i] {
+				signature[i] = h
+			}
+		}
+	}
+	return signature
+}
+
+// mixHash is the splitmix64 finalizer, deriving a hash function per seed.
+func mixHash(x uint64) uint64 {
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for value := range a {
+		if b[value] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+func scoreDuplicates(a, b duplicateDocument) DuplicateCandidate {
+	if a.id > b.id {
+		a, b = b, a
+	}
+
+	candidate := DuplicateCandidate{
+		DocumentID:  a.id,
+		DuplicateID: b.id,
+		TitleScore:  jaccard(a.shingles, b.shingles),
+	}
+	candidate.Score = candidate.TitleScore
+
+	if len(a.surnames) != 0 && len(b.surnames) != 0 {
+		authorScore := jaccard(a.surnames, b.surnames)
+		candidate.AuthorScore = &authorScore
+		candidate.Score = titleWeight*candidate.TitleScore + (1-titleWeight)*authorScore
+	}
+	return candidate
+}
+
+// ListDuplicates groups the pairs reported by the last detection in
+// clusters, the most similar first.
+func (d *DBCatalog) ListDuplicates(ctx context.Context, request ListDuplicatesRequest) (ListDuplicatesResponse, error) {
+	tx := d.db.WithContext(ctx)
+
+	// the documents deleted since the detection are left out
+	live := tx.Model(&Document{}).Select("id")
+	var candidates []DuplicateCandidate
+	err := tx.Where("score >= ?", request.MinScore).
+		Where("document_id IN (?) AND duplicate_id IN (?)", live, live).
+		Order("score DESC").Order("document_id").Order("duplicate_id").
+		Find(&candidates).Error
+	if err != nil {
+		return ListDuplicatesResponse{}, err
+	}
+
+	parents := make(map[int]int)
+	var find func(id int) int
+	find = func(id int) int {
+		parent, ok := parents[id]
+		if !ok || parent == id {
+			parents[id] = id
+			return id
+		}
+		root := find(parent)
+		parents[id] = root
+		return root
+	}
+
+	for _, candidate := range candidates {
+		a, b := find(candidate.DocumentID), find(candidate.DuplicateID)
+		if a < b {
+			parents[b] = a
+		} else if b < a {
+			parents[a] = b
+		}
+	}
+
+	// the candidates are sorted by score, the first pair of a cluster is its best one
+	var clusters []*DuplicateCluster
+	byRoot := make(map[int]*DuplicateCluster)
+	for _, candidate := range candidates {
+		root := find(candidate.DocumentID)
+		cluster, ok := byRoot[root]
+		if !ok {
+			cluster = &DuplicateCluster{Score: candidate.Score}
+			byRoot[root] = cluster
+			clusters = append(clusters, cluster)
+		}
+		cluster.Pairs = append(cluster.Pairs, candidate)
+	}
+
+	response := ListDuplicatesResponse{
+		Items:      []DuplicateCluster{},
+		Pagination: newPaginationResponse(int64(len(clusters)), request.Pagination),
+	}
+
+	offset := request.Pagination.Offset()
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(clusters) {
+		return response, nil
+	}
+	end := offset + request.Pagination.PageSize
+	if end > len(clusters) {
+		end = len(clusters)
+	}
+	if end <= offset {
+		return response, nil
+	}
+
+	var ids []int
+	for _, cluster := range clusters[offset:end] {
+		ids = append(ids, clusterDocumentIDs(cluster.Pairs)...)
+	}
+
+	var documents []Document
+	if err := tx.Preload("Tags").Preload("Authors").Where("id IN ?", ids).Find(&documents).Error; err != nil {
+		return ListDuplicatesResponse{}, err
+	}
+	byID := make(map[int]Document, len(documents))
+	for _, document := range documents {
+		byID[document.ID] = document
+	}
+
+	for _, cluster := range clusters[offset:end] {
+		for _, id := range clusterDocumentIDs(cluster.Pairs) {
+			cluster.Documents = append(cluster.Documents, byID[id])
+		}
+		response.Items = append(response.Items, *cluster)
+	}
+
+	return response, nil
+}
+
+func clusterDocumentIDs(pairs []DuplicateCandidate) []int {
+	seen := make(map[int]bool)
+	var ids []int
+	for _, pair := range pairs {
+		for _, id := range []int{pair.DocumentID, pair.DuplicateID} {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
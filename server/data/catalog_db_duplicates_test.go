@@ -0,0 +1,92 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestDBCatalog_FindDuplicates(t *testing.T) {
+	catalog, _ := newTestCatalog(t)
+
+	documents := []Document{
+		{Title: strptr("Paxos Made Simple"), Authors: []DocumentAuthor{{Name: "Leslie", Surname: "Lamport"}}},
+		{Title: strptr("paxos made simple."), Authors: []DocumentAuthor{{Name: "L.", Surname: "Lamport"}}},
+		{Title: strptr("Paxos Made Simple 2001")},
+		{Title: strptr("The Go Programming Language"), Authors: []DocumentAuthor{{Name: "Alan", Surname: "Donovan"}}},
+		{Title: strptr("Go Programming"), Authors: []DocumentAuthor{{Name: "Brian", Surname: "Kernighan"}}},
+		{Title: strptr("Time, Clocks, and the Ordering of Events in a Distributed System")},
+	}
+	for i, document := range documents {
+		document.Uri = strptr(fmt.Sprintf("https://example.com/%d", i))
+		require.NoError(t, catalog.InsertDocument(context.TODO(), InsertDocumentRequest{Document: document}))
+	}
+
+	require.NoError(t, catalog.FindDuplicates(context.TODO(), DuplicateOptions{}))
+
+	duplicates, err := catalog.ListDuplicates(context.TODO(), ListDuplicatesRequest{Pagination: PaginationRequest{Page: 1, PageSize: 10}})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, duplicates.Pagination.TotalElements)
+	require.Len(t, duplicates.Items, 1)
+
+	cluster := duplicates.Items[0]
+	require.Equal(t, 1.0, cluster.Score)
+	require.Len(t, cluster.Documents, 3)
+	for i, document := range cluster.Documents {
+		require.Equal(t, i+1, document.ID)
+	}
+	require.Len(t, cluster.Pairs, 3)
+	require.Equal(t, 1, cluster.Pairs[0].DocumentID)
+	require.Equal(t, 2, cluster.Pairs[0].DuplicateID)
+	require.NotNil(t, cluster.Pairs[0].AuthorScore)
+	require.Nil(t, cluster.Pairs[1].AuthorScore, "the documents without authors are compared by title")
+	require.InDelta(t, 5.0/7, cluster.Pairs[1].Score, 0.001)
+
+	duplicates, err = catalog.ListDuplicates(context.TODO(), ListDuplicatesRequest{MinScore: 0.8, Pagination: PaginationRequest{Page: 1, PageSize: 10}})
+	require.NoError(t, err)
+	require.Len(t, duplicates.Items, 1)
+	require.Len(t, duplicates.Items[0].Documents, 2)
+
+	require.NoError(t, catalog.DeleteDocument(context.TODO(), DeleteDocumentRequest{DocumentID: 2}))
+	duplicates, err = catalog.ListDuplicates(context.TODO(), ListDuplicatesRequest{Pagination: PaginationRequest{Page: 1, PageSize: 10}})
+	require.NoError(t, err)
+	require.Len(t, duplicates.Items, 1)
+	require.Len(t, duplicates.Items[0].Pairs, 1, "the deleted documents are left out of the report")
+
+	duplicates, err = catalog.ListDuplicates(context.TODO(), ListDuplicatesRequest{Pagination: PaginationRequest{Page: 2, PageSize: 10}})
+	require.NoError(t, err)
+	require.Empty(t, duplicates.Items)
+
+	for _, pagination := range []PaginationRequest{{Page: 0, PageSize: 100}, {Page: 1, PageSize: -1}} {
+		duplicates, err = catalog.ListDuplicates(context.TODO(), ListDuplicatesRequest{Pagination: pagination})
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(duplicates.Items), 1, "%+v", pagination)
+	}
+
+	require.NoError(t, catalog.FindDuplicates(context.TODO(), DuplicateOptions{Threshold: 0.99}))
+	duplicates, err = catalog.ListDuplicates(context.TODO(), ListDuplicatesRequest{Pagination: PaginationRequest{Page: 1, PageSize: 10}})
+	require.NoError(t, err)
+	require.Empty(t, duplicates.Items, "each detection replaces the reported pairs")
+}
+
+func TestMinHashSignature(t *testing.T) {
+	a := titleShingles("Designing Data-Intensive Applications: The Big Ideas Behind Reliable, Scalable, and Maintainable Systems")
+	b := titleShingles("Designing Data Intensive Applications")
+	c := titleShingles("Structure and Interpretation of Computer Programs")
+
+	estimate := func(x, y []uint64) float64 {
+		shared := 0
+		for i := range x {
+			if x[i] == y[i] {
+				shared++
+			}
+		}
+		return float64(shared) / float64(len(x))
+	}
+
+	signatureA, signatureB, signatureC := minHashSignature(a), minHashSignature(b), minHashSignature(c)
+	require.Equal(t, signatureA, minHashSignature(titleShingles("designing data intensive applications the big ideas behind reliable scalable and maintainable systems")))
+	require.InDelta(t, jaccard(a, b), estimate(signatureA, signatureB), 0.2)
+	require.InDelta(t, jaccard(a, c), estimate(signatureA, signatureC), 0.2)
+}
@@ -0,0 +1,171 @@
+package data
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"time"
+)
+
+const (
+	// DefaultEventInterval is the time between two reads of the outbox when none is configured
+	DefaultEventInterval = time.Second
+	// DefaultEventRetention is the time the events stay in the outbox when none is configured
+	DefaultEventRetention = 7 * 24 * time.Hour
+
+	eventBatchSize = 500
+	// eventPurgeInterval is the time between two deletions of the expired events
+	eventPurgeInterval = time.Hour
+)
+
+type EventOptions struct {
+	// Interval is the time between two reads of the outbox
+	Interval time.Duration
+	// Retention is the time the events stay in the outbox
+	Retention time.Duration
+}
+
+// RunEventRelay periodically publishes the events of the outbox to the
+// subscribers until ctx is done. The events are listed once published, the
+// relay runs with the default options when none are given.
+func (d *DBCatalog) RunEventRelay(ctx context.Context, opts EventOptions) {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultEventInterval
+	}
+	if opts.Retention <= 0 {
+		opts.Retention = DefaultEventRetention
+	}
+
+	var purged time.Time
+	for {
+		// the events left are published on the next run
+		logBackgroundError(ctx, "events", d.PublishEvents(ctx))
+
+		if time.Since(purged) >= eventPurgeInterval {
+			err := d.PurgeEvents(ctx, time.Now().Add(-opts.Retention))
+			if err == nil {
+				purged = time.Now()
+			}
+			logBackgroundError(ctx, "events", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(opts.Interval):
+		}
+	}
+}
+
+// revisionEvent returns the type of the event of a change recorded as a
+// revision with action.
+func revisionEvent(action RevisionAction) EventType {
+	switch action {
+	case RevisionInsert, RevisionUndelete:
+		return EventDocumentCreated
+	case RevisionDelete:
+		return EventDocumentDeleted
+	default:
+		return EventDocumentUpdated
+	}
+}
+
+// recordEvent stores the event in the outbox, it is published once the
+// transaction is committed.
+func recordEvent(tx *gorm.DB, event CatalogEvent) error {
+	return tx.Create(&event).Error
+}
+
+// initEvents skips the events published before the start, the subscribers
+// read them with ListEvents.
+func (d *DBCatalog) initEvents() error {
+	d.publishedMu.Lock()
+	defer d.publishedMu.Unlock()
+
+	return d.db.Model(&CatalogEvent{}).Select("COALESCE(MAX(sequence), 0)").Scan(&d.published).Error
+}
+
+// sequenceEvents numbers the committed events in the order they are found.
+// The IDs are allocated before the transactions commit, a transaction
+// committing late would leave an event with an ID below the ones already
+// delivered: the sequence is only assigned once the event is visible.
+func (d *DBCatalog) sequenceEvents(ctx context.Context) error {
+	for {
+		var events []CatalogEvent
+		err := d.db.WithContext(ctx).Where("sequence IS NULL").Order("id").Limit(eventBatchSize).Find(&events).Error
+		if err != nil || len(events) == 0 {
+			return err
+		}
+
+		var last uint
+		if err := d.db.WithContext(ctx).Model(&CatalogEvent{}).Select("COALESCE(MAX(sequence), 0)").Scan(&last).Error; err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			// the unique sequence fails the relays of other instances racing for it
+			result := d.db.WithContext(ctx).Model(&CatalogEvent{}).Where("id = ? AND sequence IS NULL", event.ID).
+				UpdateColumn("sequence", last+1)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected != 0 {
+				last++
+			}
+		}
+
+		if len(events) < eventBatchSize {
+			return nil
+		}
+	}
+}
+
+// PublishEvents numbers the events committed since the last call and
+// publishes them to the subscribers, in sequence.
+func (d *DBCatalog) PublishEvents(ctx context.Context) error {
+	d.publishedMu.Lock()
+	defer d.publishedMu.Unlock()
+
+	if err := d.sequenceEvents(ctx); err != nil {
+		return err
+	}
+
+	for {
+		var events []CatalogEvent
+		err := d.db.WithContext(ctx).Where("sequence > ?", d.published).Order("sequence").Limit(eventBatchSize).Find(&events).Error
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			d.events.publish(event)
+			d.published = *event.Sequence
+		}
+
+		if len(events) < eventBatchSize {
+			return nil
+		}
+	}
+}
+
+// PurgeEvents deletes the events published before createdBefore.
+func (d *DBCatalog) PurgeEvents(ctx context.Context, createdBefore time.Time) error {
+	return d.db.WithContext(ctx).Where("sequence IS NOT NULL AND created_at < ?", createdBefore).Delete(&CatalogEvent{}).Error
+}
+
+// ListEvents returns the events numbered by the relay following the sequence
+// request.After.
+func (d *DBCatalog) ListEvents(ctx context.Context, request ListEventsRequest) (ListEventsResponse, error) {
+	limit := request.Limit
+	if limit <= 0 || limit > eventBatchSize {
+		limit = eventBatchSize
+	}
+
+	events := []CatalogEvent{}
+	err := d.db.WithContext(ctx).Where("sequence > ?", request.After).Order("sequence").Limit(limit).Find(&events).Error
+	return ListEventsResponse{Items: events}, err
+}
+
+// SubscribeEvents returns the events published until ctx is done.
+func (d *DBCatalog) SubscribeEvents(ctx context.Context) <-chan CatalogEvent {
+	return d.events.subscribe(ctx)
+}
@@ -0,0 +1,116 @@
+package data
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestDBCatalog_Events(t *testing.T) {
+	catalog, db := newTestCatalog(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	subscription := catalog.SubscribeEvents(ctx)
+
+	err := catalog.InsertDocument(context.TODO(), InsertDocumentRequest{
+		Document: Document{Title: strptr("title"), Uri: strptr("uri"), Tags: []DocumentTag{{Tag: "tag"}}},
+	})
+	require.NoError(t, err)
+	_, err = catalog.UpdateDocument(context.TODO(), UpdateDocumentRequest{
+		DocumentID: 1,
+		Document:   Document{Title: strptr("updated")},
+		Partial:    true,
+		UserID:     7,
+	})
+	require.NoError(t, err)
+	_, err = catalog.UpdateTag(context.TODO(), UpdateTagRequest{TagID: 1, Tag: DocumentTag{Tag: "renamed"}, UserID: 8})
+	require.NoError(t, err)
+	require.NoError(t, catalog.DeleteDocument(context.TODO(), DeleteDocumentRequest{DocumentID: 1}))
+
+	events, err := catalog.ListEvents(context.TODO(), ListEventsRequest{})
+	require.NoError(t, err)
+	require.Empty(t, events.Items, "the events are listed once sequenced by the relay")
+	require.Empty(t, subscription, "the events are published by the relay")
+
+	require.NoError(t, catalog.PublishEvents(context.TODO()))
+	events, err = catalog.ListEvents(context.TODO(), ListEventsRequest{})
+	require.NoError(t, err)
+	require.Len(t, events.Items, 4)
+	var types []EventType
+	for i, event := range events.Items {
+		require.Equal(t, 1, event.DocumentID)
+		require.Equal(t, uint(i+1), *event.Sequence)
+		types = append(types, event.Type)
+	}
+	require.Equal(t, []EventType{EventDocumentCreated, EventDocumentUpdated, EventDocumentUpdated, EventDocumentDeleted}, types)
+	require.NotZero(t, events.Items[1].RevisionID)
+	require.Equal(t, uint(7), events.Items[1].UserID)
+	require.NotZero(t, events.Items[2].RevisionID, "the taxonomy changes record a revision")
+	require.Equal(t, uint(8), events.Items[2].UserID)
+
+	resumed, err := catalog.ListEvents(context.TODO(), ListEventsRequest{After: *events.Items[1].Sequence, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, resumed.Items, 1)
+	require.Equal(t, events.Items[2].ID, resumed.Items[0].ID)
+
+	for _, expected := range events.Items {
+		require.Equal(t, expected.ID, (<-subscription).ID)
+	}
+	require.NoError(t, catalog.PublishEvents(context.TODO()))
+	require.Empty(t, subscription, "the events are published once")
+
+	cancel()
+	require.Eventually(t, func() bool {
+		_, open := <-subscription
+		return !open
+	}, time.Second, 10*time.Millisecond)
+
+	restarted := NewDBCatalog(db)
+	require.NoError(t, restarted.Init())
+	restartedSubscription := restarted.SubscribeEvents(context.TODO())
+	require.NoError(t, restarted.PublishEvents(context.TODO()))
+	require.Empty(t, restartedSubscription, "the events stored before the start must not be published")
+
+	require.NoError(t, catalog.PurgeEvents(context.TODO(), time.Now().Add(time.Hour)))
+	events, err = catalog.ListEvents(context.TODO(), ListEventsRequest{})
+	require.NoError(t, err)
+	require.Empty(t, events.Items)
+}
+
+func TestDBCatalog_Events_OutOfOrder(t *testing.T) {
+	catalog, db := newTestCatalog(t)
+
+	subscription := catalog.SubscribeEvents(context.TODO())
+	received := func() []uint {
+		var ids []uint
+		for len(subscription) > 0 {
+			ids = append(ids, (<-subscription).ID)
+		}
+		return ids
+	}
+	listed := func(after uint) []uint {
+		response, err := catalog.ListEvents(context.TODO(), ListEventsRequest{After: after})
+		require.NoError(t, err)
+		var ids []uint
+		for _, event := range response.Items {
+			ids = append(ids, event.ID)
+		}
+		return ids
+	}
+
+	// sqlite serializes the writes, the transactions committed out of order
+	// on other databases are reproduced storing the events with their IDs:
+	// the first transaction gets the ID 2 and commits after the second one
+	require.NoError(t, db.Create(&CatalogEvent{ID: 1, Type: EventDocumentCreated, DocumentID: 1}).Error)
+	require.NoError(t, db.Create(&CatalogEvent{ID: 3, Type: EventDocumentCreated, DocumentID: 2}).Error)
+	require.NoError(t, catalog.PublishEvents(context.TODO()))
+	require.Equal(t, []uint{1, 3}, received())
+	require.Equal(t, []uint{1, 3}, listed(0))
+
+	// the late commit is numbered after the events already delivered
+	require.NoError(t, db.Create(&CatalogEvent{ID: 2, Type: EventDocumentUpdated, DocumentID: 1, CreatedAt: time.Now().Add(-time.Hour)}).Error)
+	require.NoError(t, catalog.PublishEvents(context.TODO()))
+	require.Equal(t, []uint{2}, received())
+	require.Equal(t, []uint{2}, listed(2))
+}
@@ -0,0 +1,494 @@
+package data
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// exportRecordsName is the name of the records in the tar.gz exports
+	exportRecordsName = "catalog.ndjson"
+	// exportContentPrefix prefixes the document contents in the tar.gz exports
+	exportContentPrefix = "contents/"
+	maxExportRecordSize = 64 << 20
+)
+
+// ExportRecord is a line of a catalog export, only one of its fields is set.
+// Kinds, tags and authors come before the documents, parent tags before their
+// children, so that a restore can recreate them in order.
+type ExportRecord struct {
+	Kind     *DocumentKind     `json:"kind,omitempty"`
+	Tag      *DocumentTag      `json:"tag,omitempty"`
+	Author   *DocumentAuthor   `json:"author,omitempty"`
+	Document *ExportedDocument `json:"document,omitempty"`
+}
+
+// ExportedDocument is a document with its kind, tags and authors along with
+// the text extracted from its content.
+type ExportedDocument struct {
+	Document
+	Text string `json:"text,omitempty"`
+}
+
+func (d *DBCatalog) ExportCatalog(ctx context.Context, request ExportCatalogRequest) error {
+	switch request.Format {
+	case ExportNDJSON:
+		_, err := d.exportRecords(ctx, request.Writer)
+		return err
+	case ExportTarGz:
+		return d.exportArchive(ctx, request.Writer)
+	default:
+		return fmt.Errorf("unknown export format %s", request.Format)
+	}
+}
+
+// exportRecords writes the records, returning the ids of the documents with
+// a content.
+func (d *DBCatalog) exportRecords(ctx context.Context, writer io.Writer) ([]int, error) {
+	// the deleted records are exported too, restoring the catalog as it was
+	tx := d.db.WithContext(ctx).Unscoped().Session(&gorm.Session{})
+	buffered := bufio.NewWriter(writer)
+	encoder := json.NewEncoder(buffered)
+
+	var kinds []DocumentKind
+	if err := tx.Order("id").Find(&kinds).Error; err != nil {
+		return nil, err
+	}
+	for i := range kinds {
+		if err := encoder.Encode(ExportRecord{Kind: &kinds[i]}); err != nil {
+			return nil, err
+		}
+	}
+
+	var tags []DocumentTag
+	err := tx.Order("(SELECT MAX(depth) FROM tag_closures WHERE tag_closures.descendant_id = document_tags.id)").
+		Order("id").Find(&tags).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := fillTagAliases(tx, tags); err != nil {
+		return nil, err
+	}
+	for i := range tags {
+		if err := encoder.Encode(ExportRecord{Tag: &tags[i]}); err != nil {
+			return nil, err
+		}
+	}
+
+	var authors []DocumentAuthor
+	if err := tx.Order("id").Find(&authors).Error; err != nil {
+		return nil, err
+	}
+	if err := fillAuthorAliases(tx, authors); err != nil {
+		return nil, err
+	}
+	for i := range authors {
+		if err := encoder.Encode(ExportRecord{Author: &authors[i]}); err != nil {
+			return nil, err
+		}
+	}
+
+	var contents []int
+	const batchSize = 100
+	for lastID := 0; ; {
+		var documents []Document
+		err := tx.Preload("DocumentKind").Preload("Tags").Preload("Authors").Preload("Text").
+			Where("id > ?", lastID).Order("id").Limit(batchSize).Find(&documents).Error
+		if err != nil {
+			return nil, err
+		}
+
+		for _, document := range documents {
+			exported := ExportedDocument{Document: document}
+			if document.Text != nil {
+				exported.Text = document.Text.Text
+			}
+			if len(document.Content.SHA256) != 0 {
+				contents = append(contents, document.ID)
+			}
+
+			if err := encoder.Encode(ExportRecord{Document: &exported}); err != nil {
+				return nil, err
+			}
+		}
+
+		if len(documents) < batchSize {
+			break
+		}
+		lastID = documents[len(documents)-1].ID
+	}
+
+	return contents, buffered.Flush()
+}
+
+func fillTagAliases(tx *gorm.DB, tags []DocumentTag) error {
+	var aliases []TagAlias
+	if err := tx.Order("alias").Find(&aliases).Error; err != nil {
+		return err
+	}
+
+	positions := make(map[uint]int, len(tags))
+	for i, tag := range tags {
+		positions[tag.ID] = i
+	}
+	for _, alias := range aliases {
+		if i, ok := positions[alias.TagID]; ok {
+			tags[i].Aliases = append(tags[i].Aliases, alias.Alias)
+		}
+	}
+	return nil
+}
+
+func fillAuthorAliases(tx *gorm.DB, authors []DocumentAuthor) error {
+	var aliases []AuthorAlias
+	if err := tx.Order("surname, name").Find(&aliases).Error; err != nil {
+		return err
+	}
+
+	positions := make(map[uint]int, len(authors))
+	for i, author := range authors {
+		positions[author.ID] = i
+	}
+	for _, alias := range aliases {
+		if i, ok := positions[alias.AuthorID]; ok {
+			authors[i].Aliases = append(authors[i].Aliases, alias)
+		}
+	}
+	return nil
+}
+
+// exportArchive writes the records followed by the document contents, the
+// records are spooled to disk as tar needs their size upfront.
+func (d *DBCatalog) exportArchive(ctx context.Context, writer io.Writer) error {
+	if d.blobs == nil {
+		return errContentStorageDisabled
+	}
+
+	spool, err := os.CreateTemp("", "knowledge-export-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	contents, err := d.exportRecords(ctx, spool)
+	if err != nil {
+		return err
+	}
+
+	size, err := spool.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	compressed := gzip.NewWriter(writer)
+	archive := tar.NewWriter(compressed)
+
+	if err := writeArchiveEntry(archive, exportRecordsName, spool, size); err != nil {
+		return err
+	}
+
+	for _, documentID := range contents {
+		if err := d.exportContent(ctx, archive, documentID); err != nil {
+			return err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	return compressed.Close()
+}
+
+func (d *DBCatalog) exportContent(ctx context.Context, archive *tar.Writer, documentID int) error {
+	content, err := d.blobs.Get(ctx, documentContentKey(documentID))
+	if err != nil {
+		return fmt.Errorf("reading the content of document %d: %w", documentID, err)
+	}
+	defer content.Close()
+
+	size, err := content.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	return writeArchiveEntry(archive, exportContentPrefix+strconv.Itoa(documentID), content, size)
+}
+
+func writeArchiveEntry(archive *tar.Writer, name string, content io.Reader, size int64) error {
+	err := archive.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    size,
+		ModTime: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(archive, content)
+	return err
+}
+
+// RestoreCatalog recreates an exported catalog with the same ids, the catalog
+// must not have any document, kind, tag or author.
+func (d *DBCatalog) RestoreCatalog(ctx context.Context, request RestoreCatalogRequest) (RestoreCatalogResponse, error) {
+	for _, model := range []interface{}{&Document{}, &DocumentKind{}, &DocumentTag{}, &DocumentAuthor{}} {
+		var count int64
+		if err := d.db.WithContext(ctx).Model(model).Unscoped().Count(&count).Error; err != nil {
+			return RestoreCatalogResponse{}, err
+		}
+		if count != 0 {
+			return RestoreCatalogResponse{}, errors.New("the catalog must be empty to be restored")
+		}
+	}
+
+	var (
+		response RestoreCatalogResponse
+		err      error
+	)
+
+	switch request.Format {
+	case ExportNDJSON:
+		response, err = d.restoreRecords(ctx, request.Reader)
+	case ExportTarGz:
+		response, err = d.restoreArchive(ctx, request.Reader)
+	default:
+		err = fmt.Errorf("unknown export format %s", request.Format)
+	}
+	if err != nil {
+		return response, err
+	}
+
+	return response, d.Reindex(ctx)
+}
+
+func (d *DBCatalog) restoreArchive(ctx context.Context, reader io.Reader) (RestoreCatalogResponse, error) {
+	compressed, err := gzip.NewReader(reader)
+	if err != nil {
+		return RestoreCatalogResponse{}, err
+	}
+	defer compressed.Close()
+
+	var (
+		response RestoreCatalogResponse
+		restored bool
+	)
+
+	archive := tar.NewReader(compressed)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return response, err
+		}
+
+		switch {
+		case header.Name == exportRecordsName:
+			if response, err = d.restoreRecords(ctx, archive); err != nil {
+				return response, err
+			}
+			restored = true
+		case strings.HasPrefix(header.Name, exportContentPrefix):
+			if !restored {
+				return response, errors.New("the document contents must follow the catalog records")
+			}
+			if d.blobs == nil {
+				return response, errContentStorageDisabled
+			}
+
+			documentID, err := strconv.Atoi(strings.TrimPrefix(header.Name, exportContentPrefix))
+			if err != nil {
+				return response, fmt.Errorf("invalid content entry %s", header.Name)
+			}
+			if err := d.blobs.Put(ctx, documentContentKey(documentID), archive, header.Size); err != nil {
+				return response, err
+			}
+			response.Contents++
+		}
+	}
+
+	if !restored {
+		return response, fmt.Errorf("the archive has no %s", exportRecordsName)
+	}
+	return response, nil
+}
+
+// restoreRecords inserts the records in a single transaction, keeping their
+// ids, timestamps and the state managed by the catalog.
+func (d *DBCatalog) restoreRecords(ctx context.Context, reader io.Reader) (RestoreCatalogResponse, error) {
+	var response RestoreCatalogResponse
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), maxExportRecordSize)
+
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for line := 1; scanner.Scan(); line++ {
+			if len(strings.TrimSpace(scanner.Text())) == 0 {
+				continue
+			}
+
+			var record ExportRecord
+			if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+				return fmt.Errorf("line %d: %w", line, err)
+			}
+
+			if err := restoreRecord(tx, record, &response); err != nil {
+				return fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+
+		return resetSequences(tx)
+	})
+
+	return response, err
+}
+
+func restoreRecord(tx *gorm.DB, record ExportRecord, response *RestoreCatalogResponse) error {
+	switch {
+	case record.Kind != nil:
+		response.Kinds++
+		return tx.Create(record.Kind).Error
+	case record.Tag != nil:
+		response.Tags++
+		return restoreTag(tx, *record.Tag)
+	case record.Author != nil:
+		response.Authors++
+		return restoreAuthor(tx, *record.Author)
+	case record.Document != nil:
+		response.Documents++
+		return restoreDocument(tx, *record.Document)
+	default:
+		return errors.New("empty record")
+	}
+}
+
+func restoreTag(tx *gorm.DB, tag DocumentTag) error {
+	var ancestors []TagClosure
+	if tag.ParentID != nil {
+		if err := tx.Where("descendant_id = ?", *tag.ParentID).Find(&ancestors).Error; err != nil {
+			return err
+		}
+		if len(ancestors) == 0 {
+			return fmt.Errorf("the parent of tag %s must be restored first", tag.Tag)
+		}
+	}
+
+	aliases := tag.Aliases
+	tag.Aliases, tag.Children = nil, nil
+	if err := tx.Create(&tag).Error; err != nil {
+		return err
+	}
+
+	closure := []TagClosure{{AncestorID: tag.ID, DescendantID: tag.ID}}
+	for _, ancestor := range ancestors {
+		closure = append(closure, TagClosure{AncestorID: ancestor.AncestorID, DescendantID: tag.ID, Depth: ancestor.Depth + 1})
+	}
+	if err := tx.Create(&closure).Error; err != nil {
+		return err
+	}
+
+	for _, alias := range aliases {
+		if err := tx.Create(&TagAlias{Alias: alias, TagID: tag.ID}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func restoreAuthor(tx *gorm.DB, author DocumentAuthor) error {
+	aliases := author.Aliases
+	author.Aliases = nil
+	if err := tx.Create(&author).Error; err != nil {
+		return err
+	}
+
+	for _, alias := range aliases {
+		alias.AuthorID = author.ID
+		if err := tx.Create(&alias).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func restoreDocument(tx *gorm.DB, exported ExportedDocument) error {
+	document := exported.Document
+	document.UriKey = nil
+	if !document.DeletedAt.Valid {
+		var duplicate *DuplicateDocumentError
+		err := checkDuplicateUri(tx, document.Uri, document.ID)
+		if err != nil && !errors.As(err, &duplicate) {
+			return err
+		}
+		// the duplicates of a catalog created by older versions keep no key
+		if err == nil {
+			document.UriKey = uriKey(document.Uri)
+		}
+	}
+
+	if err := tx.Omit(clause.Associations).Create(&document).Error; err != nil {
+		return err
+	}
+
+	for _, tag := range document.Tags {
+		err := tx.Table("document_document_tags").
+			Create(map[string]interface{}{"document_id": document.ID, "document_tag_id": tag.ID}).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, author := range document.Authors {
+		err := tx.Table("document_document_authors").
+			Create(map[string]interface{}{"document_id": document.ID, "document_author_id": author.ID}).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(exported.Text) == 0 {
+		return nil
+	}
+	return tx.Create(&DocumentText{DocumentID: document.ID, Text: exported.Text}).Error
+}
+
+// resetSequences moves the postgres sequences past the restored ids, the
+// other databases follow the explicit ids on their own.
+func resetSequences(tx *gorm.DB) error {
+	if tx.Dialector.Name() != "postgres" {
+		return nil
+	}
+
+	for _, table := range []string{"documents", "document_kinds", "document_tags", "document_authors"} {
+		err := tx.Exec(fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%s', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM %s", table, table)).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
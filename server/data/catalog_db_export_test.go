@@ -0,0 +1,111 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"github.com/garugaru/knowledge/server/storage"
+	"github.com/stretchr/testify/require"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDBCatalog_ExportRestore(t *testing.T) {
+	catalog, _ := newTestCatalog(t, WithBlobStore(storage.NewLocalBlobStore(t.TempDir())))
+
+	for _, document := range searchTestDocuments() {
+		require.NoError(t, catalog.InsertDocument(context.TODO(), InsertDocumentRequest{Document: document}))
+	}
+	require.NoError(t, catalog.DeleteDocument(context.TODO(), DeleteDocumentRequest{DocumentID: 2}))
+
+	nested, err := catalog.InsertTag(context.TODO(), InsertTagRequest{Tag: DocumentTag{Tag: "databases/postgres"}})
+	require.NoError(t, err)
+	merged, err := catalog.InsertTag(context.TODO(), InsertTagRequest{Tag: DocumentTag{Tag: "postgresql"}})
+	require.NoError(t, err)
+	_, err = catalog.MergeTags(context.TODO(), MergeTagsRequest{TagID: nested.ID, MergedIDs: []uint{merged.ID}})
+	require.NoError(t, err)
+
+	require.NoError(t, catalog.InsertDocument(context.TODO(), InsertDocumentRequest{Document: Document{
+		Title: strptr("PostgreSQL Internals"),
+		Uri:   strptr("https://www.interdb.jp/pg/"),
+		Tags:  []DocumentTag{{Tag: "databases/postgres"}},
+	}}))
+	_, err = catalog.PutDocumentContent(context.TODO(), PutDocumentContentRequest{
+		DocumentID: 4,
+		Content:    strings.NewReader("hello world"),
+	})
+	require.NoError(t, err)
+
+	var exported bytes.Buffer
+	err = catalog.ExportCatalog(context.TODO(), ExportCatalogRequest{Writer: &exported, Format: ExportTarGz})
+	require.NoError(t, err)
+
+	restored, _ := newTestCatalog(t, WithBlobStore(storage.NewLocalBlobStore(t.TempDir())))
+	response, err := restored.RestoreCatalog(context.TODO(), RestoreCatalogRequest{Reader: &exported, Format: ExportTarGz})
+	require.NoError(t, err)
+	require.Equal(t, RestoreCatalogResponse{Kinds: 2, Tags: 5, Authors: 2, Documents: 4, Contents: 1}, response)
+
+	for _, documentID := range []int{1, 3, 4} {
+		expected, err := catalog.GetDocument(context.TODO(), GetDocumentRequest{DocumentID: documentID})
+		require.NoError(t, err)
+		document, err := restored.GetDocument(context.TODO(), GetDocumentRequest{DocumentID: documentID})
+		require.NoError(t, err)
+		require.Equal(t, expected, document)
+	}
+
+	_, err = restored.GetDocument(context.TODO(), GetDocumentRequest{DocumentID: 2})
+	require.Error(t, err, "deleted documents must stay deleted")
+
+	expectedTree, err := catalog.GetTagTree(context.TODO(), GetTagTreeRequest{})
+	require.NoError(t, err)
+	tree, err := restored.GetTagTree(context.TODO(), GetTagTreeRequest{})
+	require.NoError(t, err)
+	require.Equal(t, expectedTree, tree)
+
+	tags, err := restored.ListTags(context.TODO(), ListTagsRequest{Pagination: PaginationRequest{Page: 1, PageSize: 10}})
+	require.NoError(t, err)
+	for _, tag := range tags.Items {
+		if tag.ID == nested.ID {
+			require.Equal(t, []string{"postgresql"}, tag.Aliases)
+		}
+	}
+
+	content, err := restored.GetDocumentContent(context.TODO(), GetDocumentContentRequest{DocumentID: 4})
+	require.NoError(t, err)
+	text, err := io.ReadAll(content.Content)
+	require.NoError(t, err)
+	require.NoError(t, content.Content.Close())
+	require.Equal(t, "hello world", string(text))
+
+	err = restored.InsertDocument(context.TODO(), InsertDocumentRequest{Document: Document{
+		Title: strptr("The Art of PostgreSQL"),
+		Uri:   strptr("https://theartofpostgresql.com/"),
+	}})
+	require.NoError(t, err)
+	_, err = restored.GetDocument(context.TODO(), GetDocumentRequest{DocumentID: 5})
+	require.NoError(t, err, "new documents must not reuse the restored ids")
+
+	_, err = restored.RestoreCatalog(context.TODO(), RestoreCatalogRequest{Reader: &exported, Format: ExportTarGz})
+	require.Error(t, err, "only empty catalogs can be restored")
+
+	exported.Reset()
+	err = catalog.ExportCatalog(context.TODO(), ExportCatalogRequest{Writer: &exported, Format: ExportNDJSON})
+	require.NoError(t, err)
+
+	records, _ := newTestCatalog(t)
+	response, err = records.RestoreCatalog(context.TODO(), RestoreCatalogRequest{Reader: &exported, Format: ExportNDJSON})
+	require.NoError(t, err)
+	require.Equal(t, 4, response.Documents)
+	require.Zero(t, response.Contents)
+
+	listed, err := records.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Tags:       []string{"databases"},
+		Pagination: PaginationRequest{Page: 1, PageSize: 10},
+	})
+	require.NoError(t, err)
+	require.Len(t, listed.Items, 1)
+	require.Equal(t, 4, listed.Items[0].ID)
+
+	err = records.ExportCatalog(context.TODO(), ExportCatalogRequest{Writer: io.Discard, Format: ExportTarGz})
+	require.ErrorIs(t, err, errContentStorageDisabled)
+}
@@ -0,0 +1,254 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	extractionQueueSize     = 256
+	extractionSweepInterval = time.Minute
+)
+
+// enqueueExtraction schedules the extraction of a document without blocking,
+// documents not fitting in the queue stay pending until the next sweep.
+func (d *DBCatalog) enqueueExtraction(documentID int) {
+	d.queuedMu.Lock()
+	defer d.queuedMu.Unlock()
+
+	if d.queued[documentID] {
+		return
+	}
+
+	select {
+	case d.extraction <- documentID:
+		d.queued[documentID] = true
+	default:
+	}
+}
+
+func (d *DBCatalog) dequeueExtraction(documentID int) {
+	d.queuedMu.Lock()
+	defer d.queuedMu.Unlock()
+	delete(d.queued, documentID)
+}
+
+// RunExtraction processes the pending extractions with the given number of
+// workers until ctx is done. It does nothing when extraction is not enabled.
+func (d *DBCatalog) RunExtraction(ctx context.Context, workers int) {
+	if d.extractors == nil {
+		return
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case documentID := <-d.extraction:
+					d.dequeueExtraction(documentID)
+					// failures are recorded on the document, only the context can stop the worker
+					logBackgroundError(ctx, "extraction", d.safeExtractDocument(ctx, documentID))
+				}
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(extractionSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		// pending documents are swept to recover the ones left by a restart or a full queue
+		logBackgroundError(ctx, "extraction", d.enqueuePending(ctx))
+
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *DBCatalog) enqueuePending(ctx context.Context) error {
+	var ids []int
+	err := d.db.WithContext(ctx).Model(&Document{}).
+		Where("extraction_status = ?", ExtractionPending).
+		Order("id").Limit(extractionQueueSize).Pluck("id", &ids).Error
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		d.enqueueExtraction(id)
+	}
+
+	return nil
+}
+
+// safeExtractDocument extracts the document, marking it failed when the
+// extraction panics so that a malformed content cannot stop the process.
+func (d *DBCatalog) safeExtractDocument(ctx context.Context, documentID int) (err error) {
+	defer func() {
+		recovered := recover()
+		if recovered == nil {
+			return
+		}
+
+		err = fmt.Errorf("extraction panicked: %v", recovered)
+		updateErr := d.db.WithContext(ctx).Model(&Document{}).
+			Where("id = ? AND extraction_status = ?", documentID, ExtractionPending).
+			Updates(map[string]interface{}{
+				"extraction_status": ExtractionFailed,
+				"extraction_error":  err.Error(),
+			}).Error
+		if updateErr != nil {
+			err = fmt.Errorf("%s: %w", err, updateErr)
+		}
+	}()
+
+	return d.extractDocument(ctx, documentID)
+}
+
+// extractDocument extracts the text of a pending document, storing it in the
+// catalog and the search index. Extraction failures are recorded on the
+// document, the returned error is only meant for logging.
+func (d *DBCatalog) extractDocument(ctx context.Context, documentID int) error {
+	var document Document
+	if err := d.db.WithContext(ctx).First(&document, documentID).Error; err != nil {
+		return err
+	}
+
+	if document.Extraction.Status != ExtractionPending {
+		return nil
+	}
+
+	text, extractErr := d.extractText(ctx, document)
+	if ctx.Err() != nil {
+		// the document stays pending and is extracted again after a restart
+		return ctx.Err()
+	}
+
+	state := ExtractionState{Status: ExtractionDone}
+	if extractErr != nil {
+		state = ExtractionState{Status: ExtractionFailed, Error: extractErr.Error()}
+	}
+
+	var (
+		indexed Document
+		stored  bool
+	)
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := unchangedSinceExtraction(tx, document).Updates(map[string]interface{}{
+			"extraction_status": state.Status,
+			"extraction_error":  state.Error,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		// the content changed during the extraction, the new one is already queued
+		if result.RowsAffected == 0 || extractErr != nil {
+			return nil
+		}
+
+		err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&DocumentText{DocumentID: document.ID, Text: text}).Error
+		if err != nil {
+			return err
+		}
+
+		indexed, err = d.loadIndexedDocument(tx, document.ID)
+		stored = err == nil
+		return err
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if stored {
+		d.syncSearchIndex(indexed.ID, &indexed)
+	}
+
+	return extractErr
+}
+
+// unchangedSinceExtraction restricts the updates to the document if it still
+// has the content that was extracted.
+func unchangedSinceExtraction(tx *gorm.DB, document Document) *gorm.DB {
+	query := tx.Model(&Document{}).Where("id = ? AND extraction_status = ? AND uri = ?",
+		document.ID, ExtractionPending, *document.Uri)
+
+	if len(document.Content.SHA256) == 0 {
+		return query.Where("(content_sha256 IS NULL OR content_sha256 = '')")
+	}
+	return query.Where("content_sha256 = ?", document.Content.SHA256)
+}
+
+func (d *DBCatalog) extractText(ctx context.Context, document Document) (string, error) {
+	content, contentType, err := d.openContent(ctx, document)
+	if err != nil {
+		return "", err
+	}
+	defer content.Close()
+
+	extractor, err := d.extractors.Lookup(contentType)
+	if err != nil {
+		return "", err
+	}
+
+	return extractor.Extract(ctx, content)
+}
+
+// openContent opens the uploaded content of the document, fetching its uri
+// when nothing was uploaded.
+func (d *DBCatalog) openContent(ctx context.Context, document Document) (io.ReadCloser, string, error) {
+	if len(document.Content.SHA256) != 0 {
+		if d.blobs == nil {
+			return nil, "", errContentStorageDisabled
+		}
+
+		content, err := d.blobs.Get(ctx, documentContentKey(document.ID))
+		if err != nil {
+			return nil, "", err
+		}
+		return content, document.Content.Type, nil
+	}
+
+	if !isFetchable(document.Uri) {
+		return nil, "", errors.New("document has no content to extract")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, *document.Uri, nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if resp.StatusCode >= 300 {
+		resp.Body.Close()
+		return nil, "", fmt.Errorf("fetching %s failed with status %s", *document.Uri, resp.Status)
+	}
+
+	content := struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(resp.Body, fetchMaxSize), resp.Body}
+
+	return content, resp.Header.Get("Content-Type"), nil
+}
@@ -0,0 +1,188 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"github.com/garugaru/knowledge/server/extract"
+	"github.com/garugaru/knowledge/server/storage"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDBCatalog_ExtractDocument(t *testing.T) {
+	catalog, db := newTestCatalog(t,
+		WithPrivateFetch(),
+		WithBlobStore(storage.NewLocalBlobStore(t.TempDir())),
+		WithExtraction(extract.DefaultRegistry()),
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/paxos.html" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, "<html><body><p>The Paxos algorithm is very simple.</p><script>hidden()</script></body></html>")
+	}))
+	defer server.Close()
+
+	for _, document := range []Document{
+		{Title: strptr("Uploaded"), Uri: strptr("file://uploaded.md")},
+		{Title: strptr("Fetched"), Uri: strptr(server.URL + "/paxos.html")},
+		{Title: strptr("Missing"), Uri: strptr(server.URL + "/missing.html")},
+	} {
+		err := catalog.InsertDocument(context.TODO(), InsertDocumentRequest{Document: document})
+		require.NoError(t, err)
+	}
+
+	document, err := catalog.GetDocument(context.TODO(), GetDocumentRequest{DocumentID: 1})
+	require.NoError(t, err)
+	require.Equal(t, ExtractionState{}, document.Extraction, "documents without content are not extracted")
+
+	document, err = catalog.PutDocumentContent(context.TODO(), PutDocumentContentRequest{
+		DocumentID:  1,
+		ContentType: "text/markdown",
+		Content:     strings.NewReader("# Notes\n\nLamport **clocks** order events."),
+	})
+	require.NoError(t, err)
+	require.Equal(t, ExtractionState{Status: ExtractionPending}, document.Extraction)
+
+	for id := 1; id <= 3; id++ {
+		document, err = catalog.GetDocument(context.TODO(), GetDocumentRequest{DocumentID: id})
+		require.NoError(t, err)
+		require.Equal(t, ExtractionPending, document.Extraction.Status)
+
+		_ = catalog.extractDocument(context.TODO(), id)
+	}
+
+	document, err = catalog.GetDocument(context.TODO(), GetDocumentRequest{DocumentID: 1})
+	require.NoError(t, err)
+	require.Equal(t, ExtractionState{Status: ExtractionDone}, document.Extraction)
+
+	document, err = catalog.GetDocument(context.TODO(), GetDocumentRequest{DocumentID: 2})
+	require.NoError(t, err)
+	require.Equal(t, ExtractionState{Status: ExtractionDone}, document.Extraction)
+
+	document, err = catalog.GetDocument(context.TODO(), GetDocumentRequest{DocumentID: 3})
+	require.NoError(t, err)
+	require.Equal(t, ExtractionFailed, document.Extraction.Status)
+	require.Contains(t, document.Extraction.Error, "404")
+
+	var text DocumentText
+	require.NoError(t, db.First(&text, "document_id = ?", 2).Error)
+	require.Equal(t, "The Paxos algorithm is very simple.", text.Text)
+
+	response, err := catalog.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Query:      "clocks",
+		Pagination: PaginationRequest{Page: 1, PageSize: 10},
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Items, 1)
+	require.Equal(t, "Uploaded", *response.Items[0].Title)
+
+	response, err = catalog.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Query:      "paxos algorithm",
+		Pagination: PaginationRequest{Page: 1, PageSize: 10},
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Items, 1)
+	require.Equal(t, "Fetched", *response.Items[0].Title)
+
+	response, err = catalog.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Query:      "hidden",
+		Pagination: PaginationRequest{Page: 1, PageSize: 10},
+	})
+	require.NoError(t, err)
+	require.Empty(t, response.Items)
+
+	document, err = catalog.PutDocumentContent(context.TODO(), PutDocumentContentRequest{
+		DocumentID:  1,
+		ContentType: "image/png",
+		Content:     strings.NewReader("not really a png"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, ExtractionPending, document.Extraction.Status)
+
+	err = catalog.extractDocument(context.TODO(), 1)
+	require.ErrorIs(t, err, extract.ErrUnsupportedType)
+
+	document, err = catalog.GetDocument(context.TODO(), GetDocumentRequest{DocumentID: 1})
+	require.NoError(t, err)
+	require.Equal(t, ExtractionFailed, document.Extraction.Status)
+	require.Contains(t, document.Extraction.Error, "image/png")
+
+	catalog.extractors.Register("image/png", extract.ExtractorFunc(func(ctx context.Context, content io.Reader) (string, error) {
+		panic("malformed content")
+	}))
+	_, err = catalog.PutDocumentContent(context.TODO(), PutDocumentContentRequest{
+		DocumentID:  1,
+		ContentType: "image/png",
+		Content:     strings.NewReader("still not a png"),
+	})
+	require.NoError(t, err)
+
+	err = catalog.safeExtractDocument(context.TODO(), 1)
+	require.Error(t, err)
+
+	document, err = catalog.GetDocument(context.TODO(), GetDocumentRequest{DocumentID: 1})
+	require.NoError(t, err)
+	require.Equal(t, ExtractionFailed, document.Extraction.Status, "panics must fail the extraction")
+	require.Contains(t, document.Extraction.Error, "malformed content")
+}
+
+func TestDBCatalog_RunExtraction(t *testing.T) {
+	catalog, db := newTestCatalog(t,
+		WithPrivateFetch(),
+		WithBlobStore(storage.NewLocalBlobStore(t.TempDir())),
+		WithSearchIndex(path.Join(t.TempDir(), "search.idx")),
+		WithExtraction(extract.DefaultRegistry()),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		catalog.RunExtraction(ctx, 2)
+		close(done)
+	}()
+
+	for i := 0; i < 5; i++ {
+		err := catalog.InsertDocument(context.TODO(), InsertDocumentRequest{
+			Document: Document{Title: strptr(fmt.Sprintf("Document %d", i)), Uri: strptr(fmt.Sprintf("file://document-%d.txt", i))},
+		})
+		require.NoError(t, err)
+
+		_, err = catalog.PutDocumentContent(context.TODO(), PutDocumentContentRequest{
+			DocumentID:  i + 1,
+			ContentType: "text/plain",
+			Content:     strings.NewReader(fmt.Sprintf("extracted body number%d", i)),
+		})
+		require.NoError(t, err)
+	}
+
+	require.Eventually(t, func() bool {
+		var pending int64
+		err := db.Model(&Document{}).Where("extraction_status = ?", ExtractionPending).Count(&pending).Error
+		return err == nil && pending == 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	response, err := catalog.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Query:      "number3",
+		Pagination: PaginationRequest{Page: 1, PageSize: 10},
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Items, 1)
+	require.Equal(t, "Document 3", *response.Items[0].Title)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("extraction did not stop")
+	}
+}
@@ -0,0 +1,128 @@
+package data
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"sort"
+	"strings"
+)
+
+const (
+	// facetBucketsLimit bounds the buckets of each facet
+	facetBucketsLimit = 100
+	// facetIDsChunk bounds the ids bound to a single facet query
+	facetIDsChunk = 500
+)
+
+type facetRow struct {
+	ID      uint
+	Value   string
+	Surname string
+	Count   int64
+}
+
+// documentFacets counts the documents by facet value, the documents are
+// selected by the filtered query or by their ids when filtered is nil.
+func (d *DBCatalog) documentFacets(ctx context.Context, facets []Facet, filtered *gorm.DB, ids []int) (map[Facet][]FacetBucket, error) {
+	if len(facets) == 0 {
+		return nil, nil
+	}
+
+	buckets := make(map[Facet][]FacetBucket, len(facets))
+	for _, facet := range facets {
+		if _, present := buckets[facet]; present {
+			continue
+		}
+
+		var rows []facetRow
+		if filtered != nil {
+			query := facetQuery(d.db.WithContext(ctx), facet).
+				Where("documents.id IN (?)", filtered.Session(&gorm.Session{}).Model(&Document{}).Select("documents.id")).
+				Order("count DESC").Order("value").Limit(facetBucketsLimit)
+			if err := query.Scan(&rows).Error; err != nil {
+				return nil, err
+			}
+		} else {
+			var err error
+			if rows, err = d.facetRowsByIDs(ctx, facet, ids); err != nil {
+				return nil, err
+			}
+		}
+
+		facetBuckets := make([]FacetBucket, len(rows))
+		for i, row := range rows {
+			facetBuckets[i] = FacetBucket{ID: row.ID, Value: strings.TrimSpace(row.Value + " " + row.Surname), Count: row.Count}
+		}
+		buckets[facet] = facetBuckets
+	}
+
+	return buckets, nil
+}
+
+// facetQuery groups the documents by the values of the facet, the documents
+// are joined so that they can be filtered by id.
+func facetQuery(db *gorm.DB, facet Facet) *gorm.DB {
+	switch facet {
+	case FacetTags:
+		return db.Table("document_tags").
+			Select("document_tags.id AS id, document_tags.tag AS value, COUNT(*) AS count").
+			Joins("JOIN document_document_tags ON document_document_tags.document_tag_id = document_tags.id").
+			Joins("JOIN documents ON documents.id = document_document_tags.document_id").
+			Group("document_tags.id, document_tags.tag")
+	case FacetAuthors:
+		return db.Table("document_authors").
+			Select("document_authors.id AS id, document_authors.name AS value, document_authors.surname AS surname, COUNT(*) AS count").
+			Joins("JOIN document_document_authors ON document_document_authors.document_author_id = document_authors.id").
+			Joins("JOIN documents ON documents.id = document_document_authors.document_id").
+			Group("document_authors.id, document_authors.name, document_authors.surname")
+	default:
+		return db.Table("document_kinds").
+			Select("document_kinds.id AS id, document_kinds.name AS value, COUNT(*) AS count").
+			Joins("JOIN documents ON documents.document_kind_id = document_kinds.id").
+			Group("document_kinds.id, document_kinds.name")
+	}
+}
+
+// facetRowsByIDs counts the documents with the ids in chunks, summing the
+// counts of the chunks before keeping the most frequent values.
+func (d *DBCatalog) facetRowsByIDs(ctx context.Context, facet Facet, ids []int) ([]facetRow, error) {
+	counted := make(map[uint]*facetRow)
+	for start := 0; start < len(ids); start += facetIDsChunk {
+		end := start + facetIDsChunk
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		var rows []facetRow
+		err := facetQuery(d.db.WithContext(ctx), facet).Where("documents.id IN ?", ids[start:end]).Scan(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+
+		for _, row := range rows {
+			if previous, present := counted[row.ID]; present {
+				previous.Count += row.Count
+				continue
+			}
+			row := row
+			counted[row.ID] = &row
+		}
+	}
+
+	rows := make([]facetRow, 0, len(counted))
+	for _, row := range counted {
+		rows = append(rows, *row)
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Count != rows[j].Count {
+			return rows[i].Count > rows[j].Count
+		}
+		return rows[i].Value+" "+rows[i].Surname < rows[j].Value+" "+rows[j].Surname
+	})
+
+	if len(rows) > facetBucketsLimit {
+		rows = rows[:facetBucketsLimit]
+	}
+	return rows, nil
+}
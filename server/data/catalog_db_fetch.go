@@ -0,0 +1,156 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/garugaru/knowledge/server/extract"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	fetchTimeout = time.Minute
+	// fetchMaxSize limits the content read from a document uri
+	fetchMaxSize = 64 << 20
+)
+
+func isFetchable(uri *string) bool {
+	if uri == nil {
+		return false
+	}
+
+	parsed, err := url.Parse(*uri)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https")
+}
+
+// fetchMetadata downloads the document uri, reading the metadata of html pages.
+func (d *DBCatalog) fetchMetadata(ctx context.Context, uri *string) (extract.Metadata, FetchState) {
+	state := FetchState{Time: int(time.Now().Unix())}
+
+	fail := func(err error) (extract.Metadata, FetchState) {
+		state.Status = FetchFailed
+		state.Error = err.Error()
+		return extract.Metadata{}, state
+	}
+
+	if !isFetchable(uri) {
+		return fail(errors.New("document uri is not an http url"))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, *uri, nil)
+	if err != nil {
+		return fail(err)
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.8")
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fail(err)
+	}
+	defer resp.Body.Close()
+
+	state.StatusCode = resp.StatusCode
+	if resp.StatusCode >= 300 {
+		return fail(fmt.Errorf("fetching %s failed with status %s", *uri, resp.Status))
+	}
+
+	state.Status = FetchDone
+
+	// other documents are fetched successfully but carry no metadata
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return extract.Metadata{}, state
+	}
+
+	metadata, err := extract.HTMLMetadata(ctx, io.LimitReader(resp.Body, fetchMaxSize))
+	if err != nil {
+		return fail(err)
+	}
+
+	return metadata, state
+}
+
+// applyMetadata sets the fetched metadata on document, overwrite replaces the
+// fields already set instead of only filling the empty ones.
+func applyMetadata(document *Document, metadata extract.Metadata, overwrite bool) {
+	if len(metadata.Title) != 0 && (overwrite || document.Title == nil || len(*document.Title) == 0) {
+		title := metadata.Title
+		document.Title = &title
+	}
+
+	if len(metadata.Authors) != 0 && (overwrite || len(document.Authors) == 0) {
+		document.Authors = make([]DocumentAuthor, len(metadata.Authors))
+		for i, author := range metadata.Authors {
+			document.Authors[i] = newDocumentAuthor(author)
+		}
+	}
+
+	if len(metadata.Tags) != 0 && (overwrite || len(document.Tags) == 0) {
+		document.Tags = make([]DocumentTag, len(metadata.Tags))
+		for i, tag := range metadata.Tags {
+			document.Tags[i] = DocumentTag{Tag: tag}
+		}
+	}
+}
+
+// newDocumentAuthor splits an author name written either as "Surname, Name"
+// or "Name Surname".
+func newDocumentAuthor(author string) DocumentAuthor {
+	if comma := strings.Index(author, ","); comma != -1 {
+		return DocumentAuthor{
+			Name:    strings.TrimSpace(author[comma+1:]),
+			Surname: strings.TrimSpace(author[:comma]),
+		}
+	}
+
+	words := strings.Fields(author)
+	if len(words) < 2 {
+		return DocumentAuthor{Name: author}
+	}
+
+	return DocumentAuthor{
+		Name:    strings.Join(words[:len(words)-1], " "),
+		Surname: words[len(words)-1],
+	}
+}
+
+// RefreshDocument fetches the document uri again, replacing the title, authors
+// and tags with the ones found in the page. Fetch failures are recorded on the
+// returned document.
+func (d *DBCatalog) RefreshDocument(ctx context.Context, request RefreshDocumentRequest) (Document, error) {
+	var document Document
+	if err := d.db.WithContext(ctx).First(&document, request.DocumentID).Error; err != nil {
+		return Document{}, err
+	}
+
+	metadata, state := d.fetchMetadata(ctx, document.Uri)
+
+	err := d.db.WithContext(ctx).Model(&document).Updates(map[string]interface{}{
+		"fetch_status":      state.Status,
+		"fetch_status_code": state.StatusCode,
+		"fetch_error":       state.Error,
+		"fetch_time":        state.Time,
+	}).Error
+	if err != nil {
+		return Document{}, err
+	}
+
+	var update Document
+	applyMetadata(&update, metadata, true)
+
+	if update.Title == nil && update.Authors == nil && update.Tags == nil {
+		return d.GetDocument(ctx, GetDocumentRequest{DocumentID: document.ID})
+	}
+
+	return d.updateDocument(ctx, UpdateDocumentRequest{
+		DocumentID: document.ID,
+		Document:   update,
+		Partial:    true,
+		UserID:     request.UserID,
+	}, RevisionRefresh)
+}
@@ -0,0 +1,144 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+const fetchTestPage = `<html>
+<head>
+	<title>Paxos Made Simple - Example</title>
+	<meta name="citation_title" content="Paxos Made Simple">
+	<meta name="citation_author" content="Lamport, Leslie">
+	<meta name="DC.subject" content="consensus">
+	<meta property="article:tag" content="distributed">
+</head>
+<body></body>
+</html>`
+
+func TestDBCatalog_InsertDocument_Fetch(t *testing.T) {
+	catalog, _ := newTestCatalog(t, WithPrivateFetch())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/paxos" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, fetchTestPage)
+	}))
+	defer server.Close()
+
+	err := catalog.InsertDocument(context.TODO(), InsertDocumentRequest{
+		Document: Document{Uri: strptr(server.URL + "/paxos")},
+		Fetch:    true,
+	})
+	require.NoError(t, err)
+
+	err = catalog.InsertDocument(context.TODO(), InsertDocumentRequest{
+		Document: Document{
+			Title: strptr("Client Title"),
+			Uri:   strptr(server.URL + "/paxos?copy=1"),
+			Tags:  []DocumentTag{{Tag: "mine"}},
+		},
+		Fetch: true,
+	})
+	require.NoError(t, err)
+
+	err = catalog.InsertDocument(context.TODO(), InsertDocumentRequest{
+		Document: Document{Uri: strptr(server.URL + "/missing")},
+		Fetch:    true,
+	})
+	require.NoError(t, err)
+
+	document, err := catalog.GetDocument(context.TODO(), GetDocumentRequest{DocumentID: 1})
+	require.NoError(t, err)
+	require.Equal(t, "Paxos Made Simple", *document.Title)
+	require.Len(t, document.Authors, 1)
+	require.Equal(t, "Leslie", document.Authors[0].Name)
+	require.Equal(t, "Lamport", document.Authors[0].Surname)
+	require.Len(t, document.Tags, 2)
+	require.Equal(t, "consensus", document.Tags[0].Tag)
+	require.Equal(t, "distributed", document.Tags[1].Tag)
+	require.Equal(t, FetchDone, document.Fetch.Status)
+	require.Equal(t, http.StatusOK, document.Fetch.StatusCode)
+	require.NotZero(t, document.Fetch.Time)
+
+	document, err = catalog.GetDocument(context.TODO(), GetDocumentRequest{DocumentID: 2})
+	require.NoError(t, err)
+	require.Equal(t, "Client Title", *document.Title, "fetched metadata only fills empty fields")
+	require.Len(t, document.Tags, 1)
+	require.Equal(t, "mine", document.Tags[0].Tag)
+	require.Len(t, document.Authors, 1)
+
+	document, err = catalog.GetDocument(context.TODO(), GetDocumentRequest{DocumentID: 3})
+	require.NoError(t, err)
+	require.Equal(t, server.URL+"/missing", *document.Title)
+	require.Equal(t, FetchFailed, document.Fetch.Status)
+	require.Equal(t, http.StatusNotFound, document.Fetch.StatusCode)
+	require.Contains(t, document.Fetch.Error, "404")
+}
+
+func TestDBCatalog_RefreshDocument(t *testing.T) {
+	catalog, _ := newTestCatalog(t, WithPrivateFetch())
+
+	var offline int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&offline) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, fetchTestPage)
+	}))
+	defer server.Close()
+
+	err := catalog.InsertDocument(context.TODO(), InsertDocumentRequest{
+		Document: Document{
+			Title: strptr("Handwritten"),
+			Uri:   strptr(server.URL + "/paxos"),
+			Tags:  []DocumentTag{{Tag: "mine"}},
+		},
+	})
+	require.NoError(t, err)
+
+	document, err := catalog.GetDocument(context.TODO(), GetDocumentRequest{DocumentID: 1})
+	require.NoError(t, err)
+	require.Equal(t, FetchState{}, document.Fetch, "documents are not fetched by default")
+
+	document, err = catalog.RefreshDocument(context.TODO(), RefreshDocumentRequest{DocumentID: 1})
+	require.NoError(t, err)
+	require.Equal(t, "Paxos Made Simple", *document.Title)
+	require.Len(t, document.Tags, 2)
+	require.Len(t, document.Authors, 1)
+	require.Equal(t, FetchDone, document.Fetch.Status)
+
+	response, err := catalog.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Query:      "lamport",
+		Pagination: PaginationRequest{Page: 1, PageSize: 10},
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Items, 1, "refreshed documents are indexed")
+
+	atomic.StoreInt32(&offline, 1)
+	document, err = catalog.RefreshDocument(context.TODO(), RefreshDocumentRequest{DocumentID: 1})
+	require.NoError(t, err)
+	require.Equal(t, "Paxos Made Simple", *document.Title, "failed fetches keep the document")
+	require.Equal(t, FetchFailed, document.Fetch.Status)
+	require.Equal(t, http.StatusServiceUnavailable, document.Fetch.StatusCode)
+
+	_, err = catalog.RefreshDocument(context.TODO(), RefreshDocumentRequest{DocumentID: 2})
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestNewDocumentAuthor(t *testing.T) {
+	require.Equal(t, DocumentAuthor{Name: "Leslie", Surname: "Lamport"}, newDocumentAuthor("Lamport, Leslie"))
+	require.Equal(t, DocumentAuthor{Name: "Brian W.", Surname: "Kernighan"}, newDocumentAuthor("Brian W. Kernighan"))
+	require.Equal(t, DocumentAuthor{Name: "Plato"}, newDocumentAuthor("Plato"))
+}
@@ -0,0 +1,266 @@
+package data
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	linkCheckBatchSize = 100
+	linkCheckTimeout   = 30 * time.Second
+)
+
+var (
+	documentLinks = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "knowledge_catalog_document_links",
+		Help: "Number of documents by state of their uri: ok, broken or unchecked.",
+	}, []string{"state"})
+	linkCheckTimestamp = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "knowledge_catalog_link_check_timestamp_seconds",
+		Help: "Unix time of the last completed link check.",
+	})
+	linkCheckDuration = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "knowledge_catalog_link_check_duration_seconds",
+		Help: "Duration of the last completed link check.",
+	})
+)
+
+type LinkCheckOptions struct {
+	// Interval is the time between two checks of the same uri
+	Interval time.Duration
+	// Workers is the number of uris checked concurrently
+	Workers int
+	// HostDelay is the minimum time between two requests to the same host
+	HostDelay time.Duration
+}
+
+// RunLinkChecker periodically checks the uri of every document until ctx is done.
+func (d *DBCatalog) RunLinkChecker(ctx context.Context, opts LinkCheckOptions) {
+	for {
+		// errors are retried on the next run, the documents not checked yet are still due
+		logBackgroundError(ctx, "link_check", d.CheckLinks(ctx, opts))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(opts.Interval):
+		}
+	}
+}
+
+// CheckLinks checks the http uris of the documents not checked during the
+// last interval, recording the result on each document.
+func (d *DBCatalog) CheckLinks(ctx context.Context, opts LinkCheckOptions) error {
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+
+	started := time.Now()
+	due := started.Add(-opts.Interval).Unix()
+	limiter := newHostLimiter(opts.HostDelay)
+
+	type linkCheck struct {
+		ID  int
+		Uri string
+	}
+
+	checks := make(chan linkCheck)
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for check := range checks {
+				state := d.checkLink(ctx, limiter, check.Uri)
+				if ctx.Err() != nil {
+					continue
+				}
+
+				// columns are updated directly to leave updated_at untouched
+				err := d.db.WithContext(ctx).Model(&Document{}).Where("id = ?", check.ID).UpdateColumns(map[string]interface{}{
+					"link_check_time":   state.CheckTime,
+					"link_status_code":  state.StatusCode,
+					"link_redirect_uri": state.RedirectUri,
+					"link_error":        state.Error,
+					"link_broken":       state.Broken,
+				}).Error
+				// the document is checked again on the next run
+				logBackgroundError(ctx, "link_check", err)
+			}
+		}()
+	}
+
+	err := func() error {
+		defer close(checks)
+
+		for lastID := 0; ; {
+			var batch []linkCheck
+			err := d.db.WithContext(ctx).Model(&Document{}).Select("id, uri").
+				Where("id > ?", lastID).
+				Where("uri LIKE ? OR uri LIKE ?", "http://%", "https://%").
+				Where("link_check_time IS NULL OR link_check_time <= ?", due).
+				Order("id").Limit(linkCheckBatchSize).Scan(&batch).Error
+			if err != nil {
+				return err
+			}
+
+			for _, check := range batch {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case checks <- check:
+				}
+			}
+
+			if len(batch) < linkCheckBatchSize {
+				return nil
+			}
+			lastID = batch[len(batch)-1].ID
+		}
+	}()
+	wg.Wait()
+
+	if err != nil {
+		return err
+	}
+
+	linkCheckTimestamp.Set(float64(time.Now().Unix()))
+	linkCheckDuration.Set(time.Since(started).Seconds())
+
+	return d.updateLinkMetrics(ctx)
+}
+
+func (d *DBCatalog) updateLinkMetrics(ctx context.Context) error {
+	var counts []struct {
+		Checked bool
+		Broken  bool
+		Count   int64
+	}
+
+	const (
+		checked = "CASE WHEN link_check_time > 0 THEN 1 ELSE 0 END"
+		broken  = "CASE WHEN link_broken THEN 1 ELSE 0 END"
+	)
+
+	err := d.db.WithContext(ctx).Model(&Document{}).
+		Select(checked + " AS checked, " + broken + " AS broken, count(*) AS count").
+		Group(checked + ", " + broken).Scan(&counts).Error
+	if err != nil {
+		return err
+	}
+
+	states := map[string]float64{"ok": 0, "broken": 0, "unchecked": 0}
+	for _, count := range counts {
+		switch {
+		case !count.Checked:
+			states["unchecked"] += float64(count.Count)
+		case count.Broken:
+			states["broken"] += float64(count.Count)
+		default:
+			states["ok"] += float64(count.Count)
+		}
+	}
+
+	for state, count := range states {
+		documentLinks.WithLabelValues(state).Set(count)
+	}
+
+	return nil
+}
+
+// checkLink requests the head of uri, falling back to a get for the servers
+// not supporting head requests.
+func (d *DBCatalog) checkLink(ctx context.Context, limiter *hostLimiter, uri string) LinkState {
+	state := LinkState{CheckTime: int(time.Now().Unix())}
+
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		state.Error = err.Error()
+		state.Broken = true
+		return state
+	}
+
+	var resp *http.Response
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		if err := limiter.wait(ctx, parsed.Host); err != nil {
+			state.Error = err.Error()
+			return state
+		}
+
+		resp, err = d.requestLink(ctx, method, uri)
+		if err == nil && resp.StatusCode < 400 {
+			break
+		}
+	}
+
+	if err != nil {
+		state.Error = err.Error()
+		state.Broken = true
+		return state
+	}
+
+	state.StatusCode = resp.StatusCode
+	state.Broken = resp.StatusCode >= 400
+	if final := resp.Request.URL.String(); final != uri {
+		state.RedirectUri = final
+	}
+
+	return state
+}
+
+func (d *DBCatalog) requestLink(ctx context.Context, method string, uri string) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, linkCheckTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	// only the status is needed, the body is never read
+	resp.Body.Close()
+
+	return resp, nil
+}
+
+// hostLimiter spaces the requests to the same host by a fixed delay.
+type hostLimiter struct {
+	mu    sync.Mutex
+	delay time.Duration
+	next  map[string]time.Time
+}
+
+func newHostLimiter(delay time.Duration) *hostLimiter {
+	return &hostLimiter{delay: delay, next: make(map[string]time.Time)}
+}
+
+// wait blocks until a request to host is allowed.
+func (l *hostLimiter) wait(ctx context.Context, host string) error {
+	l.mu.Lock()
+	now := time.Now()
+	slot := l.next[host]
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next[host] = slot.Add(l.delay)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(slot.Sub(now))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,113 @@
+package data
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDBCatalog_CheckLinks(t *testing.T) {
+	catalog, _ := newTestCatalog(t, WithPrivateFetch())
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		switch r.URL.Path {
+		case "/ok":
+		case "/get-only":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		case "/moved":
+			http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	for _, uri := range []string{"/ok", "/get-only", "/moved", "/gone"} {
+		err := catalog.InsertDocument(context.TODO(), InsertDocumentRequest{
+			Document: Document{Title: strptr(uri), Uri: strptr(server.URL + uri)},
+		})
+		require.NoError(t, err)
+	}
+	err := catalog.InsertDocument(context.TODO(), InsertDocumentRequest{
+		Document: Document{Title: strptr("local"), Uri: strptr("file://local.pdf")},
+	})
+	require.NoError(t, err)
+
+	opts := LinkCheckOptions{Interval: time.Hour, Workers: 2, HostDelay: time.Millisecond}
+	require.NoError(t, catalog.CheckLinks(context.TODO(), opts))
+
+	expected := map[int]LinkState{
+		1: {StatusCode: http.StatusOK},
+		2: {StatusCode: http.StatusOK},
+		3: {StatusCode: http.StatusOK, RedirectUri: server.URL + "/ok"},
+		4: {StatusCode: http.StatusNotFound, Broken: true},
+		5: {},
+	}
+	for id, state := range expected {
+		document, err := catalog.GetDocument(context.TODO(), GetDocumentRequest{DocumentID: id})
+		require.NoError(t, err)
+		if id != 5 {
+			require.NotZero(t, document.Link.CheckTime, *document.Title)
+		}
+		document.Link.CheckTime = 0
+		require.Equal(t, state, document.Link, *document.Title)
+	}
+
+	broken := true
+	response, err := catalog.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Broken:     &broken,
+		Pagination: PaginationRequest{Page: 1, PageSize: 10},
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Items, 1)
+	require.Equal(t, "/gone", *response.Items[0].Title)
+
+	broken = false
+	response, err = catalog.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Broken:     &broken,
+		Pagination: PaginationRequest{Page: 1, PageSize: 10},
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Items, 4)
+
+	checked := atomic.LoadInt32(&requests)
+	require.NoError(t, catalog.CheckLinks(context.TODO(), opts))
+	require.Equal(t, checked, atomic.LoadInt32(&requests), "links checked during the interval are skipped")
+
+	_, err = catalog.UpdateDocument(context.TODO(), UpdateDocumentRequest{
+		DocumentID: 4,
+		Document:   Document{Uri: strptr(server.URL + "/ok?moved=gone")},
+		Partial:    true,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, catalog.CheckLinks(context.TODO(), opts))
+	document, err := catalog.GetDocument(context.TODO(), GetDocumentRequest{DocumentID: 4})
+	require.NoError(t, err)
+	require.False(t, document.Link.Broken, "changing the uri resets the link state")
+	require.Equal(t, http.StatusOK, document.Link.StatusCode)
+}
+
+func TestHostLimiter(t *testing.T) {
+	limiter := newHostLimiter(50 * time.Millisecond)
+
+	started := time.Now()
+	require.NoError(t, limiter.wait(context.TODO(), "a.example.com"))
+	require.NoError(t, limiter.wait(context.TODO(), "b.example.com"))
+	require.Less(t, time.Since(started), 50*time.Millisecond, "hosts are limited independently")
+
+	require.NoError(t, limiter.wait(context.TODO(), "a.example.com"))
+	require.GreaterOrEqual(t, time.Since(started), 50*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(t, limiter.wait(ctx, "a.example.com"), context.Canceled)
+}
@@ -0,0 +1,190 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+)
+
+func newDocumentRevision(document Document, action RevisionAction, userID uint) DocumentRevision {
+	revision := DocumentRevision{
+		DocumentID:    document.ID,
+		Action:        action,
+		UserID:        userID,
+		Kind:          document.DocumentKind.Name,
+		Tags:          RevisionTags{},
+		Authors:       RevisionAuthors{},
+		ContentSHA256: document.Content.SHA256,
+	}
+	if document.Title != nil {
+		revision.Title = *document.Title
+	}
+	if document.Uri != nil {
+		revision.Uri = *document.Uri
+	}
+	for _, tag := range document.Tags {
+		revision.Tags = append(revision.Tags, tag.Tag)
+	}
+	for _, author := range document.Authors {
+		revision.Authors = append(revision.Authors, RevisionAuthor{Name: author.Name, Surname: author.Surname})
+	}
+	return revision
+}
+
+// recordRevision snapshots the document along with the event of the change,
+// its kind, tags and authors must be loaded.
+func recordRevision(tx *gorm.DB, document Document, action RevisionAction, userID uint) error {
+	revision := newDocumentRevision(document, action, userID)
+	if err := tx.Create(&revision).Error; err != nil {
+		return err
+	}
+
+	return recordEvent(tx, CatalogEvent{
+		Type:       revisionEvent(action),
+		DocumentID: document.ID,
+		RevisionID: revision.ID,
+		UserID:     userID,
+	})
+}
+
+// recordStoredRevision snapshots the stored document, deleted or not.
+func recordStoredRevision(tx *gorm.DB, documentID int, action RevisionAction, userID uint) error {
+	var document Document
+	err := tx.Unscoped().Preload("DocumentKind").Preload("Tags").Preload("Authors").First(&document, documentID).Error
+	if err != nil {
+		return err
+	}
+	return recordRevision(tx, document, action, userID)
+}
+
+// ListRevisions returns the revisions of the document, the latest first.
+func (d *DBCatalog) ListRevisions(ctx context.Context, request ListRevisionsRequest) (ListRevisionsResponse, error) {
+	query := d.db.WithContext(ctx).Model(&DocumentRevision{}).Where("document_id = ?", request.DocumentID)
+
+	var totalElements int64
+	if err := query.Count(&totalElements).Error; err != nil {
+		return ListRevisionsResponse{}, err
+	}
+
+	var revisions []DocumentRevision
+	err := query.Order("id DESC").Offset(request.Pagination.Offset()).Limit(request.Pagination.PageSize).
+		Find(&revisions).Error
+
+	return ListRevisionsResponse{
+		Items:      revisions,
+		Pagination: newPaginationResponse(totalElements, request.Pagination),
+	}, err
+}
+
+func (d *DBCatalog) DiffRevisions(ctx context.Context, request DiffRevisionsRequest) (RevisionDiff, error) {
+	tx := d.db.WithContext(ctx)
+
+	var from DocumentRevision
+	if err := tx.Where("document_id = ?", request.DocumentID).First(&from, request.From).Error; err != nil {
+		return RevisionDiff{}, err
+	}
+
+	var to DocumentRevision
+	query := tx.Where("document_id = ?", request.DocumentID)
+	if request.To == 0 {
+		query = query.Order("id DESC")
+	} else {
+		query = query.Where("id = ?", request.To)
+	}
+	if err := query.Take(&to).Error; err != nil {
+		return RevisionDiff{}, err
+	}
+
+	return diffRevisions(from, to), nil
+}
+
+func diffRevisions(from, to DocumentRevision) RevisionDiff {
+	diff := RevisionDiff{From: from.ID, To: to.ID, Changes: []FieldChange{}}
+
+	fields := []struct {
+		name     string
+		from, to string
+	}{
+		{name: "title", from: from.Title, to: to.Title},
+		{name: "uri", from: from.Uri, to: to.Uri},
+		{name: "kind", from: from.Kind, to: to.Kind},
+		{name: "content_sha256", from: from.ContentSHA256, to: to.ContentSHA256},
+	}
+	for _, field := range fields {
+		if field.from != field.to {
+			diff.Changes = append(diff.Changes, FieldChange{Field: field.name, From: field.from, To: field.to})
+		}
+	}
+
+	diff.AddedTags = missingStrings(to.Tags, from.Tags)
+	diff.RemovedTags = missingStrings(from.Tags, to.Tags)
+	diff.AddedAuthors = missingAuthors(to.Authors, from.Authors)
+	diff.RemovedAuthors = missingAuthors(from.Authors, to.Authors)
+	return diff
+}
+
+// missingStrings returns the values of a missing from b.
+func missingStrings(a, b []string) []string {
+	present := make(map[string]bool, len(b))
+	for _, value := range b {
+		present[value] = true
+	}
+
+	var missing []string
+	for _, value := range a {
+		if !present[value] {
+			missing = append(missing, value)
+		}
+	}
+	return missing
+}
+
+// missingAuthors returns the authors of a missing from b.
+func missingAuthors(a, b []RevisionAuthor) []RevisionAuthor {
+	present := make(map[RevisionAuthor]bool, len(b))
+	for _, author := range b {
+		present[author] = true
+	}
+
+	var missing []RevisionAuthor
+	for _, author := range a {
+		if !present[author] {
+			missing = append(missing, author)
+		}
+	}
+	return missing
+}
+
+// RestoreRevision sets the metadata of the document back to the revision,
+// the content is left untouched.
+func (d *DBCatalog) RestoreRevision(ctx context.Context, request RestoreRevisionRequest) (Document, error) {
+	if request.RevisionID == 0 {
+		return Document{}, errors.New("the revision must be defined")
+	}
+
+	var revision DocumentRevision
+	err := d.db.WithContext(ctx).Where("document_id = ?", request.DocumentID).First(&revision, request.RevisionID).Error
+	if err != nil {
+		return Document{}, err
+	}
+
+	document := Document{
+		Title:        &revision.Title,
+		Uri:          &revision.Uri,
+		DocumentKind: DocumentKind{Name: revision.Kind},
+		Tags:         []DocumentTag{},
+		Authors:      []DocumentAuthor{},
+	}
+	for _, tag := range revision.Tags {
+		document.Tags = append(document.Tags, DocumentTag{Tag: tag})
+	}
+	for _, author := range revision.Authors {
+		document.Authors = append(document.Authors, DocumentAuthor{Name: author.Name, Surname: author.Surname})
+	}
+
+	return d.updateDocument(ctx, UpdateDocumentRequest{
+		DocumentID: request.DocumentID,
+		Document:   document,
+		UserID:     request.UserID,
+	}, RevisionRestore)
+}
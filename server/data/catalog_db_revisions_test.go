@@ -0,0 +1,108 @@
+package data
+
+import (
+	"context"
+	"github.com/garugaru/knowledge/server/storage"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestDBCatalog_Revisions(t *testing.T) {
+	catalog, _ := newTestCatalog(t, WithBlobStore(storage.NewLocalBlobStore(t.TempDir())))
+
+	err := catalog.InsertDocument(context.TODO(), InsertDocumentRequest{Document: Document{
+		Title:        strptr("Paxos Made Simple"),
+		Uri:          strptr("https://lamport.azurewebsites.net/pubs/paxos-simple.pdf"),
+		DocumentKind: DocumentKind{Name: "paper"},
+		Tags:         []DocumentTag{{Tag: "consensus"}},
+		Authors:      []DocumentAuthor{{Name: "Leslie", Surname: "Lamport"}},
+		OwnerID:      7,
+	}})
+	require.NoError(t, err)
+
+	_, err = catalog.UpdateDocument(context.TODO(), UpdateDocumentRequest{
+		DocumentID: 1,
+		Document: Document{
+			Title: strptr("Paxos"),
+			Tags:  []DocumentTag{{Tag: "distributed"}},
+		},
+		Partial: true,
+		UserID:  8,
+	})
+	require.NoError(t, err)
+
+	_, err = catalog.PutDocumentContent(context.TODO(), PutDocumentContentRequest{
+		DocumentID: 1,
+		Content:    strings.NewReader("hello world"),
+		UserID:     8,
+	})
+	require.NoError(t, err)
+
+	revisions, err := catalog.ListRevisions(context.TODO(), ListRevisionsRequest{
+		DocumentID: 1,
+		Pagination: PaginationRequest{Page: 1, PageSize: 10},
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 3, revisions.Pagination.TotalElements)
+	require.Len(t, revisions.Items, 3)
+
+	content, update, insert := revisions.Items[0], revisions.Items[1], revisions.Items[2]
+	require.Equal(t, RevisionContent, content.Action)
+	require.NotEmpty(t, content.ContentSHA256)
+	require.Equal(t, RevisionUpdate, update.Action)
+	require.Equal(t, uint(8), update.UserID)
+	require.Equal(t, RevisionInsert, insert.Action)
+	require.Equal(t, uint(7), insert.UserID)
+	require.Equal(t, "Paxos Made Simple", insert.Title)
+	require.Equal(t, "paper", insert.Kind)
+	require.Equal(t, RevisionTags{"consensus"}, insert.Tags)
+	require.Equal(t, RevisionAuthors{{Name: "Leslie", Surname: "Lamport"}}, insert.Authors)
+	require.NotZero(t, insert.CreatedAt)
+
+	diff, err := catalog.DiffRevisions(context.TODO(), DiffRevisionsRequest{DocumentID: 1, From: insert.ID})
+	require.NoError(t, err)
+	require.Equal(t, content.ID, diff.To, "the diff is made against the latest revision by default")
+	require.Equal(t, []FieldChange{
+		{Field: "title", From: "Paxos Made Simple", To: "Paxos"},
+		{Field: "content_sha256", From: "", To: content.ContentSHA256},
+	}, diff.Changes)
+	require.Equal(t, []string{"distributed"}, diff.AddedTags)
+	require.Equal(t, []string{"consensus"}, diff.RemovedTags)
+	require.Empty(t, diff.AddedAuthors)
+
+	_, err = catalog.DiffRevisions(context.TODO(), DiffRevisionsRequest{DocumentID: 2, From: insert.ID})
+	require.Error(t, err, "the revisions must belong to the document")
+
+	document, err := catalog.RestoreRevision(context.TODO(), RestoreRevisionRequest{DocumentID: 1, RevisionID: insert.ID, UserID: 9})
+	require.NoError(t, err)
+	require.Equal(t, "Paxos Made Simple", *document.Title)
+	require.Len(t, document.Tags, 1)
+	require.Equal(t, "consensus", document.Tags[0].Tag)
+	require.Equal(t, "paper", document.DocumentKind.Name)
+	require.NotEmpty(t, document.Content.SHA256, "restoring a revision leaves the content untouched")
+
+	restore, err := catalog.DiffRevisions(context.TODO(), DiffRevisionsRequest{DocumentID: 1, From: insert.ID})
+	require.NoError(t, err)
+	require.Len(t, restore.Changes, 1)
+	require.Equal(t, "content_sha256", restore.Changes[0].Field)
+	require.Empty(t, restore.AddedTags)
+	require.Empty(t, restore.RemovedTags)
+
+	_, err = catalog.UpdateTag(context.TODO(), UpdateTagRequest{TagID: document.Tags[0].ID, Tag: DocumentTag{Tag: "consensus-algorithms"}})
+	require.NoError(t, err)
+
+	require.NoError(t, catalog.DeleteDocument(context.TODO(), DeleteDocumentRequest{DocumentID: 1, UserID: 9}))
+	revisions, err = catalog.ListRevisions(context.TODO(), ListRevisionsRequest{
+		DocumentID: 1,
+		Pagination: PaginationRequest{Page: 1, PageSize: 3},
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 6, revisions.Pagination.TotalElements, "the history outlives the document")
+	require.Len(t, revisions.Items, 3)
+	require.Equal(t, RevisionDelete, revisions.Items[0].Action)
+	require.Equal(t, RevisionUpdate, revisions.Items[1].Action, "the taxonomy changes are recorded")
+	require.Equal(t, RevisionTags{"consensus-algorithms"}, revisions.Items[1].Tags)
+	require.Equal(t, RevisionRestore, revisions.Items[2].Action)
+	require.Equal(t, uint(9), revisions.Items[2].UserID)
+}
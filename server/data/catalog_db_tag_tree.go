@@ -0,0 +1,318 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"sort"
+	"strings"
+)
+
+func validateTagPath(path string) error {
+	if len(path) == 0 {
+		return errors.New("tag must be defined")
+	}
+
+	for _, segment := range strings.Split(path, "/") {
+		if len(segment) == 0 {
+			return fmt.Errorf("tag %q has an empty segment", path)
+		}
+	}
+
+	return nil
+}
+
+// tagName returns the last segment of the path of a tag.
+func tagName(path string) string {
+	return path[strings.LastIndex(path, "/")+1:]
+}
+
+// createTag creates the tag of path below its parent, creating the missing
+// ancestors. The path follows the parent when it is resolved through an
+// alias, go/generics becomes golang/generics once go is merged into golang.
+func createTag(tx *gorm.DB, path string) (DocumentTag, error) {
+	if err := validateTagPath(path); err != nil {
+		return DocumentTag{}, err
+	}
+
+	tag := DocumentTag{Tag: path}
+	var ancestors []TagClosure
+	if slash := strings.LastIndex(path, "/"); slash != -1 {
+		parent, err := reuseTag(tx, path[:slash])
+		if err != nil {
+			return DocumentTag{}, err
+		}
+
+		// the path below the surviving tag can be taken or be an alias too
+		if resolved := parent.Tag + "/" + tagName(path); resolved != path {
+			return reuseTag(tx, resolved)
+		}
+		tag.ParentID = &parent.ID
+
+		if err := tx.Where("descendant_id = ?", parent.ID).Find(&ancestors).Error; err != nil {
+			return DocumentTag{}, err
+		}
+	}
+
+	result := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tag"}},
+		DoNothing: true,
+	}).Create(&tag)
+	if result.Error != nil {
+		return DocumentTag{}, result.Error
+	}
+
+	// the tag was created concurrently, along with its closure
+	if result.RowsAffected == 0 {
+		var existing DocumentTag
+		return existing, tx.Where("tag = ?", path).First(&existing).Error
+	}
+
+	closure := []TagClosure{{AncestorID: tag.ID, DescendantID: tag.ID}}
+	for _, ancestor := range ancestors {
+		closure = append(closure, TagClosure{AncestorID: ancestor.AncestorID, DescendantID: tag.ID, Depth: ancestor.Depth + 1})
+	}
+
+	return tag, tx.Create(&closure).Error
+}
+
+// initTagClosure adds the tags created by older versions to the closure, as
+// roots of the hierarchy.
+func initTagClosure(db *gorm.DB) error {
+	return db.Exec("INSERT INTO tag_closures (ancestor_id, descendant_id, depth) " +
+		"SELECT id, id, 0 FROM document_tags WHERE id NOT IN (SELECT descendant_id FROM tag_closures)").Error
+}
+
+// relocateTag renames tag to path and moves it below parent, a nil parent
+// makes it a root. The tags below it follow, the returned documents reference
+// one of the renamed tags.
+func relocateTag(tx *gorm.DB, tag *DocumentTag, path string, parent *DocumentTag) ([]int, error) {
+	var links []TagClosure
+	if err := tx.Where("ancestor_id = ?", tag.ID).Find(&links).Error; err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, len(links))
+	for i, link := range links {
+		ids[i] = link.DescendantID
+	}
+
+	var subtree []DocumentTag
+	if err := tx.Find(&subtree, ids).Error; err != nil {
+		return nil, err
+	}
+
+	if parent != nil {
+		for _, id := range ids {
+			if id == parent.ID {
+				return nil, errors.New("a tag cannot be moved below itself")
+			}
+		}
+	}
+
+	// the paths are rebuilt from the parents, closest first, the tags created
+	// below an alias keep the last segment only
+	depths := make(map[uint]int, len(links))
+	for _, link := range links {
+		depths[link.DescendantID] = link.Depth
+	}
+	sort.Slice(subtree, func(i, j int) bool { return depths[subtree[i].ID] < depths[subtree[j].ID] })
+
+	paths := map[uint]string{tag.ID: path}
+	renamed := make(map[uint]string)
+	owners := make(map[string]uint)
+	for _, member := range subtree {
+		name, ok := paths[member.ID]
+		if !ok {
+			if member.ParentID == nil {
+				continue
+			}
+			parentPath, ok := paths[*member.ParentID]
+			if !ok {
+				continue
+			}
+			name = parentPath + "/" + tagName(member.Tag)
+			paths[member.ID] = name
+		}
+		if name != member.Tag {
+			renamed[member.ID] = name
+			owners[name] = member.ID
+		}
+	}
+
+	if len(renamed) != 0 {
+		names := make([]string, 0, len(owners))
+		for name := range owners {
+			names = append(names, name)
+		}
+
+		var taken []DocumentTag
+		if err := tx.Where("tag IN ?", names).Find(&taken).Error; err != nil {
+			return nil, err
+		}
+
+		for _, existing := range taken {
+			if existing.ID != owners[existing.Tag] {
+				return nil, fmt.Errorf("tag %s already exists", existing.Tag)
+			}
+		}
+
+		var aliases int64
+		if err := tx.Model(&TagAlias{}).Where("alias IN ? AND tag_id NOT IN ?", names, ids).Count(&aliases).Error; err != nil {
+			return nil, err
+		}
+
+		if aliases != 0 {
+			return nil, errors.New("the new path is the alias of another tag")
+		}
+
+		// taking back the spelling of a merged tag makes its alias useless
+		if err := tx.Where("alias IN ?", names).Delete(&TagAlias{}).Error; err != nil {
+			return nil, err
+		}
+
+		for id, name := range renamed {
+			if err := tx.Model(&DocumentTag{}).Where("id = ?", id).Update("tag", name).Error; err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var parentID *uint
+	if parent != nil {
+		parentID = &parent.ID
+	}
+
+	if !sameTagParent(tag.ParentID, parentID) {
+		if err := tx.Where("descendant_id IN ? AND ancestor_id NOT IN ?", ids, ids).Delete(&TagClosure{}).Error; err != nil {
+			return nil, err
+		}
+
+		if parent != nil {
+			var ancestors []TagClosure
+			if err := tx.Where("descendant_id = ?", parent.ID).Find(&ancestors).Error; err != nil {
+				return nil, err
+			}
+
+			closure := make([]TagClosure, 0, len(ancestors)*len(links))
+			for _, ancestor := range ancestors {
+				for _, link := range links {
+					closure = append(closure, TagClosure{
+						AncestorID:   ancestor.AncestorID,
+						DescendantID: link.DescendantID,
+						Depth:        ancestor.Depth + link.Depth + 1,
+					})
+				}
+			}
+
+			if err := tx.Create(&closure).Error; err != nil {
+				return nil, err
+			}
+		}
+
+		if err := tx.Model(&DocumentTag{}).Where("id = ?", tag.ID).Update("parent_id", parentID).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var documentIDs []int
+	err := tx.Table("document_document_tags").Distinct("document_id").
+		Where("document_tag_id IN ?", ids).Pluck("document_id", &documentIDs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return documentIDs, tx.First(tag, tag.ID).Error
+}
+
+func sameTagParent(a *uint, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// MoveTag moves a tag with the tags below it under another parent, the paths
+// of the moved tags are updated.
+func (d *DBCatalog) MoveTag(ctx context.Context, request MoveTagRequest) (DocumentTag, error) {
+	var tag DocumentTag
+	var documents []Document
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&tag, request.TagID).Error; err != nil {
+			return err
+		}
+
+		name := tagName(tag.Tag)
+		path := name
+		var parent *DocumentTag
+		if request.ParentID != 0 {
+			parent = &DocumentTag{}
+			if err := tx.First(parent, request.ParentID).Error; err != nil {
+				return err
+			}
+			path = parent.Tag + "/" + name
+		}
+
+		documentIDs, err := relocateTag(tx, &tag, path, parent)
+		if err != nil {
+			return err
+		}
+
+		documents, err = d.reindexDocuments(tx, documentIDs, request.UserID)
+		return err
+	})
+
+	if err != nil {
+		return DocumentTag{}, err
+	}
+
+	d.syncSearchIndexes(documents)
+	return tag, nil
+}
+
+// GetTagTree returns the tag hierarchy, or the subtree of a tag, with the
+// number of documents of each tag.
+func (d *DBCatalog) GetTagTree(ctx context.Context, request GetTagTreeRequest) ([]DocumentTag, error) {
+	query := d.db.WithContext(ctx).Select("document_tags.*, " + tagDocumentCount)
+	if request.TagID != 0 {
+		query = query.Where("document_tags.id IN (?)",
+			d.db.WithContext(ctx).Model(&TagClosure{}).Select("descendant_id").Where("ancestor_id = ?", request.TagID))
+	}
+
+	var tags []DocumentTag
+	if err := query.Order("tag").Find(&tags).Error; err != nil {
+		return nil, err
+	}
+
+	if request.TagID != 0 && len(tags) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	children := make(map[uint][]DocumentTag)
+	present := make(map[uint]bool, len(tags))
+	for _, tag := range tags {
+		present[tag.ID] = true
+	}
+
+	var roots []DocumentTag
+	for _, tag := range tags {
+		if tag.ParentID == nil || !present[*tag.ParentID] {
+			roots = append(roots, tag)
+		} else {
+			children[*tag.ParentID] = append(children[*tag.ParentID], tag)
+		}
+	}
+
+	var build func(tags []DocumentTag) []DocumentTag
+	build = func(tags []DocumentTag) []DocumentTag {
+		sort.Slice(tags, func(i, j int) bool { return tags[i].Tag < tags[j].Tag })
+		for i := range tags {
+			tags[i].Children = build(children[tags[i].ID])
+		}
+		return tags
+	}
+
+	return build(roots), nil
+}
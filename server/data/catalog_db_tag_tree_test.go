@@ -0,0 +1,152 @@
+package data
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestDBCatalog_TagTree(t *testing.T) {
+	catalog, db := newTestCatalog(t)
+
+	documents := map[string]string{
+		"streaming":   "databases/postgres/replication",
+		"vacuum":      "databases/postgres",
+		"binlog":      "databases/mysql/replication",
+		"kubernetes":  "ops",
+		"no-database": "ops/backups",
+	}
+	for _, title := range []string{"streaming", "vacuum", "binlog", "kubernetes", "no-database"} {
+		err := catalog.InsertDocument(context.TODO(), InsertDocumentRequest{
+			Document: Document{Title: strptr(title), Uri: strptr(title), Tags: []DocumentTag{{Tag: documents[title]}}},
+		})
+		require.NoError(t, err)
+	}
+
+	titles := func(tags ...string) []string {
+		response, err := catalog.ListDocuments(context.TODO(), ListDocumentsRequest{
+			Tags:       tags,
+			Pagination: PaginationRequest{Page: 1, PageSize: 10},
+		})
+		require.NoError(t, err)
+
+		var titles []string
+		for _, document := range response.Items {
+			titles = append(titles, *document.Title)
+		}
+		return titles
+	}
+	require.Equal(t, []string{"binlog", "streaming", "vacuum"}, titles("databases"))
+	require.Equal(t, []string{"streaming", "vacuum"}, titles("databases/postgres"))
+	require.Equal(t, []string{"binlog", "streaming"}, titles("databases/postgres/replication", "databases/mysql/replication"))
+
+	tree, err := catalog.GetTagTree(context.TODO(), GetTagTreeRequest{})
+	require.NoError(t, err)
+	require.Len(t, tree, 2)
+	require.Equal(t, "databases", tree[0].Tag)
+	require.Equal(t, "ops", tree[1].Tag)
+	require.Len(t, tree[0].Children, 2)
+	require.Equal(t, "databases/mysql", tree[0].Children[0].Tag)
+	require.Equal(t, "databases/postgres", tree[0].Children[1].Tag)
+	require.Equal(t, int64(1), tree[0].Children[1].DocumentCount)
+	require.Equal(t, "databases/postgres/replication", tree[0].Children[1].Children[0].Tag)
+
+	var ops, postgres DocumentTag
+	require.NoError(t, db.Where("tag = ?", "ops").First(&ops).Error)
+	require.NoError(t, db.Where("tag = ?", "databases/postgres").First(&postgres).Error)
+
+	_, err = catalog.MoveTag(context.TODO(), MoveTagRequest{TagID: *postgres.ParentID, ParentID: postgres.ID})
+	require.Error(t, err, "a tag cannot be moved below itself")
+
+	moved, err := catalog.MoveTag(context.TODO(), MoveTagRequest{TagID: postgres.ID, ParentID: ops.ID})
+	require.NoError(t, err)
+	require.Equal(t, "ops/postgres", moved.Tag)
+	require.Equal(t, ops.ID, *moved.ParentID)
+
+	require.Equal(t, []string{"binlog"}, titles("databases"))
+	require.Equal(t, []string{"kubernetes", "no-database", "streaming", "vacuum"}, titles("ops"))
+	require.Equal(t, []string{"streaming"}, titles("ops/postgres/replication"))
+
+	subtree, err := catalog.GetTagTree(context.TODO(), GetTagTreeRequest{TagID: postgres.ID})
+	require.NoError(t, err)
+	require.Len(t, subtree, 1)
+	require.Equal(t, "ops/postgres/replication", subtree[0].Children[0].Tag)
+
+	response, err := catalog.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Query:      "ops/postgres/replication",
+		Pagination: PaginationRequest{Page: 1, PageSize: 10},
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, response.Items, "moved tags are indexed")
+
+	renamed, err := catalog.UpdateTag(context.TODO(), UpdateTagRequest{TagID: postgres.ID, Tag: DocumentTag{Tag: "sql/pg"}})
+	require.NoError(t, err)
+	require.Equal(t, "sql/pg", renamed.Tag)
+	require.Equal(t, []string{"streaming", "vacuum"}, titles("sql"))
+
+	moved, err = catalog.MoveTag(context.TODO(), MoveTagRequest{TagID: postgres.ID})
+	require.NoError(t, err)
+	require.Equal(t, "pg", moved.Tag)
+	require.Nil(t, moved.ParentID)
+	require.Empty(t, titles("sql"))
+
+	_, err = catalog.MoveTag(context.TODO(), MoveTagRequest{TagID: postgres.ID, ParentID: ops.ID})
+	require.NoError(t, err)
+	_, err = catalog.UpdateTag(context.TODO(), UpdateTagRequest{TagID: ops.ID, Tag: DocumentTag{Tag: "databases/mysql"}})
+	require.Error(t, err, "renaming onto an existing tag is rejected")
+
+	require.Error(t, catalog.DeleteTag(context.TODO(), DeleteTagRequest{TagID: ops.ID}), "tags with children cannot be deleted")
+
+	_, err = catalog.InsertTag(context.TODO(), InsertTagRequest{Tag: DocumentTag{Tag: "a//b"}})
+	require.Error(t, err)
+}
+
+func TestDBCatalog_TagTree_Aliases(t *testing.T) {
+	catalog, db := newTestCatalog(t)
+
+	golang, err := catalog.InsertTag(context.TODO(), InsertTagRequest{Tag: DocumentTag{Tag: "golang"}})
+	require.NoError(t, err)
+	goTag, err := catalog.InsertTag(context.TODO(), InsertTagRequest{Tag: DocumentTag{Tag: "go"}})
+	require.NoError(t, err)
+	_, err = catalog.MergeTags(context.TODO(), MergeTagsRequest{TagID: golang.ID, MergedIDs: []uint{goTag.ID}})
+	require.NoError(t, err)
+
+	err = catalog.InsertDocument(context.TODO(), InsertDocumentRequest{
+		Document: Document{Title: strptr("generics"), Uri: strptr("generics"), Tags: []DocumentTag{{Tag: "go/generics"}}},
+	})
+	require.NoError(t, err)
+
+	document, err := catalog.GetDocument(context.TODO(), GetDocumentRequest{DocumentID: 1})
+	require.NoError(t, err)
+	require.Len(t, document.Tags, 1)
+	generics := document.Tags[0]
+	require.Equal(t, "golang/generics", generics.Tag, "the path follows the parent resolved through the alias")
+	require.Equal(t, golang.ID, *generics.ParentID)
+
+	err = catalog.InsertDocument(context.TODO(), InsertDocumentRequest{
+		Document: Document{Title: strptr("again"), Uri: strptr("again"), Tags: []DocumentTag{{Tag: "go/generics"}}},
+	})
+	require.NoError(t, err)
+	document, err = catalog.GetDocument(context.TODO(), GetDocumentRequest{DocumentID: 2})
+	require.NoError(t, err)
+	require.Equal(t, generics.ID, document.Tags[0].ID)
+
+	ops, err := catalog.InsertTag(context.TODO(), InsertTagRequest{Tag: DocumentTag{Tag: "ops"}})
+	require.NoError(t, err)
+	renamed, err := catalog.UpdateTag(context.TODO(), UpdateTagRequest{TagID: ops.ID, Tag: DocumentTag{Tag: "go/ops"}})
+	require.NoError(t, err)
+	require.Equal(t, "golang/ops", renamed.Tag)
+	require.Equal(t, golang.ID, *renamed.ParentID)
+
+	// the paths created below an alias by older versions are rebuilt when moved
+	require.NoError(t, db.Model(&DocumentTag{}).Where("id = ?", generics.ID).Update("tag", "go/generics").Error)
+	moved, err := catalog.MoveTag(context.TODO(), MoveTagRequest{TagID: golang.ID})
+	require.NoError(t, err)
+	require.Equal(t, "golang", moved.Tag)
+	document, err = catalog.GetDocument(context.TODO(), GetDocumentRequest{DocumentID: 1})
+	require.NoError(t, err)
+	require.Equal(t, "golang/generics", document.Tags[0].Tag, "the tags below follow the rebuilt path")
+	moved, err = catalog.MoveTag(context.TODO(), MoveTagRequest{TagID: generics.ID, ParentID: renamed.ID})
+	require.NoError(t, err)
+	require.Equal(t, "golang/ops/generics", moved.Tag)
+}
@@ -0,0 +1,892 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strings"
+)
+
+const (
+	tagDocumentCount = "(SELECT count(*) FROM document_document_tags JOIN documents ON documents.id = document_document_tags.document_id " +
+		"WHERE document_document_tags.document_tag_id = document_tags.id AND documents.deleted_at IS NULL) AS document_count"
+	authorDocumentCount = "(SELECT count(*) FROM document_document_authors JOIN documents ON documents.id = document_document_authors.document_id " +
+		"WHERE document_document_authors.document_author_id = document_authors.id AND documents.deleted_at IS NULL) AS document_count"
+	kindDocumentCount = "(SELECT count(*) FROM documents " +
+		"WHERE documents.document_kind_id = document_kinds.id AND documents.deleted_at IS NULL) AS document_count"
+)
+
+// naturalKey identifies the tags, authors and kinds by their content instead
+// of their id, reference is the table pointing to them through column.
+type naturalKey struct {
+	table     string
+	columns   []string
+	reference string
+	column    string
+}
+
+var (
+	tagKey    = naturalKey{table: "document_tags", columns: []string{"tag"}, reference: "document_document_tags", column: "document_tag_id"}
+	authorKey = naturalKey{table: "document_authors", columns: []string{"name", "surname"}, reference: "document_document_authors", column: "document_author_id"}
+	kindKey   = naturalKey{table: "document_kinds", columns: []string{"name"}, reference: "documents", column: "document_kind_id"}
+
+	naturalKeys = []naturalKey{tagKey, authorKey, kindKey}
+)
+
+// mergeDuplicateEntities merges the tags, authors and kinds sharing the same
+// natural key, it runs before the unique indexes are created on databases
+// filled by older versions.
+func mergeDuplicateEntities(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, key := range naturalKeys {
+			if !tx.Migrator().HasTable(key.table) || !tx.Migrator().HasTable(key.reference) {
+				continue
+			}
+
+			// the soft deleted rows are already hidden from the documents
+			var deleted []int64
+			if err := tx.Table(key.table).Where("deleted_at IS NOT NULL").Pluck("id", &deleted).Error; err != nil {
+				return err
+			}
+			if err := removeEntityRows(tx, key, deleted); err != nil {
+				return err
+			}
+
+			columns := strings.Join(key.columns, ", ")
+			var groups []map[string]interface{}
+			err := tx.Table(key.table).Select("min(id) AS keeper, " + columns).
+				Group(columns).Having("count(*) > 1").Find(&groups).Error
+			if err != nil {
+				return err
+			}
+
+			for _, group := range groups {
+				query := tx.Table(key.table).Where("id <> ?", group["keeper"])
+				for _, column := range key.columns {
+					query = query.Where(column+" = ?", group[column])
+				}
+
+				var duplicates []int64
+				if err := query.Pluck("id", &duplicates).Error; err != nil {
+					return err
+				}
+
+				for _, duplicate := range duplicates {
+					if err := moveEntityReferences(tx, key, duplicate, group["keeper"]); err != nil {
+						return err
+					}
+				}
+
+				if err := removeEntityRows(tx, key, duplicates); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// moveEntityReferences points the documents referencing from to the entity to.
+func moveEntityReferences(tx *gorm.DB, key naturalKey, from interface{}, to interface{}) error {
+	if key.reference == "documents" {
+		return tx.Table(key.reference).Where(key.column+" = ?", from).UpdateColumn(key.column, to).Error
+	}
+
+	// documents referencing both entities keep a single row
+	var linked []int64
+	if err := tx.Table(key.reference).Where(key.column+" = ?", to).Pluck("document_id", &linked).Error; err != nil {
+		return err
+	}
+	if len(linked) != 0 {
+		err := tx.Table(key.reference).Where(key.column+" = ? AND document_id IN ?", from, linked).
+			Delete(map[string]interface{}{}).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Table(key.reference).Where(key.column+" = ?", from).UpdateColumn(key.column, to).Error
+}
+
+// removeEntityRows deletes the entities ids with their references.
+func removeEntityRows(tx *gorm.DB, key naturalKey, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var err error
+	if key.reference == "documents" {
+		err = tx.Table(key.reference).Where(key.column+" IN ?", ids).UpdateColumn(key.column, 0).Error
+	} else {
+		err = tx.Table(key.reference).Where(key.column+" IN ?", ids).Delete(map[string]interface{}{}).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Table(key.table).Where("id IN ?", ids).Delete(map[string]interface{}{}).Error
+}
+
+// mergedEntityIDs validates the entities merged into survivorID, removing the repeated ones.
+func mergedEntityIDs(survivorID uint, ids []uint) ([]int64, error) {
+	if len(ids) == 0 {
+		return nil, errors.New("the merged ids must be defined")
+	}
+
+	seen := make(map[uint]bool, len(ids))
+	merged := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if id == survivorID {
+			return nil, errors.New("an entity cannot be merged into itself")
+		}
+		if !seen[id] {
+			seen[id] = true
+			merged = append(merged, int64(id))
+		}
+	}
+
+	return merged, nil
+}
+
+func reuseTag(tx *gorm.DB, tag string) (DocumentTag, error) {
+	if len(tag) == 0 {
+		return DocumentTag{}, errors.New("tag must be defined")
+	}
+
+	var alias TagAlias
+	if err := tx.Where("alias = ?", tag).Limit(1).Find(&alias).Error; err != nil {
+		return DocumentTag{}, err
+	}
+
+	if alias.TagID != 0 {
+		var merged DocumentTag
+		return merged, tx.First(&merged, alias.TagID).Error
+	}
+
+	var reused DocumentTag
+	if err := tx.Where("tag = ?", tag).Limit(1).Find(&reused).Error; err != nil {
+		return DocumentTag{}, err
+	}
+
+	if reused.ID != 0 {
+		return reused, nil
+	}
+
+	return createTag(tx, tag)
+}
+
+func reuseAuthor(tx *gorm.DB, author DocumentAuthor) (DocumentAuthor, error) {
+	if len(author.Name) == 0 && len(author.Surname) == 0 {
+		return DocumentAuthor{}, errors.New("author name or surname must be defined")
+	}
+
+	var alias AuthorAlias
+	if err := tx.Where("name = ? AND surname = ?", author.Name, author.Surname).Limit(1).Find(&alias).Error; err != nil {
+		return DocumentAuthor{}, err
+	}
+
+	if alias.AuthorID != 0 {
+		var merged DocumentAuthor
+		return merged, tx.First(&merged, alias.AuthorID).Error
+	}
+
+	err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}, {Name: "surname"}},
+		DoNothing: true,
+	}).Create(&DocumentAuthor{Name: author.Name, Surname: author.Surname}).Error
+	if err != nil {
+		return DocumentAuthor{}, err
+	}
+
+	var reused DocumentAuthor
+	return reused, tx.Where("name = ? AND surname = ?", author.Name, author.Surname).First(&reused).Error
+}
+
+func reuseKind(tx *gorm.DB, name string) (DocumentKind, error) {
+	if len(name) == 0 {
+		return DocumentKind{}, errors.New("kind name must be defined")
+	}
+
+	err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoNothing: true,
+	}).Create(&DocumentKind{Name: name}).Error
+	if err != nil {
+		return DocumentKind{}, err
+	}
+
+	var reused DocumentKind
+	return reused, tx.Where("name = ?", name).First(&reused).Error
+}
+
+// resolveDocumentEntities replaces the tags, authors and kind without id of
+// document with the existing ones sharing their natural key, creating the
+// missing ones.
+func resolveDocumentEntities(tx *gorm.DB, document *Document) error {
+	if document.DocumentKind.ID == 0 && !document.DocumentKind.isZero() {
+		kind, err := reuseKind(tx, document.DocumentKind.Name)
+		if err != nil {
+			return err
+		}
+		document.DocumentKind = kind
+	}
+
+	if document.Tags != nil {
+		tags := make([]DocumentTag, 0, len(document.Tags))
+		seen := make(map[uint]bool, len(document.Tags))
+		for _, tag := range document.Tags {
+			if tag.ID == 0 {
+				var err error
+				if tag, err = reuseTag(tx, tag.Tag); err != nil {
+					return err
+				}
+			}
+			if !seen[tag.ID] {
+				seen[tag.ID] = true
+				tags = append(tags, tag)
+			}
+		}
+		document.Tags = tags
+	}
+
+	if document.Authors != nil {
+		authors := make([]DocumentAuthor, 0, len(document.Authors))
+		seen := make(map[uint]bool, len(document.Authors))
+		for _, author := range document.Authors {
+			if author.ID == 0 {
+				var err error
+				if author, err = reuseAuthor(tx, author); err != nil {
+					return err
+				}
+			}
+			if !seen[author.ID] {
+				seen[author.ID] = true
+				authors = append(authors, author)
+			}
+		}
+		document.Authors = authors
+	}
+
+	return nil
+}
+
+// reindexDocuments refreshes the search index of documentIDs after a change
+// of the entities they reference, see syncSearchIndexes, and records their
+// updated revisions made by userID.
+func (d *DBCatalog) reindexDocuments(tx *gorm.DB, documentIDs []int, userID uint) ([]Document, error) {
+	documents := make([]Document, 0, len(documentIDs))
+	for _, documentID := range documentIDs {
+		document, err := d.loadIndexedDocument(tx, documentID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := recordRevision(tx, document, RevisionUpdate, userID); err != nil {
+			return nil, err
+		}
+		documents = append(documents, document)
+	}
+	return documents, nil
+}
+
+// syncSearchIndexes applies a committed change of many documents to the
+// embedded search index.
+func (d *DBCatalog) syncSearchIndexes(documents []Document) {
+	if d.index == nil {
+		return
+	}
+
+	for _, document := range documents {
+		d.index.Add(newSearchDocument(document).indexDocument())
+	}
+}
+
+func (d *DBCatalog) ListTags(ctx context.Context, request ListTagsRequest) (ListTagsResponse, error) {
+	var totalElements int64
+	if err := d.db.WithContext(ctx).Model(&DocumentTag{}).Count(&totalElements).Error; err != nil {
+		return ListTagsResponse{}, err
+	}
+
+	var tags []DocumentTag
+	err := d.db.WithContext(ctx).Select("document_tags.*, " + tagDocumentCount).Order("tag").
+		Offset(request.Pagination.Offset()).Limit(request.Pagination.PageSize).
+		Find(&tags).Error
+	if err != nil {
+		return ListTagsResponse{}, err
+	}
+
+	ids := make([]uint, len(tags))
+	for i, tag := range tags {
+		ids[i] = tag.ID
+	}
+
+	var aliases []TagAlias
+	err = d.db.WithContext(ctx).Where("tag_id IN ?", ids).Order("alias").Find(&aliases).Error
+	for _, alias := range aliases {
+		for i := range tags {
+			if tags[i].ID == alias.TagID {
+				tags[i].Aliases = append(tags[i].Aliases, alias.Alias)
+			}
+		}
+	}
+
+	return ListTagsResponse{
+		Items:      tags,
+		Pagination: newPaginationResponse(totalElements, request.Pagination),
+	}, err
+}
+
+func (d *DBCatalog) InsertTag(ctx context.Context, request InsertTagRequest) (DocumentTag, error) {
+	tag := DocumentTag{Tag: request.Tag.Tag}
+	if len(tag.Tag) == 0 {
+		return DocumentTag{}, errors.New("tag must be defined")
+	}
+
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		if err := tx.Model(&DocumentTag{}).Where("tag = ?", tag.Tag).Count(&existing).Error; err != nil {
+			return err
+		}
+
+		if existing != 0 {
+			return fmt.Errorf("tag %s already exists", tag.Tag)
+		}
+
+		if err := tx.Model(&TagAlias{}).Where("alias = ?", tag.Tag).Count(&existing).Error; err != nil {
+			return err
+		}
+
+		if existing != 0 {
+			return fmt.Errorf("%s is the alias of an existing tag", tag.Tag)
+		}
+
+		var err error
+		tag, err = createTag(tx, tag.Tag)
+		return err
+	})
+
+	return tag, err
+}
+
+// UpdateTag renames a tag, the tags below it follow to the new path.
+func (d *DBCatalog) UpdateTag(ctx context.Context, request UpdateTagRequest) (DocumentTag, error) {
+	path := request.Tag.Tag
+	if err := validateTagPath(path); err != nil {
+		return DocumentTag{}, err
+	}
+
+	var tag DocumentTag
+	var documents []Document
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&tag, request.TagID).Error; err != nil {
+			return err
+		}
+
+		if strings.HasPrefix(path, tag.Tag+"/") {
+			return errors.New("a tag cannot be moved below itself")
+		}
+
+		var parent *DocumentTag
+		if slash := strings.LastIndex(path, "/"); slash != -1 {
+			reused, err := reuseTag(tx, path[:slash])
+			if err != nil {
+				return err
+			}
+			parent = &reused
+			// the parent can be resolved through an alias
+			path = reused.Tag + "/" + tagName(path)
+		}
+
+		documentIDs, err := relocateTag(tx, &tag, path, parent)
+		if err != nil {
+			return err
+		}
+
+		documents, err = d.reindexDocuments(tx, documentIDs, request.UserID)
+		return err
+	})
+
+	if err != nil {
+		return DocumentTag{}, err
+	}
+
+	d.syncSearchIndexes(documents)
+	return tag, nil
+}
+
+func (d *DBCatalog) DeleteTag(ctx context.Context, request DeleteTagRequest) error {
+	var documents []Document
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var children int64
+		if err := tx.Model(&DocumentTag{}).Where("parent_id = ?", request.TagID).Count(&children).Error; err != nil {
+			return err
+		}
+
+		if children != 0 {
+			return errors.New("the tags below the tag must be moved or deleted first")
+		}
+
+		var documentIDs []int
+		if err := tx.Table("document_document_tags").Where("document_tag_id = ?", request.TagID).Pluck("document_id", &documentIDs).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("descendant_id = ?", request.TagID).Delete(&TagClosure{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Table("document_document_tags").Where("document_tag_id = ?", request.TagID).Delete(map[string]interface{}{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("tag_id = ?", request.TagID).Delete(&TagAlias{}).Error; err != nil {
+			return err
+		}
+
+		// tags are removed for good so that they can be created again
+		result := tx.Unscoped().Delete(&DocumentTag{}, request.TagID)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		var err error
+		documents, err = d.reindexDocuments(tx, documentIDs, request.UserID)
+		return err
+	})
+
+	if err != nil {
+		return err
+	}
+
+	d.syncSearchIndexes(documents)
+	return nil
+}
+
+// MergeTags moves the documents of the merged tags to the surviving one and
+// deletes the merged tags, their spelling keeps resolving to the survivor.
+func (d *DBCatalog) MergeTags(ctx context.Context, request MergeTagsRequest) (DocumentTag, error) {
+	mergedIDs, err := mergedEntityIDs(request.TagID, request.MergedIDs)
+	if err != nil {
+		return DocumentTag{}, err
+	}
+
+	var tag DocumentTag
+	var documents []Document
+	err = d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&tag, request.TagID).Error; err != nil {
+			return err
+		}
+
+		var merged []DocumentTag
+		if err := tx.Find(&merged, mergedIDs).Error; err != nil {
+			return err
+		}
+
+		if len(merged) != len(mergedIDs) {
+			return gorm.ErrRecordNotFound
+		}
+
+		var children int64
+		if err := tx.Model(&DocumentTag{}).Where("parent_id IN ?", mergedIDs).Count(&children).Error; err != nil {
+			return err
+		}
+
+		if children != 0 {
+			return errors.New("the tags below a merged tag must be moved first")
+		}
+
+		if err := tx.Where("descendant_id IN ?", mergedIDs).Delete(&TagClosure{}).Error; err != nil {
+			return err
+		}
+
+		var documentIDs []int
+		if err := tx.Table(tagKey.reference).Where("document_tag_id IN ?", mergedIDs).Pluck("document_id", &documentIDs).Error; err != nil {
+			return err
+		}
+
+		for _, loser := range merged {
+			if err := moveEntityReferences(tx, tagKey, loser.ID, tag.ID); err != nil {
+				return err
+			}
+
+			if err := tx.Model(&TagAlias{}).Where("tag_id = ?", loser.ID).Update("tag_id", tag.ID).Error; err != nil {
+				return err
+			}
+
+			alias := TagAlias{Alias: loser.Tag, TagID: tag.ID}
+			if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&alias).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := removeEntityRows(tx, tagKey, mergedIDs); err != nil {
+			return err
+		}
+
+		var err error
+		documents, err = d.reindexDocuments(tx, documentIDs, request.UserID)
+		return err
+	})
+
+	if err != nil {
+		return DocumentTag{}, err
+	}
+
+	d.syncSearchIndexes(documents)
+	return tag, nil
+}
+
+func (d *DBCatalog) ListAuthors(ctx context.Context, request ListAuthorsRequest) (ListAuthorsResponse, error) {
+	var totalElements int64
+	if err := d.db.WithContext(ctx).Model(&DocumentAuthor{}).Count(&totalElements).Error; err != nil {
+		return ListAuthorsResponse{}, err
+	}
+
+	var authors []DocumentAuthor
+	err := d.db.WithContext(ctx).Select("document_authors.*, " + authorDocumentCount).Order("surname, name").
+		Offset(request.Pagination.Offset()).Limit(request.Pagination.PageSize).
+		Find(&authors).Error
+	if err != nil {
+		return ListAuthorsResponse{}, err
+	}
+
+	ids := make([]uint, len(authors))
+	for i, author := range authors {
+		ids[i] = author.ID
+	}
+
+	var aliases []AuthorAlias
+	err = d.db.WithContext(ctx).Where("author_id IN ?", ids).Order("surname, name").Find(&aliases).Error
+	for _, alias := range aliases {
+		for i := range authors {
+			if authors[i].ID == alias.AuthorID {
+				authors[i].Aliases = append(authors[i].Aliases, alias)
+			}
+		}
+	}
+
+	return ListAuthorsResponse{
+		Items:      authors,
+		Pagination: newPaginationResponse(totalElements, request.Pagination),
+	}, err
+}
+
+func (d *DBCatalog) InsertAuthor(ctx context.Context, request InsertAuthorRequest) (DocumentAuthor, error) {
+	author := DocumentAuthor{Name: request.Author.Name, Surname: request.Author.Surname}
+	if len(author.Name) == 0 && len(author.Surname) == 0 {
+		return DocumentAuthor{}, errors.New("author name or surname must be defined")
+	}
+
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		if err := tx.Model(&DocumentAuthor{}).Where("name = ? AND surname = ?", author.Name, author.Surname).Count(&existing).Error; err != nil {
+			return err
+		}
+
+		if existing != 0 {
+			return fmt.Errorf("author %s %s already exists", author.Name, author.Surname)
+		}
+
+		err := tx.Model(&AuthorAlias{}).Where("name = ? AND surname = ?", author.Name, author.Surname).Count(&existing).Error
+		if err != nil {
+			return err
+		}
+
+		if existing != 0 {
+			return fmt.Errorf("%s %s is the alias of an existing author", author.Name, author.Surname)
+		}
+
+		return tx.Create(&author).Error
+	})
+
+	return author, err
+}
+
+func (d *DBCatalog) UpdateAuthor(ctx context.Context, request UpdateAuthorRequest) (DocumentAuthor, error) {
+	if len(request.Author.Name) == 0 && len(request.Author.Surname) == 0 {
+		return DocumentAuthor{}, errors.New("author name or surname must be defined")
+	}
+
+	var author DocumentAuthor
+	var documents []Document
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&author, request.AuthorID).Error; err != nil {
+			return err
+		}
+
+		var existing int64
+		err := tx.Model(&DocumentAuthor{}).
+			Where("name = ? AND surname = ? AND id <> ?", request.Author.Name, request.Author.Surname, author.ID).
+			Count(&existing).Error
+		if err != nil {
+			return err
+		}
+
+		if existing != 0 {
+			return fmt.Errorf("author %s %s already exists", request.Author.Name, request.Author.Surname)
+		}
+
+		err = tx.Model(&AuthorAlias{}).
+			Where("name = ? AND surname = ? AND author_id <> ?", request.Author.Name, request.Author.Surname, author.ID).
+			Count(&existing).Error
+		if err != nil {
+			return err
+		}
+
+		if existing != 0 {
+			return fmt.Errorf("%s %s is the alias of another author", request.Author.Name, request.Author.Surname)
+		}
+
+		err = tx.Where("name = ? AND surname = ?", request.Author.Name, request.Author.Surname).Delete(&AuthorAlias{}).Error
+		if err != nil {
+			return err
+		}
+
+		author.Name = request.Author.Name
+		author.Surname = request.Author.Surname
+		if err := tx.Save(&author).Error; err != nil {
+			return err
+		}
+
+		var documentIDs []int
+		if err := tx.Table("document_document_authors").Where("document_author_id = ?", author.ID).Pluck("document_id", &documentIDs).Error; err != nil {
+			return err
+		}
+
+		documents, err = d.reindexDocuments(tx, documentIDs, request.UserID)
+		return err
+	})
+
+	if err != nil {
+		return DocumentAuthor{}, err
+	}
+
+	d.syncSearchIndexes(documents)
+	return author, nil
+}
+
+func (d *DBCatalog) DeleteAuthor(ctx context.Context, request DeleteAuthorRequest) error {
+	var documents []Document
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var documentIDs []int
+		if err := tx.Table("document_document_authors").Where("document_author_id = ?", request.AuthorID).Pluck("document_id", &documentIDs).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Table("document_document_authors").Where("document_author_id = ?", request.AuthorID).Delete(map[string]interface{}{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("author_id = ?", request.AuthorID).Delete(&AuthorAlias{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Unscoped().Delete(&DocumentAuthor{}, request.AuthorID)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		var err error
+		documents, err = d.reindexDocuments(tx, documentIDs, request.UserID)
+		return err
+	})
+
+	if err != nil {
+		return err
+	}
+
+	d.syncSearchIndexes(documents)
+	return nil
+}
+
+// MergeAuthors moves the documents of the merged authors to the surviving one
+// and deletes the merged authors, their spelling keeps resolving to the survivor.
+func (d *DBCatalog) MergeAuthors(ctx context.Context, request MergeAuthorsRequest) (DocumentAuthor, error) {
+	mergedIDs, err := mergedEntityIDs(request.AuthorID, request.MergedIDs)
+	if err != nil {
+		return DocumentAuthor{}, err
+	}
+
+	var author DocumentAuthor
+	var documents []Document
+	err = d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&author, request.AuthorID).Error; err != nil {
+			return err
+		}
+
+		var merged []DocumentAuthor
+		if err := tx.Find(&merged, mergedIDs).Error; err != nil {
+			return err
+		}
+
+		if len(merged) != len(mergedIDs) {
+			return gorm.ErrRecordNotFound
+		}
+
+		var documentIDs []int
+		if err := tx.Table(authorKey.reference).Where("document_author_id IN ?", mergedIDs).Pluck("document_id", &documentIDs).Error; err != nil {
+			return err
+		}
+
+		for _, loser := range merged {
+			if err := moveEntityReferences(tx, authorKey, loser.ID, author.ID); err != nil {
+				return err
+			}
+
+			if err := tx.Model(&AuthorAlias{}).Where("author_id = ?", loser.ID).Update("author_id", author.ID).Error; err != nil {
+				return err
+			}
+
+			alias := AuthorAlias{Name: loser.Name, Surname: loser.Surname, AuthorID: author.ID}
+			if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&alias).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := removeEntityRows(tx, authorKey, mergedIDs); err != nil {
+			return err
+		}
+
+		var err error
+		documents, err = d.reindexDocuments(tx, documentIDs, request.UserID)
+		return err
+	})
+
+	if err != nil {
+		return DocumentAuthor{}, err
+	}
+
+	d.syncSearchIndexes(documents)
+	return author, nil
+}
+
+func (d *DBCatalog) ListKinds(ctx context.Context, request ListKindsRequest) (ListKindsResponse, error) {
+	var totalElements int64
+	if err := d.db.WithContext(ctx).Model(&DocumentKind{}).Count(&totalElements).Error; err != nil {
+		return ListKindsResponse{}, err
+	}
+
+	var kinds []DocumentKind
+	err := d.db.WithContext(ctx).Select("document_kinds.*, " + kindDocumentCount).Order("name").
+		Offset(request.Pagination.Offset()).Limit(request.Pagination.PageSize).
+		Find(&kinds).Error
+
+	return ListKindsResponse{
+		Items:      kinds,
+		Pagination: newPaginationResponse(totalElements, request.Pagination),
+	}, err
+}
+
+func (d *DBCatalog) InsertKind(ctx context.Context, request InsertKindRequest) (DocumentKind, error) {
+	kind := DocumentKind{Name: request.Kind.Name}
+	if len(kind.Name) == 0 {
+		return DocumentKind{}, errors.New("kind name must be defined")
+	}
+
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		if err := tx.Model(&DocumentKind{}).Where("name = ?", kind.Name).Count(&existing).Error; err != nil {
+			return err
+		}
+
+		if existing != 0 {
+			return fmt.Errorf("kind %s already exists", kind.Name)
+		}
+
+		return tx.Create(&kind).Error
+	})
+
+	return kind, err
+}
+
+func (d *DBCatalog) UpdateKind(ctx context.Context, request UpdateKindRequest) (DocumentKind, error) {
+	if len(request.Kind.Name) == 0 {
+		return DocumentKind{}, errors.New("kind name must be defined")
+	}
+
+	var kind DocumentKind
+	var documents []Document
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&kind, request.KindID).Error; err != nil {
+			return err
+		}
+
+		var existing int64
+		if err := tx.Model(&DocumentKind{}).Where("name = ? AND id <> ?", request.Kind.Name, kind.ID).Count(&existing).Error; err != nil {
+			return err
+		}
+
+		if existing != 0 {
+			return fmt.Errorf("kind %s already exists", request.Kind.Name)
+		}
+
+		kind.Name = request.Kind.Name
+		if err := tx.Save(&kind).Error; err != nil {
+			return err
+		}
+
+		var documentIDs []int
+		if err := tx.Model(&Document{}).Where("document_kind_id = ?", kind.ID).Pluck("id", &documentIDs).Error; err != nil {
+			return err
+		}
+
+		var err error
+		documents, err = d.reindexDocuments(tx, documentIDs, request.UserID)
+		return err
+	})
+
+	if err != nil {
+		return DocumentKind{}, err
+	}
+
+	d.syncSearchIndexes(documents)
+	return kind, nil
+}
+
+func (d *DBCatalog) DeleteKind(ctx context.Context, request DeleteKindRequest) error {
+	var documents []Document
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var documentIDs []int
+		if err := tx.Model(&Document{}).Where("document_kind_id = ?", request.KindID).Pluck("id", &documentIDs).Error; err != nil {
+			return err
+		}
+
+		// columns are updated directly to leave updated_at untouched
+		err := tx.Unscoped().Model(&Document{}).Where("document_kind_id = ?", request.KindID).UpdateColumn("document_kind_id", 0).Error
+		if err != nil {
+			return err
+		}
+
+		result := tx.Unscoped().Delete(&DocumentKind{}, request.KindID)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		documents, err = d.reindexDocuments(tx, documentIDs, request.UserID)
+		return err
+	})
+
+	if err != nil {
+		return err
+	}
+
+	d.syncSearchIndexes(documents)
+	return nil
+}
@@ -0,0 +1,279 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"testing"
+)
+
+func TestDBCatalog_ReuseEntities(t *testing.T) {
+	catalog, _ := newTestCatalog(t)
+
+	for _, title := range []string{"effective go", "go memory model"} {
+		err := catalog.InsertDocument(context.TODO(), InsertDocumentRequest{
+			Document: Document{
+				Title:        strptr(title),
+				Uri:          strptr(title),
+				DocumentKind: DocumentKind{Name: "article"},
+				Tags:         []DocumentTag{{Tag: "golang"}, {Tag: "golang"}},
+				Authors:      []DocumentAuthor{{Name: "Rob", Surname: "Pike"}},
+			},
+		})
+		require.NoError(t, err)
+	}
+
+	_, err := catalog.UpdateDocument(context.TODO(), UpdateDocumentRequest{
+		DocumentID: 2,
+		Document:   Document{Tags: []DocumentTag{{Tag: "golang"}, {Tag: "memory"}}, DocumentKind: DocumentKind{Name: "article"}},
+		Partial:    true,
+	})
+	require.NoError(t, err)
+
+	tags, err := catalog.ListTags(context.TODO(), ListTagsRequest{Pagination: PaginationRequest{Page: 1, PageSize: 10}})
+	require.NoError(t, err)
+	require.Len(t, tags.Items, 2)
+	require.Equal(t, "golang", tags.Items[0].Tag)
+	require.Equal(t, int64(2), tags.Items[0].DocumentCount)
+	require.Equal(t, "memory", tags.Items[1].Tag)
+	require.Equal(t, int64(1), tags.Items[1].DocumentCount)
+
+	authors, err := catalog.ListAuthors(context.TODO(), ListAuthorsRequest{Pagination: PaginationRequest{Page: 1, PageSize: 10}})
+	require.NoError(t, err)
+	require.Len(t, authors.Items, 1)
+	require.Equal(t, int64(2), authors.Items[0].DocumentCount)
+
+	kinds, err := catalog.ListKinds(context.TODO(), ListKindsRequest{Pagination: PaginationRequest{Page: 1, PageSize: 10}})
+	require.NoError(t, err)
+	require.Len(t, kinds.Items, 1)
+	require.Equal(t, int64(2), kinds.Items[0].DocumentCount)
+}
+
+func TestDBCatalog_Tags(t *testing.T) {
+	catalog, _ := newTestCatalog(t)
+
+	err := catalog.InsertDocument(context.TODO(), InsertDocumentRequest{
+		Document: Document{Title: strptr("paxos"), Uri: strptr("paxos"), Tags: []DocumentTag{{Tag: "consesus"}}},
+	})
+	require.NoError(t, err)
+
+	tag, err := catalog.InsertTag(context.TODO(), InsertTagRequest{Tag: DocumentTag{Tag: "distributed"}})
+	require.NoError(t, err)
+	require.NotZero(t, tag.ID)
+
+	_, err = catalog.InsertTag(context.TODO(), InsertTagRequest{Tag: DocumentTag{Tag: "distributed"}})
+	require.Error(t, err)
+
+	_, err = catalog.UpdateTag(context.TODO(), UpdateTagRequest{TagID: 1, Tag: DocumentTag{Tag: "distributed"}})
+	require.Error(t, err, "renaming to an existing tag is rejected")
+
+	renamed, err := catalog.UpdateTag(context.TODO(), UpdateTagRequest{TagID: 1, Tag: DocumentTag{Tag: "consensus"}})
+	require.NoError(t, err)
+	require.Equal(t, "consensus", renamed.Tag)
+
+	search := func(query string) int {
+		response, err := catalog.ListDocuments(context.TODO(), ListDocumentsRequest{
+			Query:      query,
+			Pagination: PaginationRequest{Page: 1, PageSize: 10},
+		})
+		require.NoError(t, err)
+		return len(response.Items)
+	}
+	require.Equal(t, 1, search("consensus"), "renamed tags are indexed")
+	require.Equal(t, 0, search("consesus"))
+
+	require.NoError(t, catalog.DeleteTag(context.TODO(), DeleteTagRequest{TagID: 1}))
+	require.Equal(t, 0, search("consensus"))
+
+	document, err := catalog.GetDocument(context.TODO(), GetDocumentRequest{DocumentID: 1})
+	require.NoError(t, err)
+	require.Empty(t, document.Tags)
+
+	require.ErrorIs(t, catalog.DeleteTag(context.TODO(), DeleteTagRequest{TagID: 1}), gorm.ErrRecordNotFound)
+
+	_, err = catalog.InsertTag(context.TODO(), InsertTagRequest{Tag: DocumentTag{Tag: "consensus"}})
+	require.NoError(t, err, "deleted tags can be created again")
+}
+
+func TestDBCatalog_AuthorsAndKinds(t *testing.T) {
+	catalog, db := newTestCatalog(t)
+
+	err := catalog.InsertDocument(context.TODO(), InsertDocumentRequest{
+		Document: Document{
+			Title:        strptr("paxos"),
+			Uri:          strptr("paxos"),
+			DocumentKind: DocumentKind{Name: "paper"},
+			Authors:      []DocumentAuthor{{Name: "Leslie", Surname: "Lampor"}},
+		},
+	})
+	require.NoError(t, err)
+
+	author, err := catalog.UpdateAuthor(context.TODO(), UpdateAuthorRequest{AuthorID: 1, Author: DocumentAuthor{Name: "Leslie", Surname: "Lamport"}})
+	require.NoError(t, err)
+	require.Equal(t, "Lamport", author.Surname)
+
+	_, err = catalog.InsertAuthor(context.TODO(), InsertAuthorRequest{Author: DocumentAuthor{Name: "Leslie", Surname: "Lamport"}})
+	require.Error(t, err)
+
+	kind, err := catalog.InsertKind(context.TODO(), InsertKindRequest{Kind: DocumentKind{Name: "book"}})
+	require.NoError(t, err)
+
+	_, err = catalog.UpdateKind(context.TODO(), UpdateKindRequest{KindID: 1, Kind: DocumentKind{Name: "book"}})
+	require.Error(t, err)
+
+	_, err = catalog.UpdateKind(context.TODO(), UpdateKindRequest{KindID: 1, Kind: DocumentKind{Name: "article"}})
+	require.NoError(t, err)
+
+	response, err := catalog.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Query:      "lamport article",
+		Pagination: PaginationRequest{Page: 1, PageSize: 10},
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Items, 1)
+
+	err = catalog.InsertDocument(context.TODO(), InsertDocumentRequest{
+		Document: Document{Title: strptr("raft"), Uri: strptr("raft"), DocumentKindID: 1},
+	})
+	require.NoError(t, err)
+	require.NoError(t, catalog.DeleteDocument(context.TODO(), DeleteDocumentRequest{DocumentID: 2}))
+
+	require.NoError(t, catalog.DeleteKind(context.TODO(), DeleteKindRequest{KindID: 1}))
+	require.NoError(t, catalog.DeleteAuthor(context.TODO(), DeleteAuthorRequest{AuthorID: 1}))
+
+	var trashed Document
+	require.NoError(t, db.Unscoped().First(&trashed, 2).Error)
+	require.Zero(t, trashed.DocumentKindID, "the trashed documents must not reference the deleted kind")
+
+	document, err := catalog.GetDocument(context.TODO(), GetDocumentRequest{DocumentID: 1})
+	require.NoError(t, err)
+	require.Zero(t, document.DocumentKindID)
+	require.Empty(t, document.Authors)
+
+	kinds, err := catalog.ListKinds(context.TODO(), ListKindsRequest{Pagination: PaginationRequest{Page: 1, PageSize: 10}})
+	require.NoError(t, err)
+	require.Len(t, kinds.Items, 1)
+	require.Equal(t, kind.ID, kinds.Items[0].ID)
+	require.Zero(t, kinds.Items[0].DocumentCount)
+}
+
+func TestDBCatalog_Init_MergeDuplicateEntities(t *testing.T) {
+	catalog, db := newTestCatalog(t)
+
+	// older versions created a row for every tag, author and kind inserted
+	require.NoError(t, db.Migrator().DropIndex(&DocumentTag{}, "idx_document_tags_tag"))
+	require.NoError(t, db.Migrator().DropIndex(&DocumentKind{}, "idx_document_kinds_name"))
+	for i, title := range []string{"a", "b", "c"} {
+		require.NoError(t, db.Create(&DocumentTag{Tag: "golang"}).Error)
+		require.NoError(t, db.Create(&DocumentKind{Name: "article"}).Error)
+		require.NoError(t, db.Create(&Document{Title: strptr(title), Uri: strptr(title), DocumentKindID: i + 1}).Error)
+	}
+	require.NoError(t, db.Exec("INSERT INTO document_document_tags (document_id, document_tag_id) VALUES (1, 1), (2, 2), (3, 3), (3, 1)").Error)
+
+	err := catalog.Init()
+	require.NoError(t, err)
+
+	tags, err := catalog.ListTags(context.TODO(), ListTagsRequest{Pagination: PaginationRequest{Page: 1, PageSize: 10}})
+	require.NoError(t, err)
+	require.Len(t, tags.Items, 1)
+	require.Equal(t, uint(1), tags.Items[0].ID)
+	require.Equal(t, int64(3), tags.Items[0].DocumentCount)
+
+	kinds, err := catalog.ListKinds(context.TODO(), ListKindsRequest{Pagination: PaginationRequest{Page: 1, PageSize: 10}})
+	require.NoError(t, err)
+	require.Len(t, kinds.Items, 1)
+	require.Equal(t, int64(3), kinds.Items[0].DocumentCount)
+
+	require.True(t, db.Migrator().HasIndex(&DocumentTag{}, "idx_document_tags_tag"))
+	require.Error(t, db.Create(&DocumentTag{Tag: "golang"}).Error)
+}
+
+func TestDBCatalog_MergeTags(t *testing.T) {
+	catalog, _ := newTestCatalog(t)
+
+	for i, tags := range [][]DocumentTag{{{Tag: "golang"}}, {{Tag: "Go"}, {Tag: "golang"}}, {{Tag: "go-lang"}}} {
+		err := catalog.InsertDocument(context.TODO(), InsertDocumentRequest{
+			Document: Document{Title: strptr("doc"), Uri: strptr(fmt.Sprintf("doc %d", i)), Tags: tags},
+		})
+		require.NoError(t, err)
+	}
+
+	_, err := catalog.MergeTags(context.TODO(), MergeTagsRequest{TagID: 1, MergedIDs: []uint{1}})
+	require.Error(t, err)
+
+	_, err = catalog.MergeTags(context.TODO(), MergeTagsRequest{TagID: 1, MergedIDs: []uint{42}})
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	tag, err := catalog.MergeTags(context.TODO(), MergeTagsRequest{TagID: 1, MergedIDs: []uint{2}})
+	require.NoError(t, err)
+	require.Equal(t, "golang", tag.Tag)
+
+	// aliases of a merged tag follow it into the survivor
+	_, err = catalog.MergeTags(context.TODO(), MergeTagsRequest{TagID: 3, MergedIDs: []uint{1}})
+	require.NoError(t, err)
+
+	tags, err := catalog.ListTags(context.TODO(), ListTagsRequest{Pagination: PaginationRequest{Page: 1, PageSize: 10}})
+	require.NoError(t, err)
+	require.Len(t, tags.Items, 1)
+	require.Equal(t, "go-lang", tags.Items[0].Tag)
+	require.Equal(t, int64(3), tags.Items[0].DocumentCount)
+	require.Equal(t, []string{"Go", "golang"}, tags.Items[0].Aliases)
+
+	err = catalog.InsertDocument(context.TODO(), InsertDocumentRequest{
+		Document: Document{Title: strptr("new"), Uri: strptr("new"), Tags: []DocumentTag{{Tag: "Go"}}},
+	})
+	require.NoError(t, err)
+
+	document, err := catalog.GetDocument(context.TODO(), GetDocumentRequest{DocumentID: 4})
+	require.NoError(t, err)
+	require.Len(t, document.Tags, 1)
+	require.Equal(t, "go-lang", document.Tags[0].Tag, "aliases resolve to the surviving tag")
+
+	_, err = catalog.InsertTag(context.TODO(), InsertTagRequest{Tag: DocumentTag{Tag: "golang"}})
+	require.Error(t, err)
+
+	response, err := catalog.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Tags:       []string{"go-lang"},
+		Pagination: PaginationRequest{Page: 1, PageSize: 10},
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Items, 4)
+
+	renamed, err := catalog.UpdateTag(context.TODO(), UpdateTagRequest{TagID: 3, Tag: DocumentTag{Tag: "golang"}})
+	require.NoError(t, err)
+	require.Equal(t, "golang", renamed.Tag, "a tag can take back the spelling of its aliases")
+}
+
+func TestDBCatalog_MergeAuthors(t *testing.T) {
+	catalog, _ := newTestCatalog(t)
+
+	for i, author := range []DocumentAuthor{{Name: "Leslie", Surname: "Lamport"}, {Name: "L.", Surname: "Lamport"}} {
+		err := catalog.InsertDocument(context.TODO(), InsertDocumentRequest{
+			Document: Document{Title: strptr("paxos"), Uri: strptr(fmt.Sprintf("paxos %d", i)), Authors: []DocumentAuthor{author}},
+		})
+		require.NoError(t, err)
+	}
+
+	author, err := catalog.MergeAuthors(context.TODO(), MergeAuthorsRequest{AuthorID: 1, MergedIDs: []uint{2, 2}})
+	require.NoError(t, err)
+	require.Equal(t, "Leslie", author.Name)
+
+	authors, err := catalog.ListAuthors(context.TODO(), ListAuthorsRequest{Pagination: PaginationRequest{Page: 1, PageSize: 10}})
+	require.NoError(t, err)
+	require.Len(t, authors.Items, 1)
+	require.Equal(t, int64(2), authors.Items[0].DocumentCount)
+	require.Equal(t, []AuthorAlias{{Name: "L.", Surname: "Lamport", AuthorID: 1}}, authors.Items[0].Aliases)
+
+	err = catalog.InsertDocument(context.TODO(), InsertDocumentRequest{
+		Document: Document{Title: strptr("clocks"), Uri: strptr("clocks"), Authors: []DocumentAuthor{{Name: "L.", Surname: "Lamport"}}},
+	})
+	require.NoError(t, err)
+
+	document, err := catalog.GetDocument(context.TODO(), GetDocumentRequest{DocumentID: 3})
+	require.NoError(t, err)
+	require.Equal(t, uint(1), document.Authors[0].ID)
+
+	require.NoError(t, catalog.DeleteAuthor(context.TODO(), DeleteAuthorRequest{AuthorID: 1}))
+	_, err = catalog.InsertAuthor(context.TODO(), InsertAuthorRequest{Author: DocumentAuthor{Name: "L.", Surname: "Lamport"}})
+	require.NoError(t, err, "deleting an author drops its aliases")
+}
@@ -6,10 +6,29 @@ import (
 	"github.com/stretchr/testify/require"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"os"
 	"path"
 	"testing"
 )
 
+// newTestCatalog opens an initialized catalog on a temporary sqlite database,
+// closed at the end of the test.
+func newTestCatalog(t *testing.T, opts ...DBCatalogOption) (*DBCatalog, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(path.Join(t.TempDir(), "catalog.db")), &gorm.Config{
+		DisableForeignKeyConstraintWhenMigrating: true,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		dbi, err := db.DB()
+		require.NoError(t, err)
+		require.NoError(t, dbi.Close())
+	})
+
+	catalog := NewDBCatalog(db, opts...)
+	require.NoError(t, catalog.Init())
+	return catalog, db
+}
+
 func TestDBCatalog_Init(t *testing.T) {
 	tmpDb := path.Join(t.TempDir(), t.Name())
 	db, err := gorm.Open(sqlite.Open(tmpDb), &gorm.Config{
@@ -64,7 +83,7 @@ func TestDBCatalog_InsertDocument(t *testing.T) {
 		{
 			Document: Document{
 				Title: strptr("Test Title"),
-				Uri:   strptr("file://test.txt"),
+				Uri:   strptr("file://test-2.txt"),
 				DocumentKind: DocumentKind{
 					Name: "file",
 				},
@@ -77,7 +96,7 @@ func TestDBCatalog_InsertDocument(t *testing.T) {
 		{
 			Document: Document{
 				Title: strptr("Test Title"),
-				Uri:   strptr("file://test.txt"),
+				Uri:   strptr("file://test-3.txt"),
 				DocumentKind: DocumentKind{
 					Name: "file",
 				},
@@ -119,6 +138,78 @@ func TestDBCatalog_InsertDocument_Invalid(t *testing.T) {
 	}
 }
 
+func TestDBCatalog_InsertDocument_Duplicate(t *testing.T) {
+	catalog, db := newTestCatalog(t)
+
+	err := catalog.InsertDocument(context.TODO(), InsertDocumentRequest{Document: Document{
+		Title:   strptr("Paxos Made Simple"),
+		Uri:     strptr("https://lamport.azurewebsites.net/pubs/paxos-simple.pdf"),
+		Tags:    []DocumentTag{{Tag: "consensus"}},
+		Authors: []DocumentAuthor{{Name: "Leslie", Surname: "Lamport"}},
+	}})
+	require.NoError(t, err)
+
+	duplicate := Document{
+		Title:   strptr("Paxos"),
+		Uri:     strptr("HTTPS://Lamport.azurewebsites.net/pubs/paxos-simple.pdf/?utm_source=feed#page=2"),
+		Tags:    []DocumentTag{{Tag: "consensus"}, {Tag: "distributed"}},
+		Authors: []DocumentAuthor{{Name: "L.", Surname: "Lamport"}},
+	}
+
+	err = catalog.InsertDocument(context.TODO(), InsertDocumentRequest{Document: duplicate})
+	var duplicateErr *DuplicateDocumentError
+	require.ErrorAs(t, err, &duplicateErr)
+	require.Equal(t, 1, duplicateErr.DocumentID)
+
+	err = catalog.InsertDocument(context.TODO(), InsertDocumentRequest{Document: duplicate, Upsert: true})
+	require.NoError(t, err)
+
+	document, err := catalog.GetDocument(context.TODO(), GetDocumentRequest{DocumentID: 1})
+	require.NoError(t, err)
+	require.Equal(t, "Paxos Made Simple", *document.Title, "upserts only merge the tags and authors")
+	require.Len(t, document.Tags, 2)
+	require.Len(t, document.Authors, 2)
+
+	listed, err := catalog.ListDocuments(context.TODO(), ListDocumentsRequest{Pagination: PaginationRequest{Page: 1, PageSize: 10}})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, listed.Pagination.TotalElements)
+
+	err = catalog.InsertDocument(context.TODO(), InsertDocumentRequest{Document: Document{
+		Title: strptr("Time, Clocks, and the Ordering of Events in a Distributed System"),
+		Uri:   strptr("https://lamport.azurewebsites.net/pubs/time-clocks.pdf"),
+	}})
+	require.NoError(t, err)
+
+	_, err = catalog.UpdateDocument(context.TODO(), UpdateDocumentRequest{
+		DocumentID: 2,
+		Document:   Document{Uri: strptr("https://lamport.azurewebsites.net/pubs/paxos-simple.pdf#abstract")},
+		Partial:    true,
+	})
+	require.ErrorAs(t, err, &duplicateErr)
+	require.Equal(t, 1, duplicateErr.DocumentID)
+
+	_, err = catalog.UpdateDocument(context.TODO(), UpdateDocumentRequest{
+		DocumentID: 1,
+		Document:   Document{Uri: strptr("https://lamport.azurewebsites.net/pubs/paxos-simple.pdf?utm_medium=email")},
+		Partial:    true,
+	})
+	require.NoError(t, err, "a document does not conflict with itself")
+
+	require.NoError(t, catalog.DeleteDocument(context.TODO(), DeleteDocumentRequest{DocumentID: 1}))
+	err = catalog.InsertDocument(context.TODO(), InsertDocumentRequest{Document: duplicate})
+	require.NoError(t, err, "the uri of a deleted document can be used again")
+
+	// documents inserted by older versions are left without key when duplicated
+	require.NoError(t, db.Exec("UPDATE documents SET uri = ?, uri_key = NULL WHERE id IN (2, 3)", "https://example.com").Error)
+	require.NoError(t, catalog.Init())
+
+	var documents []Document
+	require.NoError(t, db.Order("id").Find(&documents).Error)
+	require.Len(t, documents, 2)
+	require.NotNil(t, documents[0].UriKey)
+	require.Nil(t, documents[1].UriKey)
+}
+
 func TestDBCatalog_ListDocuments(t *testing.T) {
 	tmpDb := path.Join(t.TempDir(), t.Name())
 	db, err := gorm.Open(sqlite.Open(tmpDb), &gorm.Config{
@@ -169,10 +260,12 @@ func TestDBCatalog_ListDocuments(t *testing.T) {
 	require.Equal(t, "Test Title 0", *documents.Items[0].Title)
 	require.Equal(t, "Test Title 1", *documents.Items[1].Title)
 
+	require.NotEmpty(t, documents.Pagination.NextCursor)
 	require.Equal(t, PaginationResponse{
 		TotalElements: documentsCount,
 		Page:          1,
 		Pages:         documentsCount / 2,
+		NextCursor:    documents.Pagination.NextCursor,
 	}, documents.Pagination)
 
 	for _, doc := range documents.Items {
@@ -204,6 +297,242 @@ func TestDBCatalog_ListDocuments(t *testing.T) {
 	require.Len(t, documents.Items, 1, "tag only search must yield only 1 result")
 }
 
+func TestDBCatalog_ListDocuments_TagSets(t *testing.T) {
+	catalog, _ := newTestCatalog(t)
+
+	documents := map[string][]string{
+		"raft":   {"consensus", "distributed", "paper"},
+		"paxos":  {"consensus", "paper", "draft"},
+		"ddia":   {"distributed", "databases/postgres"},
+		"vacuum": {"databases/postgres/vacuum", "paper"},
+	}
+	for _, title := range []string{"raft", "paxos", "ddia", "vacuum"} {
+		var tags []DocumentTag
+		for _, tag := range documents[title] {
+			tags = append(tags, DocumentTag{Tag: tag})
+		}
+		err := catalog.InsertDocument(context.TODO(), InsertDocumentRequest{
+			Document: Document{Title: strptr(title), Uri: strptr(title), Tags: tags},
+		})
+		require.NoError(t, err)
+	}
+
+	list := func(request ListDocumentsRequest) []string {
+		request.Pagination = PaginationRequest{Page: 1, PageSize: 10}
+		response, err := catalog.ListDocuments(context.TODO(), request)
+		require.NoError(t, err)
+		require.EqualValues(t, len(response.Items), response.Pagination.TotalElements)
+
+		titles := []string{}
+		for _, document := range response.Items {
+			titles = append(titles, *document.Title)
+		}
+		return titles
+	}
+
+	require.Equal(t, []string{"ddia", "paxos", "raft", "vacuum"}, list(ListDocumentsRequest{TagsAny: []string{"consensus", "paper", "databases"}}))
+	require.Equal(t, []string{"paxos", "raft"}, list(ListDocumentsRequest{TagsAll: []string{"consensus", "paper", "consensus"}}))
+	require.Equal(t, []string{"raft"}, list(ListDocumentsRequest{TagsAll: []string{"consensus", "distributed"}}))
+	require.Equal(t, []string{"vacuum"}, list(ListDocumentsRequest{TagsAll: []string{"databases", "paper"}}))
+	require.Empty(t, list(ListDocumentsRequest{TagsAll: []string{"consensus", "unknown"}}))
+	require.Equal(t, []string{"ddia", "vacuum"}, list(ListDocumentsRequest{TagsNone: []string{"consensus"}}))
+	require.Equal(t, []string{"raft"}, list(ListDocumentsRequest{
+		TagsAll:  []string{"paper"},
+		TagsAny:  []string{"consensus", "distributed"},
+		TagsNone: []string{"draft", "databases"},
+	}))
+}
+
+func TestDBCatalog_ListDocuments_Sort(t *testing.T) {
+	catalog, db := newTestCatalog(t)
+
+	documents := []Document{
+		{Title: strptr("b"), Uri: strptr("1"), DocumentKind: DocumentKind{Name: "paper"}},
+		{Title: strptr("a"), Uri: strptr("2"), DocumentKind: DocumentKind{Name: "book"}},
+		{Title: strptr("b"), Uri: strptr("3"), DocumentKind: DocumentKind{Name: "book"}},
+		{Title: strptr("c"), Uri: strptr("4")},
+	}
+	for i, document := range documents {
+		require.NoError(t, catalog.InsertDocument(context.TODO(), InsertDocumentRequest{Document: document}))
+		require.NoError(t, db.Model(&Document{}).Where("id = ?", i+1).UpdateColumn("create_time", 100-i).Error)
+	}
+
+	list := func(pagination PaginationRequest, sort ...Sort) []string {
+		response, err := catalog.ListDocuments(context.TODO(), ListDocumentsRequest{Sort: sort, Pagination: pagination})
+		require.NoError(t, err)
+
+		var uris []string
+		for _, document := range response.Items {
+			uris = append(uris, *document.Uri)
+		}
+		return uris
+	}
+	all := PaginationRequest{Page: 1, PageSize: 10}
+
+	require.Equal(t, []string{"2", "1", "3", "4"}, list(all), "ties must be broken by id")
+	require.Equal(t, []string{"4", "1", "3", "2"}, list(all, Sort{Field: SortTitle, Descending: true}))
+	require.Equal(t, []string{"4", "3", "2", "1"}, list(all, Sort{Field: SortCreateTime}))
+	require.Equal(t, []string{"4", "2", "3", "1"}, list(all, Sort{Field: SortKind}, Sort{Field: SortTitle}))
+	require.Equal(t, []string{"1", "3", "2", "4"}, list(all, Sort{Field: SortKind, Descending: true}, Sort{Field: SortCreateTime}))
+
+	var paged []string
+	for page := 1; page <= 4; page++ {
+		paged = append(paged, list(PaginationRequest{Page: page, PageSize: 1}, Sort{Field: SortTitle})...)
+	}
+	require.Equal(t, []string{"2", "1", "3", "4"}, paged, "pages must not overlap")
+
+	_, err := catalog.ListDocuments(context.TODO(), ListDocumentsRequest{Sort: []Sort{{Field: SortRelevance}}, Pagination: all})
+	require.Error(t, err)
+}
+
+func TestDBCatalog_ListDocuments_Cursor(t *testing.T) {
+	catalog, _ := newTestCatalog(t)
+
+	kinds := []string{"paper", "book", ""}
+	insert := func(title string, i int) {
+		err := catalog.InsertDocument(context.TODO(), InsertDocumentRequest{
+			Document: Document{Title: strptr(title), Uri: strptr(fmt.Sprintf("%s-%d", title, i)), DocumentKind: DocumentKind{Name: kinds[i%len(kinds)]}},
+		})
+		require.NoError(t, err)
+	}
+	for i := 0; i < 10; i++ {
+		insert(fmt.Sprintf("title %d", i%4), i)
+	}
+
+	list := func(request ListDocumentsRequest) []string {
+		request.Pagination = PaginationRequest{Page: 1, PageSize: 20}
+		response, err := catalog.ListDocuments(context.TODO(), request)
+		require.NoError(t, err)
+		require.Empty(t, response.Pagination.NextCursor)
+
+		var uris []string
+		for _, document := range response.Items {
+			uris = append(uris, *document.Uri)
+		}
+		return uris
+	}
+
+	follow := func(request ListDocumentsRequest) []string {
+		var uris []string
+		request.Pagination = PaginationRequest{Page: 1, PageSize: 3}
+		for {
+			response, err := catalog.ListDocuments(context.TODO(), request)
+			require.NoError(t, err)
+			require.LessOrEqual(t, len(response.Items), 3)
+
+			for _, document := range response.Items {
+				uris = append(uris, *document.Uri)
+			}
+			if len(response.Pagination.NextCursor) == 0 {
+				return uris
+			}
+			request.Pagination.Cursor = response.Pagination.NextCursor
+			request.Pagination.SkipCount = true
+		}
+	}
+
+	for _, sort := range [][]Sort{
+		nil,
+		{{Field: SortTitle, Descending: true}},
+		{{Field: SortKind}, {Field: SortTitle, Descending: true}},
+		{{Field: SortUpdatedAt, Descending: true}},
+	} {
+		request := ListDocumentsRequest{Sort: sort}
+		require.Equal(t, list(request), follow(request), "the cursor pages must follow the sort %v", sort)
+	}
+
+	collection, err := catalog.InsertCollection(context.TODO(), InsertCollectionRequest{
+		Collection: Collection{Name: "reading", Items: []CollectionItem{{DocumentID: 9}, {DocumentID: 2}, {DocumentID: 5}, {DocumentID: 1}}},
+	})
+	require.NoError(t, err)
+	request := ListDocumentsRequest{Collection: collection.ID}
+	require.Equal(t, []string{"title 0-8", "title 1-1", "title 0-4", "title 0-0"}, follow(request))
+
+	for _, pagination := range []PaginationRequest{{Page: 1}, {Page: 0, PageSize: 4}, {Page: 1, PageSize: -1}} {
+		_, err = catalog.ListDocuments(context.TODO(), ListDocumentsRequest{Pagination: pagination})
+		require.ErrorIs(t, err, ErrInvalidPagination, "%+v", pagination)
+	}
+
+	response, err := catalog.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Pagination: PaginationRequest{Page: 1, PageSize: 4},
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 10, response.Pagination.TotalElements)
+	first := response.Items
+
+	// the documents inserted before the cursor do not shift the next pages
+	insert("a", 10)
+	response, err = catalog.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Pagination: PaginationRequest{PageSize: 4, Cursor: response.Pagination.NextCursor, SkipCount: true},
+	})
+	require.NoError(t, err)
+	require.Zero(t, response.Pagination.TotalElements)
+	require.Zero(t, response.Pagination.Page)
+	require.Len(t, response.Items, 4)
+	require.Equal(t, "title 1-1", *first[3].Uri)
+	require.Equal(t, "title 1-5", *response.Items[0].Uri)
+
+	_, err = catalog.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Sort:       []Sort{{Field: SortKind}},
+		Pagination: PaginationRequest{PageSize: 4, Cursor: response.Pagination.NextCursor},
+	})
+	require.Error(t, err, "cursors cannot be used with another sort")
+
+	_, err = catalog.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Pagination: PaginationRequest{PageSize: 4, Cursor: "not a cursor"},
+	})
+	require.Error(t, err)
+}
+
+func TestDBCatalog_ListDocuments_Facets(t *testing.T) {
+	catalog, _ := newTestCatalog(t)
+
+	documents := searchTestDocuments()
+	documents = append(documents, Document{
+		Title:        strptr("The Part-Time Parliament"),
+		Uri:          strptr("https://example.com/parliament"),
+		DocumentKind: DocumentKind{Name: "paper"},
+		Authors:      []DocumentAuthor{{Name: "Leslie", Surname: "Lamport"}},
+		Tags:         []DocumentTag{{Tag: "consensus"}},
+	})
+	for _, document := range documents {
+		err := catalog.InsertDocument(context.TODO(), InsertDocumentRequest{Document: document})
+		require.NoError(t, err)
+	}
+	require.NoError(t, catalog.DeleteDocument(context.TODO(), DeleteDocumentRequest{DocumentID: 1}))
+
+	response, err := catalog.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Facets:     []Facet{FacetKind, FacetAuthors, FacetTags},
+		Pagination: PaginationRequest{Page: 1, PageSize: 1},
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Items, 1, "facets must not depend on the page")
+
+	kinds := response.Facets[FacetKind]
+	require.Len(t, kinds, 2)
+	require.Equal(t, "paper", kinds[0].Value)
+	require.EqualValues(t, 2, kinds[0].Count, "deleted documents must not be counted")
+	require.Equal(t, "book", kinds[1].Value)
+	require.EqualValues(t, 1, kinds[1].Count)
+
+	authors := response.Facets[FacetAuthors]
+	require.Equal(t, "Leslie Lamport", authors[0].Value)
+	require.EqualValues(t, 2, authors[0].Count)
+
+	tags := response.Facets[FacetTags]
+	require.Equal(t, "consensus", tags[0].Value)
+	require.EqualValues(t, 2, tags[0].Count)
+
+	response, err = catalog.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Title:      "Paxos",
+		Facets:     []Facet{FacetKind},
+		Pagination: PaginationRequest{Page: 1, PageSize: 10},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []FacetBucket{{ID: kinds[0].ID, Value: "paper", Count: 1}}, response.Facets[FacetKind])
+	require.NotContains(t, response.Facets, FacetTags)
+}
+
 func TestDBCatalog_GetDocument(t *testing.T) {
 	tmpDb := path.Join(t.TempDir(), t.Name())
 	db, err := gorm.Open(sqlite.Open(tmpDb), &gorm.Config{
@@ -258,3 +587,371 @@ func TestDBCatalog_GetDocument(t *testing.T) {
 	})
 	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
 }
+
+func TestDBCatalog_UpdateDocument(t *testing.T) {
+	catalog, _ := newTestCatalog(t)
+
+	err := catalog.InsertDocument(context.TODO(), InsertDocumentRequest{
+		Document: Document{
+			Title: strptr("Test Tilte"),
+			Uri:   strptr("file://test.txt"),
+			DocumentKind: DocumentKind{
+				Name: "file",
+			},
+			Authors: []DocumentAuthor{
+				{Name: "Me", Surname: "Me"},
+			},
+			Tags: []DocumentTag{
+				{Tag: "test"},
+				{Tag: "book"},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	document, err := catalog.UpdateDocument(context.TODO(), UpdateDocumentRequest{
+		DocumentID: 1,
+		Document: Document{
+			Title: strptr("Test Title"),
+		},
+		Partial: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Test Title", *document.Title)
+	require.Equal(t, "file://test.txt", *document.Uri)
+	require.Equal(t, "file", document.DocumentKind.Name)
+	require.Len(t, document.Tags, 2)
+	require.Len(t, document.Authors, 1)
+
+	document, err = catalog.UpdateDocument(context.TODO(), UpdateDocumentRequest{
+		DocumentID: 1,
+		Document: Document{
+			Title: strptr("Replaced Title"),
+			Uri:   strptr("https://example.com"),
+			DocumentKind: DocumentKind{
+				Name: "web",
+			},
+			Tags: []DocumentTag{
+				{Tag: "web"},
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Replaced Title", *document.Title)
+	require.Equal(t, "https://example.com", *document.Uri)
+	require.Equal(t, "web", document.DocumentKind.Name)
+	require.Len(t, document.Tags, 1)
+	require.Equal(t, "web", document.Tags[0].Tag)
+	require.Empty(t, document.Authors)
+
+	stored, err := catalog.GetDocument(context.TODO(), GetDocumentRequest{DocumentID: 1})
+	require.NoError(t, err)
+	require.Equal(t, *document.Title, *stored.Title)
+	require.Len(t, stored.Tags, 1)
+	require.Empty(t, stored.Authors)
+
+	_, err = catalog.UpdateDocument(context.TODO(), UpdateDocumentRequest{
+		DocumentID: 9999,
+		Document: Document{
+			Title: strptr("Test Title"),
+		},
+		Partial: true,
+	})
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestDBCatalog_DeleteDocument(t *testing.T) {
+	catalog, _ := newTestCatalog(t)
+
+	err := catalog.InsertDocument(context.TODO(), InsertDocumentRequest{
+		Document: Document{
+			Title: strptr("Test Title"),
+			Uri:   strptr("file://test.txt"),
+		},
+	})
+	require.NoError(t, err)
+
+	err = catalog.DeleteDocument(context.TODO(), DeleteDocumentRequest{DocumentID: 1})
+	require.NoError(t, err)
+
+	_, err = catalog.GetDocument(context.TODO(), GetDocumentRequest{DocumentID: 1})
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	err = catalog.DeleteDocument(context.TODO(), DeleteDocumentRequest{DocumentID: 1})
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestDBCatalog_ListDocuments_Query(t *testing.T) {
+	catalog, _ := newTestCatalog(t)
+
+	documents := searchTestDocuments()
+	for _, document := range documents {
+		err := catalog.InsertDocument(context.TODO(), InsertDocumentRequest{Document: document})
+		require.NoError(t, err)
+	}
+
+	pagination := PaginationRequest{Page: 1, PageSize: 10}
+
+	response, err := catalog.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Query:      "lamport clocks",
+		Pagination: pagination,
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Items, 1)
+	require.Equal(t, int64(1), response.Pagination.TotalElements)
+	require.Equal(t, *documents[0].Title, *response.Items[0].Title)
+	require.Contains(t, response.Items[0].Snippet, "<mark>Clocks</mark>")
+	require.NotEmpty(t, response.Items[0].Tags)
+
+	response, err = catalog.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Query:      "lamport",
+		Pagination: pagination,
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Items, 2)
+
+	response, err = catalog.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Query:      "book",
+		Pagination: pagination,
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Items, 1, "kind must be searchable")
+	require.Equal(t, *documents[2].Title, *response.Items[0].Title)
+
+	_, err = catalog.UpdateDocument(context.TODO(), UpdateDocumentRequest{
+		DocumentID: 3,
+		Document:   Document{Tags: []DocumentTag{{Tag: "clocks"}}},
+		Partial:    true,
+	})
+	require.NoError(t, err)
+
+	response, err = catalog.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Query:      "clocks",
+		Pagination: pagination,
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Items, 2, "updated tags must be searchable")
+	require.Equal(t, *documents[0].Title, *response.Items[0].Title, "title matches must rank first")
+
+	response, err = catalog.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Query:      "clocks",
+		Sort:       []Sort{{Field: SortRelevance, Descending: true}},
+		Pagination: pagination,
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Items, 2)
+	require.Equal(t, *documents[0].Title, *response.Items[1].Title, "the least relevant documents must come first")
+
+	err = catalog.DeleteDocument(context.TODO(), DeleteDocumentRequest{DocumentID: 1})
+	require.NoError(t, err)
+
+	response, err = catalog.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Query:      "clocks",
+		Pagination: pagination,
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Items, 1, "deleted documents must not be searchable")
+
+	response, err = catalog.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Query:      "\"*",
+		Pagination: pagination,
+	})
+	require.NoError(t, err)
+	require.Empty(t, response.Items)
+}
+
+func TestDBCatalog_Init_Reindex(t *testing.T) {
+	catalog, db := newTestCatalog(t)
+
+	for i := 0; i < 150; i++ {
+		err := catalog.InsertDocument(context.TODO(), InsertDocumentRequest{
+			Document: Document{
+				Title: strptr(fmt.Sprintf("Document %d", i)),
+				Uri:   strptr(""),
+			},
+		})
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, db.Exec("DROP TABLE document_search").Error)
+
+	catalog = NewDBCatalog(db)
+	err := catalog.Init()
+	require.NoError(t, err)
+
+	response, err := catalog.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Query:      "document",
+		Pagination: PaginationRequest{Page: 1, PageSize: 10},
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(150), response.Pagination.TotalElements)
+}
+
+func TestDBCatalog_ListDocuments_SearchIndex(t *testing.T) {
+	indexPath := path.Join(t.TempDir(), "search.idx")
+	catalog, db := newTestCatalog(t, WithSearchIndex(indexPath))
+	require.False(t, db.Migrator().HasTable("document_search"), "database full text index must not be used")
+
+	documents := searchTestDocuments()
+	for _, document := range documents {
+		err := catalog.InsertDocument(context.TODO(), InsertDocumentRequest{Document: document})
+		require.NoError(t, err)
+	}
+
+	pagination := PaginationRequest{Page: 1, PageSize: 10}
+
+	response, err := catalog.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Query:      "lamport clocks",
+		Pagination: pagination,
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Items, 1)
+	require.Equal(t, int64(1), response.Pagination.TotalElements)
+	require.Equal(t, *documents[0].Title, *response.Items[0].Title)
+	require.Contains(t, response.Items[0].Snippet, "<mark>Clocks,</mark>")
+	require.NotEmpty(t, response.Items[0].Tags)
+
+	response, err = catalog.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Query:      "lamport",
+		Tags:       []string{"consensus"},
+		Pagination: pagination,
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Items, 1, "search must be combined with the other filters")
+	require.Equal(t, *documents[1].Title, *response.Items[0].Title)
+
+	response, err = catalog.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Query:      "lamport",
+		Pagination: PaginationRequest{Page: 2, PageSize: 1},
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Items, 1)
+	require.Equal(t, PaginationResponse{TotalElements: 2, Page: 2, Pages: 2}, response.Pagination)
+
+	first, err := catalog.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Query:      "lamport",
+		Pagination: PaginationRequest{Page: 1, PageSize: 1},
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, first.Pagination.NextCursor)
+
+	next, err := catalog.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Query:      "lamport",
+		Pagination: PaginationRequest{PageSize: 1, Cursor: first.Pagination.NextCursor},
+	})
+	require.NoError(t, err)
+	require.Equal(t, response.Items[0].ID, next.Items[0].ID, "search cursors must follow the ranks")
+	require.Empty(t, next.Pagination.NextCursor)
+
+	response, err = catalog.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Query:      "lamport",
+		Facets:     []Facet{FacetKind, FacetAuthors},
+		Pagination: PaginationRequest{Page: 1, PageSize: 1},
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Facets[FacetKind], 1)
+	require.Equal(t, "paper", response.Facets[FacetKind][0].Value)
+	require.EqualValues(t, 2, response.Facets[FacetKind][0].Count, "facets must count all the hits")
+	require.Equal(t, "Leslie Lamport", response.Facets[FacetAuthors][0].Value)
+
+	response, err = catalog.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Query:      "lamport",
+		Sort:       []Sort{{Field: SortTitle}},
+		Pagination: pagination,
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Items, 2)
+	require.Equal(t, *documents[1].Title, *response.Items[0].Title, "hits must be sorted by title")
+
+	relevant, err := catalog.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Query:      "lamport",
+		Pagination: pagination,
+	})
+	require.NoError(t, err)
+
+	response, err = catalog.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Query:      "lamport",
+		Sort:       []Sort{{Field: SortKind}, {Field: SortRelevance, Descending: true}},
+		Pagination: pagination,
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Items, 2)
+	require.Equal(t, *relevant.Items[1].Title, *response.Items[0].Title, "the least relevant hits must come first")
+
+	err = catalog.DeleteDocument(context.TODO(), DeleteDocumentRequest{DocumentID: 1})
+	require.NoError(t, err)
+
+	_, err = catalog.UpdateDocument(context.TODO(), UpdateDocumentRequest{
+		DocumentID: 3,
+		Document:   Document{Title: strptr("The Rust Programming Language")},
+		Partial:    true,
+	})
+	require.NoError(t, err)
+
+	// the changes are not saved yet, the index is rebuilt on start
+	reopened := NewDBCatalog(db, WithSearchIndex(indexPath))
+	err = reopened.Init()
+	require.NoError(t, err)
+
+	response, err = reopened.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Query:      "lamport",
+		Pagination: pagination,
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Items, 1, "deletes must be persisted")
+
+	response, err = reopened.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Query:      "rust",
+		Pagination: pagination,
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Items, 1, "updates must be persisted")
+
+	require.NoError(t, catalog.SaveSearchIndex())
+	require.NoFileExists(t, indexPath+".dirty")
+	reopened = NewDBCatalog(db, WithSearchIndex(indexPath))
+	require.NoError(t, reopened.Init())
+	response, err = reopened.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Query:      "rust",
+		Pagination: pagination,
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Items, 1, "saved changes must be loaded")
+
+	require.NoError(t, os.Remove(indexPath))
+	require.NoError(t, reopened.Reindex(context.TODO()))
+
+	response, err = reopened.ListDocuments(context.TODO(), ListDocumentsRequest{
+		Query:      "programming",
+		Pagination: pagination,
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Items, 1)
+	require.FileExists(t, indexPath)
+}
+
+func searchTestDocuments() []Document {
+	return []Document{
+		{
+			Title:        strptr("Time, Clocks, and the Ordering of Events in a Distributed System"),
+			Uri:          strptr("https://lamport.azurewebsites.net/pubs/time-clocks.pdf"),
+			DocumentKind: DocumentKind{Name: "paper"},
+			Authors:      []DocumentAuthor{{Name: "Leslie", Surname: "Lamport"}},
+			Tags:         []DocumentTag{{Tag: "distributed"}},
+		},
+		{
+			Title:        strptr("Paxos Made Simple"),
+			Uri:          strptr("https://lamport.azurewebsites.net/pubs/paxos-simple.pdf"),
+			DocumentKind: DocumentKind{Name: "paper"},
+			Authors:      []DocumentAuthor{{Name: "Leslie", Surname: "Lamport"}},
+			Tags:         []DocumentTag{{Tag: "consensus"}},
+		},
+		{
+			Title:        strptr("The Go Programming Language"),
+			Uri:          strptr("https://www.gopl.io"),
+			DocumentKind: DocumentKind{Name: "book"},
+			Authors:      []DocumentAuthor{{Name: "Alan", Surname: "Donovan"}},
+			Tags:         []DocumentTag{{Tag: "golang"}},
+		},
+	}
+}
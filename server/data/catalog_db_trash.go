@@ -0,0 +1,199 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"github.com/garugaru/knowledge/server/storage"
+	"gorm.io/gorm"
+	"time"
+)
+
+type TrashOptions struct {
+	// Retention is the time the deleted documents stay in the trash
+	Retention time.Duration
+	// Interval is the time between two purges of the trash
+	Interval time.Duration
+}
+
+// RunTrashPurge periodically purges the documents deleted for longer than
+// the retention until ctx is done.
+func (d *DBCatalog) RunTrashPurge(ctx context.Context, opts TrashOptions) {
+	for {
+		// the documents left are purged on the next run
+		_, err := d.PurgeTrash(ctx, time.Now().Add(-opts.Retention))
+		logBackgroundError(ctx, "trash", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(opts.Interval):
+		}
+	}
+}
+
+func (d *DBCatalog) ListTrash(ctx context.Context, request ListTrashRequest) (ListTrashResponse, error) {
+	query := d.db.WithContext(ctx).Unscoped().Model(&Document{}).Where("deleted_at IS NOT NULL")
+
+	var totalElements int64
+	if err := query.Count(&totalElements).Error; err != nil {
+		return ListTrashResponse{}, err
+	}
+
+	var documents []Document
+	err := query.Preload("Tags").Preload("Authors").
+		Order("deleted_at DESC").Order("id").
+		Offset(request.Pagination.Offset()).Limit(request.Pagination.PageSize).
+		Find(&documents).Error
+
+	return ListTrashResponse{
+		Items:      documents,
+		Pagination: newPaginationResponse(totalElements, request.Pagination),
+	}, err
+}
+
+// RestoreDocument takes the document out of the trash, failing when its uri
+// has been used again in the meantime.
+func (d *DBCatalog) RestoreDocument(ctx context.Context, request RestoreDocumentRequest) (Document, error) {
+	var document Document
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var deleted Document
+		if err := tx.Unscoped().Where("deleted_at IS NOT NULL").First(&deleted, request.DocumentID).Error; err != nil {
+			return err
+		}
+
+		if err := checkDuplicateUri(tx, deleted.Uri, deleted.ID); err != nil {
+			return err
+		}
+
+		// columns are updated directly to leave updated_at untouched
+		err := tx.Unscoped().Model(&Document{}).Where("id = ?", deleted.ID).UpdateColumns(map[string]interface{}{
+			"deleted_at": nil,
+			"uri_key":    uriKey(deleted.Uri),
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		if document, err = d.loadIndexedDocument(tx, deleted.ID); err != nil {
+			return err
+		}
+		return recordRevision(tx, document, RevisionUndelete, request.UserID)
+	})
+
+	if err != nil {
+		return Document{}, err
+	}
+
+	d.syncSearchIndex(document.ID, &document)
+	return document, nil
+}
+
+// PurgeDocument permanently deletes a document of the trash along with its
+// content, revisions and memberships.
+func (d *DBCatalog) PurgeDocument(ctx context.Context, request PurgeDocumentRequest) error {
+	purged, err := d.purgeDocuments(ctx, d.db.WithContext(ctx).Unscoped().Model(&Document{}).
+		Where("id = ? AND deleted_at IS NOT NULL", request.DocumentID))
+	if err != nil {
+		return err
+	}
+	if purged == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// PurgeTrash permanently deletes the documents deleted before deletedBefore
+// and the associations left without document, tag or author.
+func (d *DBCatalog) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int, error) {
+	purged, err := d.purgeDocuments(ctx, d.db.WithContext(ctx).Unscoped().Model(&Document{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", deletedBefore))
+	if err != nil {
+		return purged, err
+	}
+
+	tx := d.db.WithContext(ctx)
+	documents := tx.Unscoped().Model(&Document{}).Select("id")
+	orphans := []struct {
+		table, column string
+		entities      *gorm.DB
+	}{
+		{table: "document_document_tags", column: "document_tag_id", entities: tx.Unscoped().Model(&DocumentTag{}).Select("id")},
+		{table: "document_document_authors", column: "document_author_id", entities: tx.Unscoped().Model(&DocumentAuthor{}).Select("id")},
+	}
+	for _, orphan := range orphans {
+		err := tx.Table(orphan.table).
+			Where("document_id NOT IN (?) OR "+orphan.column+" NOT IN (?)", documents, orphan.entities).
+			Delete(map[string]interface{}{}).Error
+		if err != nil {
+			return purged, err
+		}
+	}
+
+	return purged, nil
+}
+
+// purgeDocuments hard deletes the documents selected by query, returning
+// how many were deleted.
+func (d *DBCatalog) purgeDocuments(ctx context.Context, query *gorm.DB) (int, error) {
+	var documents []Document
+	if err := query.Select("id", "content_sha256").Find(&documents).Error; err != nil {
+		return 0, err
+	}
+	if len(documents) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]int, len(documents))
+	for i, document := range documents {
+		ids[i] = document.ID
+	}
+
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		references := []struct {
+			table  string
+			column string
+		}{
+			{table: "document_document_tags", column: "document_id"},
+			{table: "document_document_authors", column: "document_id"},
+			{table: "document_texts", column: "document_id"},
+			{table: "collection_items", column: "document_id"},
+			{table: "document_revisions", column: "document_id"},
+			{table: "duplicate_candidates", column: "document_id"},
+			{table: "duplicate_candidates", column: "duplicate_id"},
+		}
+		for _, reference := range references {
+			err := tx.Table(reference.table).Where(reference.column+" IN ?", ids).Delete(map[string]interface{}{}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if d.search != nil {
+			for _, id := range ids {
+				if err := d.search.remove(tx, id); err != nil {
+					return err
+				}
+			}
+		}
+
+		return tx.Unscoped().Where("id IN ?", ids).Delete(&Document{}).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if d.blobs != nil {
+		for _, document := range documents {
+			if len(document.Content.SHA256) == 0 {
+				continue
+			}
+			// a missing blob is already purged
+			err := d.blobs.Delete(ctx, documentContentKey(document.ID))
+			if err != nil && !errors.Is(err, storage.ErrNotFound) {
+				return len(documents), err
+			}
+		}
+	}
+
+	return len(documents), nil
+}
@@ -0,0 +1,104 @@
+package data
+
+import (
+	"context"
+	"github.com/garugaru/knowledge/server/storage"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDBCatalog_Trash(t *testing.T) {
+	blobs := storage.NewLocalBlobStore(t.TempDir())
+	catalog, db := newTestCatalog(t, WithBlobStore(blobs))
+
+	for _, uri := range []string{"https://example.com/a", "https://example.com/b"} {
+		err := catalog.InsertDocument(context.TODO(), InsertDocumentRequest{
+			Document: Document{
+				Title: strptr("title"),
+				Uri:   strptr(uri),
+				Tags:  []DocumentTag{{Tag: "trash"}},
+			},
+		})
+		require.NoError(t, err)
+	}
+	_, err := catalog.PutDocumentContent(context.TODO(), PutDocumentContentRequest{DocumentID: 2, Content: strings.NewReader("content")})
+	require.NoError(t, err)
+
+	trash, err := catalog.ListTrash(context.TODO(), ListTrashRequest{Pagination: PaginationRequest{Page: 1, PageSize: 10}})
+	require.NoError(t, err)
+	require.Empty(t, trash.Items)
+
+	require.NoError(t, catalog.DeleteDocument(context.TODO(), DeleteDocumentRequest{DocumentID: 1}))
+	require.NoError(t, catalog.DeleteDocument(context.TODO(), DeleteDocumentRequest{DocumentID: 2}))
+
+	trash, err = catalog.ListTrash(context.TODO(), ListTrashRequest{Pagination: PaginationRequest{Page: 1, PageSize: 10}})
+	require.NoError(t, err)
+	require.EqualValues(t, 2, trash.Pagination.TotalElements)
+	require.Len(t, trash.Items[0].Tags, 1)
+
+	_, err = catalog.RestoreDocument(context.TODO(), RestoreDocumentRequest{DocumentID: 3})
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	// the uri of the trashed document is used again
+	err = catalog.InsertDocument(context.TODO(), InsertDocumentRequest{
+		Document: Document{Title: strptr("title"), Uri: strptr("https://example.com/a")},
+	})
+	require.NoError(t, err)
+	_, err = catalog.RestoreDocument(context.TODO(), RestoreDocumentRequest{DocumentID: 1})
+	var duplicate *DuplicateDocumentError
+	require.ErrorAs(t, err, &duplicate)
+	require.Equal(t, 3, duplicate.DocumentID)
+
+	require.NoError(t, catalog.DeleteDocument(context.TODO(), DeleteDocumentRequest{DocumentID: 3}))
+	restored, err := catalog.RestoreDocument(context.TODO(), RestoreDocumentRequest{DocumentID: 1, UserID: 7})
+	require.NoError(t, err)
+	require.Equal(t, "trash", restored.Tags[0].Tag)
+
+	document, err := catalog.GetDocument(context.TODO(), GetDocumentRequest{DocumentID: 1})
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/a", *document.Uri)
+
+	revisions, err := catalog.ListRevisions(context.TODO(), ListRevisionsRequest{DocumentID: 1, Pagination: PaginationRequest{Page: 1, PageSize: 10}})
+	require.NoError(t, err)
+	require.Equal(t, RevisionUndelete, revisions.Items[0].Action)
+	require.Equal(t, uint(7), revisions.Items[0].UserID)
+
+	err = catalog.PurgeDocument(context.TODO(), PurgeDocumentRequest{DocumentID: 1})
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound, "only trashed documents can be purged")
+
+	require.NoError(t, catalog.PurgeDocument(context.TODO(), PurgeDocumentRequest{DocumentID: 2}))
+	var count int64
+	require.NoError(t, db.Unscoped().Model(&Document{}).Where("id = ?", 2).Count(&count).Error)
+	require.Zero(t, count)
+	require.NoError(t, db.Model(&DocumentRevision{}).Where("document_id = ?", 2).Count(&count).Error)
+	require.Zero(t, count)
+	require.NoError(t, db.Table("document_document_tags").Where("document_id = ?", 2).Count(&count).Error)
+	require.Zero(t, count)
+	_, err = blobs.Get(context.TODO(), documentContentKey(2))
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	_, err = catalog.RestoreDocument(context.TODO(), RestoreDocumentRequest{DocumentID: 2})
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	// a join row left by a removed tag
+	require.NoError(t, db.Exec("INSERT INTO document_document_tags (document_id, document_tag_id) VALUES (1, 999)").Error)
+
+	purged, err := catalog.PurgeTrash(context.TODO(), time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.Zero(t, purged, "the documents within the retention must be kept")
+	require.NoError(t, db.Table("document_document_tags").Where("document_tag_id = ?", 999).Count(&count).Error)
+	require.Zero(t, count, "orphaned associations must be deleted")
+	require.NoError(t, db.Table("document_document_tags").Where("document_id = ?", 1).Count(&count).Error)
+	require.EqualValues(t, 1, count)
+
+	purged, err = catalog.PurgeTrash(context.TODO(), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 1, purged)
+
+	trash, err = catalog.ListTrash(context.TODO(), ListTrashRequest{Pagination: PaginationRequest{Page: 1, PageSize: 10}})
+	require.NoError(t, err)
+	require.Empty(t, trash.Items)
+}
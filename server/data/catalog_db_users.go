@@ -0,0 +1,118 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var errInvalidRole = errors.New("role must be one of reader, editor or admin")
+
+func (d *DBCatalog) ResolveUser(ctx context.Context, request ResolveUserRequest) (User, error) {
+	if len(request.Subject) == 0 {
+		return User{}, errors.New("user subject must be defined")
+	}
+
+	role := request.Role
+	if !role.Valid() {
+		role = RoleReader
+	}
+
+	// concurrent first requests of the same subject create a single user
+	user := User{Subject: request.Subject, Role: role}
+	err := d.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "subject"}},
+		DoNothing: true,
+	}).Create(&user).Error
+	if err != nil {
+		return User{}, err
+	}
+
+	var resolved User
+	return resolved, d.db.WithContext(ctx).Where("subject = ?", request.Subject).First(&resolved).Error
+}
+
+func (d *DBCatalog) InsertUser(ctx context.Context, request InsertUserRequest) (User, error) {
+	user := User{Subject: request.User.Subject, Name: request.User.Name, Role: request.User.Role}
+	if len(user.Subject) == 0 {
+		return User{}, errors.New("user subject must be defined")
+	}
+
+	if !user.Role.Valid() {
+		return User{}, errInvalidRole
+	}
+
+	var existing int64
+	if err := d.db.WithContext(ctx).Model(&User{}).Where("subject = ?", user.Subject).Count(&existing).Error; err != nil {
+		return User{}, err
+	}
+
+	if existing != 0 {
+		return User{}, fmt.Errorf("user %s already exists", user.Subject)
+	}
+
+	return user, d.db.WithContext(ctx).Create(&user).Error
+}
+
+func (d *DBCatalog) GetUser(ctx context.Context, request GetUserRequest) (User, error) {
+	var user User
+	return user, d.db.WithContext(ctx).First(&user, request.UserID).Error
+}
+
+func (d *DBCatalog) UpdateUser(ctx context.Context, request UpdateUserRequest) (User, error) {
+	if len(request.Role) != 0 && !request.Role.Valid() {
+		return User{}, errInvalidRole
+	}
+
+	var user User
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&user, request.UserID).Error; err != nil {
+			return err
+		}
+
+		if len(request.Role) != 0 {
+			user.Role = request.Role
+		}
+
+		if len(request.Name) != 0 {
+			user.Name = request.Name
+		}
+
+		return tx.Save(&user).Error
+	})
+
+	return user, err
+}
+
+func (d *DBCatalog) DeleteUser(ctx context.Context, request DeleteUserRequest) error {
+	// users are removed for good so that the subject can sign in again
+	result := d.db.WithContext(ctx).Unscoped().Delete(&User{}, request.UserID)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (d *DBCatalog) ListUsers(ctx context.Context, request ListUsersRequest) (ListUsersResponse, error) {
+	var totalElements int64
+	if err := d.db.WithContext(ctx).Model(&User{}).Count(&totalElements).Error; err != nil {
+		return ListUsersResponse{}, err
+	}
+
+	var users []User
+	err := d.db.WithContext(ctx).Order("subject").
+		Offset(request.Pagination.Offset()).Limit(request.Pagination.PageSize).
+		Find(&users).Error
+
+	return ListUsersResponse{
+		Items:      users,
+		Pagination: newPaginationResponse(totalElements, request.Pagination),
+	}, err
+}
@@ -0,0 +1,60 @@
+package data
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"testing"
+)
+
+func TestDBCatalog_Users(t *testing.T) {
+	catalog, _ := newTestCatalog(t)
+
+	alice, err := catalog.ResolveUser(context.TODO(), ResolveUserRequest{Subject: "alice"})
+	require.NoError(t, err)
+	require.Equal(t, RoleReader, alice.Role, "users are readers by default")
+	require.NotZero(t, alice.ID)
+
+	resolved, err := catalog.ResolveUser(context.TODO(), ResolveUserRequest{Subject: "alice", Role: RoleAdmin})
+	require.NoError(t, err)
+	require.Equal(t, alice.ID, resolved.ID)
+	require.Equal(t, RoleReader, resolved.Role, "the role only applies to new users")
+
+	bob, err := catalog.InsertUser(context.TODO(), InsertUserRequest{User: User{Subject: "bob", Name: "Bob", Role: RoleEditor}})
+	require.NoError(t, err)
+	require.Equal(t, RoleEditor, bob.Role)
+
+	_, err = catalog.InsertUser(context.TODO(), InsertUserRequest{User: User{Subject: "bob", Role: RoleEditor}})
+	require.Error(t, err)
+
+	_, err = catalog.InsertUser(context.TODO(), InsertUserRequest{User: User{Subject: "carol", Role: "owner"}})
+	require.Error(t, err)
+
+	updated, err := catalog.UpdateUser(context.TODO(), UpdateUserRequest{UserID: alice.ID, Role: RoleAdmin})
+	require.NoError(t, err)
+	require.Equal(t, RoleAdmin, updated.Role)
+	require.Equal(t, "alice", updated.Subject)
+
+	_, err = catalog.UpdateUser(context.TODO(), UpdateUserRequest{UserID: alice.ID, Role: "owner"})
+	require.Error(t, err)
+
+	users, err := catalog.ListUsers(context.TODO(), ListUsersRequest{Pagination: PaginationRequest{Page: 1, PageSize: 10}})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), users.Pagination.TotalElements)
+	require.Equal(t, "alice", users.Items[0].Subject)
+	require.Equal(t, "bob", users.Items[1].Subject)
+
+	require.NoError(t, catalog.DeleteUser(context.TODO(), DeleteUserRequest{UserID: bob.ID}))
+	require.ErrorIs(t, catalog.DeleteUser(context.TODO(), DeleteUserRequest{UserID: bob.ID}), gorm.ErrRecordNotFound)
+
+	bob, err = catalog.ResolveUser(context.TODO(), ResolveUserRequest{Subject: "bob"})
+	require.NoError(t, err)
+	require.Equal(t, RoleReader, bob.Role, "deleted users start over")
+}
+
+func TestRole_Includes(t *testing.T) {
+	require.True(t, RoleAdmin.Includes(RoleEditor))
+	require.True(t, RoleEditor.Includes(RoleEditor))
+	require.False(t, RoleReader.Includes(RoleEditor))
+	require.False(t, Role("").Includes(RoleReader))
+}
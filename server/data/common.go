@@ -1,5 +1,36 @@
 package data
 
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+)
+
+var backgroundErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "knowledge_catalog_background_errors_total",
+	Help: "Number of errors of the background jobs by job.",
+}, []string{"job"})
+
 func strptr(v string) *string {
 	return &v
 }
+
+// strEqual compares the values of a and b, two nil strings are equal.
+func strEqual(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// logBackgroundError logs and counts the error of a background job, the
+// errors caused by stopping the job are left out.
+func logBackgroundError(ctx context.Context, job string, err error) {
+	if err == nil || ctx.Err() != nil {
+		return
+	}
+
+	backgroundErrors.WithLabelValues(job).Inc()
+	logrus.WithError(err).WithField("job", job).Error("background job failed")
+}
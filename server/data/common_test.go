@@ -1,6 +1,9 @@
 package data
 
 import (
+	"context"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
 	"testing"
 )
@@ -10,3 +13,25 @@ func TestStrPtr(t *testing.T) {
 	ptr := strptr(value)
 	require.Equal(t, value, *ptr)
 }
+
+func TestStrEqual(t *testing.T) {
+	require.True(t, strEqual(nil, nil))
+	require.True(t, strEqual(strptr("uri"), strptr("uri")))
+	require.False(t, strEqual(strptr("uri"), nil))
+	require.False(t, strEqual(nil, strptr("uri")))
+	require.False(t, strEqual(strptr("uri"), strptr("other")))
+}
+
+func TestLogBackgroundError(t *testing.T) {
+	counter := backgroundErrors.WithLabelValues("test")
+	before := testutil.ToFloat64(counter)
+
+	logBackgroundError(context.Background(), "test", nil)
+	logBackgroundError(context.Background(), "test", errors.New("database is locked"))
+	require.Equal(t, before+1, testutil.ToFloat64(counter))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	logBackgroundError(ctx, "test", context.Canceled)
+	require.Equal(t, before+1, testutil.ToFloat64(counter), "the errors of the stopped jobs are left out")
+}
@@ -0,0 +1,82 @@
+package data
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"gorm.io/gorm/clause"
+	"strings"
+)
+
+var errInvalidCursor = errors.New("invalid cursor, it must be taken from a listing with the same sort")
+
+// documentCursor is the opaque position after the last document of a page.
+// Searches are paginated by offset as their ranks are not stored, the other
+// listings by the sort keys of the last document.
+type documentCursor struct {
+	// Sort identifies the sort the cursor was created for
+	Sort   string            `json:"s"`
+	Offset int               `json:"o,omitempty"`
+	Keys   *documentSortKeys `json:"k,omitempty"`
+}
+
+// sortSignature identifies a sort, cursors cannot be used across sorts.
+func sortSignature(request ListDocumentsRequest, columns []sortColumn) string {
+	var signature strings.Builder
+	if len(request.Query) != 0 {
+		signature.WriteString("search:")
+	}
+
+	for _, column := range columns {
+		if column.descending {
+			signature.WriteString("-")
+		}
+		signature.WriteString(column.column)
+		signature.WriteString(",")
+	}
+	return signature.String()
+}
+
+func decodeCursor(cursor string, signature string) (documentCursor, error) {
+	encoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return documentCursor{}, errInvalidCursor
+	}
+
+	var decoded documentCursor
+	if err := json.Unmarshal(encoded, &decoded); err != nil || decoded.Sort != signature {
+		return documentCursor{}, errInvalidCursor
+	}
+	return decoded, nil
+}
+
+func (c documentCursor) encode() string {
+	encoded, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+// keysetCondition matches the documents sorted after keys, for columns a, b
+// and id it is a > ? OR (a = ? AND b > ?) OR (a = ? AND b = ? AND id > ?).
+func keysetCondition(columns []sortColumn, keys documentSortKeys) clause.Expression {
+	var alternatives []string
+	var vars []interface{}
+
+	for i, column := range columns {
+		var conditions []string
+		for _, previous := range columns[:i] {
+			conditions = append(conditions, previous.column+" = ?")
+			vars = append(vars, previous.value(keys))
+		}
+
+		operator := " > ?"
+		if column.descending {
+			operator = " < ?"
+		}
+		conditions = append(conditions, column.column+operator)
+		vars = append(vars, column.value(keys))
+
+		alternatives = append(alternatives, "("+strings.Join(conditions, " AND ")+")")
+	}
+
+	return clause.Expr{SQL: "(" + strings.Join(alternatives, " OR ") + ")", Vars: vars}
+}
@@ -0,0 +1,54 @@
+package data
+
+import (
+	"context"
+	"sync"
+)
+
+// eventBufferSize is the number of events a subscriber can lag behind
+// before missing events.
+const eventBufferSize = 64
+
+// eventBus fans out the published events to the subscribers, the events are
+// dropped for the subscribers not keeping up, they catch up with ListEvents.
+type eventBus struct {
+	mu          sync.Mutex
+	subscribers map[chan CatalogEvent]struct{}
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{subscribers: make(map[chan CatalogEvent]struct{})}
+}
+
+// subscribe returns the events published until ctx is done, the channel is
+// closed afterwards.
+func (b *eventBus) subscribe(ctx context.Context) <-chan CatalogEvent {
+	events := make(chan CatalogEvent, eventBufferSize)
+
+	b.mu.Lock()
+	b.subscribers[events] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		b.mu.Lock()
+		delete(b.subscribers, events)
+		close(events)
+		b.mu.Unlock()
+	}()
+
+	return events
+}
+
+func (b *eventBus) publish(event CatalogEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for subscriber := range b.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package data
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsPublicIP(t *testing.T) {
+	for address, public := range map[string]bool{
+		"93.184.216.34":    true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"::1":              false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"fe80::1":          false,
+		"fd00::1":          false,
+		"0.0.0.0":          false,
+		"::":               false,
+		"100.64.0.1":       false,
+		"::ffff:127.0.0.1": false,
+	} {
+		require.Equal(t, public, isPublicIP(net.ParseIP(address)), address)
+	}
+}
+
+func TestFetchClient(t *testing.T) {
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("secret"))
+	}))
+	defer internal.Close()
+
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, internal.URL, http.StatusFound)
+	}))
+	defer redirect.Close()
+
+	for _, uri := range []string{internal.URL, "http://localhost:1/"} {
+		req, err := http.NewRequestWithContext(context.TODO(), http.MethodGet, uri, nil)
+		require.NoError(t, err)
+		_, err = newFetchClient(false).Do(req)
+		require.ErrorIs(t, err, errPrivateAddress, uri)
+	}
+
+	// the redirects are followed when private addresses are allowed
+	resp, err := newFetchClient(true).Get(redirect.URL)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
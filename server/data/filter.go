@@ -0,0 +1,533 @@
+package data
+
+import (
+	"fmt"
+	"gorm.io/gorm/clause"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Filter is a parsed filter expression restricting the listed documents, see
+// ParseFilter.
+type Filter interface {
+	expression() clause.Expression
+}
+
+type FilterAnd struct {
+	Operands []Filter
+}
+
+type FilterOr struct {
+	Operands []Filter
+}
+
+type FilterNot struct {
+	Operand Filter
+}
+
+// FilterTerm compares a document field with a value, like kind:paper.
+type FilterTerm struct {
+	Field    string
+	Operator string
+	Value    string
+}
+
+// FilterError reports the position, starting from 1, of the token making a
+// filter invalid.
+type FilterError struct {
+	Position int
+	Token    string
+	Message  string
+}
+
+func (e *FilterError) Error() string {
+	if len(e.Token) == 0 {
+		return fmt.Sprintf("invalid filter at position %d: %s", e.Position, e.Message)
+	}
+	return fmt.Sprintf("invalid filter at position %d near %q: %s", e.Position, e.Token, e.Message)
+}
+
+const filterDateLayout = "2006-01-02"
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenOperator
+	tokenOpen
+	tokenClose
+)
+
+type tokenKind int
+
+type token struct {
+	kind tokenKind
+	text string
+	// value is the unquoted text of strings
+	value    string
+	position int
+}
+
+type filterField struct {
+	operators []string
+	// validate checks the value of a term, it is nil for free text values
+	validate func(value string) error
+}
+
+var (
+	textOperators = []string{":", "=", "!="}
+	dateOperators = []string{":", "=", "!=", ">", ">=", "<", "<="}
+
+	filterFields = map[string]filterField{
+		"title":   {operators: textOperators},
+		"uri":     {operators: textOperators},
+		"kind":    {operators: textOperators},
+		"author":  {operators: textOperators},
+		"tag":     {operators: textOperators},
+		"created": {operators: dateOperators, validate: validateFilterTime},
+		"updated": {operators: dateOperators, validate: validateFilterTime},
+		"broken":  {operators: []string{":", "="}, validate: validateFilterBool},
+		"owner":   {operators: textOperators, validate: validateFilterID},
+	}
+)
+
+func validateFilterTime(value string) error {
+	_, _, err := parseFilterTime(value)
+	return err
+}
+
+func validateFilterBool(value string) error {
+	_, err := strconv.ParseBool(value)
+	return err
+}
+
+func validateFilterID(value string) error {
+	_, err := strconv.ParseUint(value, 10, 64)
+	return err
+}
+
+// parseFilterTime reads a date or a RFC 3339 time, returning the interval
+// starting at the time and lasting a day for dates.
+func parseFilterTime(value string) (time.Time, time.Time, error) {
+	if day, err := time.Parse(filterDateLayout, value); err == nil {
+		return day, day.AddDate(0, 0, 1), nil
+	}
+
+	instant, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("expected a date like %s or a RFC 3339 time", filterDateLayout)
+	}
+	return instant, instant, nil
+}
+
+// ParseFilter parses a filter expression made of field terms combined with
+// AND, OR, NOT and parentheses, like:
+//
+//	kind:paper AND author:"Lamport" AND created>2023-01-01 AND NOT tag:draft
+//
+// Terms next to each other are combined with AND.
+func ParseFilter(filter string) (Filter, error) {
+	parser := &filterParser{input: filter}
+	if err := parser.advance(); err != nil {
+		return nil, err
+	}
+
+	parsed, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if parser.current.kind != tokenEOF {
+		return nil, parser.errorf("unexpected token")
+	}
+
+	return parsed, nil
+}
+
+type filterParser struct {
+	input   string
+	offset  int
+	current token
+}
+
+func (p *filterParser) errorf(format string, args ...interface{}) error {
+	return &FilterError{
+		Position: p.current.position + 1,
+		Token:    p.current.text,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
+
+func (p *filterParser) skipSpaces() {
+	for p.offset < len(p.input) && unicode.IsSpace(rune(p.input[p.offset])) {
+		p.offset++
+	}
+}
+
+// advance reads the next token, words stop at operators so that field names
+// are split from their operator.
+func (p *filterParser) advance() error {
+	p.skipSpaces()
+	start := p.offset
+
+	if p.offset == len(p.input) {
+		p.current = token{kind: tokenEOF, position: start}
+		return nil
+	}
+
+	switch c := p.input[p.offset]; {
+	case c == '(':
+		p.offset++
+		p.current = token{kind: tokenOpen, text: "(", position: start}
+	case c == ')':
+		p.offset++
+		p.current = token{kind: tokenClose, text: ")", position: start}
+	case c == '"':
+		return p.readString()
+	case strings.ContainsRune(":=!<>", rune(c)):
+		for _, operator := range []string{">=", "<=", "!=", ":", "=", ">", "<"} {
+			if strings.HasPrefix(p.input[p.offset:], operator) {
+				p.offset += len(operator)
+				p.current = token{kind: tokenOperator, text: operator, position: start}
+				return nil
+			}
+		}
+		p.offset++
+		p.current = token{kind: tokenOperator, text: string(c), position: start}
+		return p.errorf("unknown operator")
+	default:
+		for p.offset < len(p.input) && !isFilterDelimiter(p.input[p.offset], ":=!<>") {
+			p.offset++
+		}
+		p.current = token{kind: tokenWord, text: p.input[start:p.offset], position: start}
+	}
+
+	return nil
+}
+
+// advanceValue reads the value of a term, unquoted values stop at spaces and
+// parentheses only.
+func (p *filterParser) advanceValue() error {
+	p.skipSpaces()
+	start := p.offset
+
+	if p.offset < len(p.input) && p.input[p.offset] == '"' {
+		return p.readString()
+	}
+
+	for p.offset < len(p.input) && !isFilterDelimiter(p.input[p.offset], "") {
+		p.offset++
+	}
+
+	if start == p.offset {
+		p.current = token{kind: tokenEOF, position: start}
+		if p.offset < len(p.input) {
+			p.current = token{kind: tokenClose, text: p.input[start : start+1], position: start}
+		}
+		return p.errorf("expected a value")
+	}
+
+	text := p.input[start:p.offset]
+	p.current = token{kind: tokenWord, text: text, value: text, position: start}
+	if p.isKeyword("AND") || p.isKeyword("OR") {
+		return p.errorf("expected a value, keywords must be quoted")
+	}
+	return nil
+}
+
+func (p *filterParser) readString() error {
+	start := p.offset
+	var value strings.Builder
+
+	for p.offset++; p.offset < len(p.input); p.offset++ {
+		switch c := p.input[p.offset]; c {
+		case '\\':
+			if p.offset+1 < len(p.input) {
+				p.offset++
+				value.WriteByte(p.input[p.offset])
+			}
+		case '"':
+			p.offset++
+			p.current = token{kind: tokenString, text: p.input[start:p.offset], value: value.String(), position: start}
+			return nil
+		default:
+			value.WriteByte(c)
+		}
+	}
+
+	p.current = token{kind: tokenString, text: p.input[start:], position: start}
+	return p.errorf("unterminated string")
+}
+
+func isFilterDelimiter(c byte, operators string) bool {
+	return unicode.IsSpace(rune(c)) || c == '(' || c == ')' || c == '"' || strings.IndexByte(operators, c) != -1
+}
+
+func (p *filterParser) isKeyword(keyword string) bool {
+	return p.current.kind == tokenWord && strings.EqualFold(p.current.text, keyword)
+}
+
+func (p *filterParser) parseOr() (Filter, error) {
+	operand, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	operands := []Filter{operand}
+	for p.isKeyword("OR") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+
+		operand, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, operand)
+	}
+
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+	return FilterOr{Operands: operands}, nil
+}
+
+func (p *filterParser) parseAnd() (Filter, error) {
+	operand, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	operands := []Filter{operand}
+	for {
+		if p.isKeyword("AND") {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+		} else if p.current.kind == tokenEOF || p.current.kind == tokenClose || p.isKeyword("OR") {
+			break
+		}
+
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, operand)
+	}
+
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+	return FilterAnd{Operands: operands}, nil
+}
+
+func (p *filterParser) parseNot() (Filter, error) {
+	if !p.isKeyword("NOT") {
+		return p.parsePrimary()
+	}
+
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	operand, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	return FilterNot{Operand: operand}, nil
+}
+
+func (p *filterParser) parsePrimary() (Filter, error) {
+	switch {
+	case p.current.kind == tokenOpen:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+
+		parsed, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if p.current.kind != tokenClose {
+			return nil, p.errorf("expected a closing parenthesis")
+		}
+		return parsed, p.advance()
+	case p.current.kind == tokenWord && !p.isKeyword("AND") && !p.isKeyword("OR"):
+		return p.parseTerm()
+	case p.current.kind == tokenEOF:
+		return nil, p.errorf("unexpected end of filter")
+	default:
+		return nil, p.errorf("expected a term like field:value")
+	}
+}
+
+func (p *filterParser) parseTerm() (Filter, error) {
+	name := strings.ToLower(p.current.text)
+	field, known := filterFields[name]
+	if !known {
+		return nil, p.errorf("unknown field, expected one of %s", strings.Join(filterFieldNames(), ", "))
+	}
+
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	if p.current.kind != tokenOperator {
+		return nil, p.errorf("expected an operator after %s", name)
+	}
+
+	operator := p.current.text
+	if !containsString(field.operators, operator) {
+		return nil, p.errorf("%s does not support the %s operator", name, operator)
+	}
+
+	if err := p.advanceValue(); err != nil {
+		return nil, err
+	}
+
+	value := p.current.value
+	if len(strings.TrimSpace(value)) == 0 {
+		return nil, p.errorf("expected a value, %s cannot be empty", name)
+	}
+
+	if field.validate != nil {
+		if err := field.validate(value); err != nil {
+			return nil, p.errorf("invalid %s value: %s", name, err)
+		}
+	}
+
+	return FilterTerm{Field: name, Operator: operator, Value: value}, p.advance()
+}
+
+func filterFieldNames() []string {
+	return []string{"title", "uri", "kind", "author", "tag", "created", "updated", "broken", "owner"}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (f FilterAnd) expression() clause.Expression {
+	expressions := make([]clause.Expression, len(f.Operands))
+	for i, operand := range f.Operands {
+		expressions[i] = operand.expression()
+	}
+	return clause.And(expressions...)
+}
+
+func (f FilterOr) expression() clause.Expression {
+	expressions := make([]clause.Expression, len(f.Operands))
+	for i, operand := range f.Operands {
+		expressions[i] = operand.expression()
+	}
+	return clause.Or(expressions...)
+}
+
+func (f FilterNot) expression() clause.Expression {
+	return clause.Not(f.Operand.expression())
+}
+
+func (f FilterTerm) expression() clause.Expression {
+	if f.Operator == "!=" {
+		return clause.Not(FilterTerm{Field: f.Field, Operator: "=", Value: f.Value}.expression())
+	}
+
+	switch f.Field {
+	case "title", "uri":
+		column := "documents." + f.Field
+		if f.Operator == ":" {
+			return clause.Expr{SQL: "(" + column + " LIKE ? ESCAPE '" + likeEscape + "')", Vars: []interface{}{containsPattern(f.Value)}}
+		}
+		return clause.Expr{SQL: "(" + column + " = ?)", Vars: []interface{}{f.Value}}
+	case "kind":
+		return clause.Expr{
+			SQL:  "(documents.document_kind_id IN (SELECT id FROM document_kinds WHERE name = ? AND deleted_at IS NULL))",
+			Vars: []interface{}{f.Value},
+		}
+	case "author":
+		return authorFilterExpression(f.Value, f.Operator == ":")
+	case "tag":
+		return clause.Expr{
+			SQL: "(documents.id IN (SELECT document_document_tags.document_id FROM document_document_tags " +
+				"JOIN tag_closures ON tag_closures.descendant_id = document_document_tags.document_tag_id " +
+				"JOIN document_tags ON document_tags.id = tag_closures.ancestor_id WHERE document_tags.tag = ?))",
+			Vars: []interface{}{f.Value},
+		}
+	case "created", "updated":
+		return timeFilterExpression("documents."+f.Field+"_at", f.Operator, f.Value)
+	case "broken":
+		broken, _ := strconv.ParseBool(f.Value)
+		return clause.Expr{SQL: "(documents.link_broken = ?)", Vars: []interface{}{broken}}
+	case "owner":
+		owner, _ := strconv.ParseUint(f.Value, 10, 64)
+		return clause.Expr{SQL: "(documents.owner_id = ?)", Vars: []interface{}{owner}}
+	}
+
+	return clause.Expr{SQL: "(1 = 0)"}
+}
+
+// likeEscape escapes the wildcards of the LIKE patterns, a backslash would
+// need escaping itself in the MySQL string literals.
+const likeEscape = "!"
+
+var likeEscaper = strings.NewReplacer(likeEscape, likeEscape+likeEscape, "%", likeEscape+"%", "_", likeEscape+"_")
+
+// containsPattern returns the LIKE pattern, escaped with likeEscape, matching
+// the values containing value.
+func containsPattern(value string) string {
+	return "%" + likeEscaper.Replace(value) + "%"
+}
+
+// authorFilterExpression matches the documents having an author whose name or
+// surname contains, or equals when not partial, every word of value.
+func authorFilterExpression(value string, partial bool) clause.Expression {
+	sql := "(documents.id IN (SELECT document_document_authors.document_id FROM document_document_authors " +
+		"JOIN document_authors ON document_authors.id = document_document_authors.document_author_id WHERE 1 = 1"
+
+	var vars []interface{}
+	for _, word := range strings.Fields(value) {
+		if partial {
+			sql += " AND (document_authors.name LIKE ? ESCAPE '" + likeEscape + "' OR document_authors.surname LIKE ? ESCAPE '" + likeEscape + "')"
+			word = containsPattern(word)
+		} else {
+			sql += " AND (document_authors.name = ? OR document_authors.surname = ?)"
+		}
+		vars = append(vars, word, word)
+	}
+
+	return clause.Expr{SQL: sql + "))", Vars: vars}
+}
+
+// timeFilterExpression compares column with a time, dates cover the whole day.
+func timeFilterExpression(column string, operator string, value string) clause.Expression {
+	start, end, _ := parseFilterTime(value)
+	if start.Equal(end) {
+		switch operator {
+		case ":", "=":
+			return clause.Expr{SQL: "(" + column + " = ?)", Vars: []interface{}{start}}
+		default:
+			return clause.Expr{SQL: "(" + column + " " + operator + " ?)", Vars: []interface{}{start}}
+		}
+	}
+
+	switch operator {
+	case ">":
+		return clause.Expr{SQL: "(" + column + " >= ?)", Vars: []interface{}{end}}
+	case ">=":
+		return clause.Expr{SQL: "(" + column + " >= ?)", Vars: []interface{}{start}}
+	case "<":
+		return clause.Expr{SQL: "(" + column + " < ?)", Vars: []interface{}{start}}
+	case "<=":
+		return clause.Expr{SQL: "(" + column + " < ?)", Vars: []interface{}{end}}
+	default:
+		return clause.Expr{SQL: "(" + column + " >= ? AND " + column + " < ?)", Vars: []interface{}{start, end}}
+	}
+}
@@ -0,0 +1,183 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		want   Filter
+	}{
+		{
+			name:   "single term",
+			filter: "kind:paper",
+			want:   FilterTerm{Field: "kind", Operator: ":", Value: "paper"},
+		},
+		{
+			name:   "quoted value",
+			filter: `author:"Leslie \"L\" Lamport"`,
+			want:   FilterTerm{Field: "author", Operator: ":", Value: `Leslie "L" Lamport`},
+		},
+		{
+			name:   "value with colons",
+			filter: "uri=https://example.com/a",
+			want:   FilterTerm{Field: "uri", Operator: "=", Value: "https://example.com/a"},
+		},
+		{
+			name:   "and with not",
+			filter: `kind:paper AND author:"Lamport" AND created>2023-01-01 AND NOT tag:draft`,
+			want: FilterAnd{Operands: []Filter{
+				FilterTerm{Field: "kind", Operator: ":", Value: "paper"},
+				FilterTerm{Field: "author", Operator: ":", Value: "Lamport"},
+				FilterTerm{Field: "created", Operator: ">", Value: "2023-01-01"},
+				FilterNot{Operand: FilterTerm{Field: "tag", Operator: ":", Value: "draft"}},
+			}},
+		},
+		{
+			name:   "or has lower precedence than and",
+			filter: "tag:a tag:b or Title!=c",
+			want: FilterOr{Operands: []Filter{
+				FilterAnd{Operands: []Filter{
+					FilterTerm{Field: "tag", Operator: ":", Value: "a"},
+					FilterTerm{Field: "tag", Operator: ":", Value: "b"},
+				}},
+				FilterTerm{Field: "title", Operator: "!=", Value: "c"},
+			}},
+		},
+		{
+			name:   "parentheses",
+			filter: "NOT (tag:a OR tag:b) AND updated<=2023-01-01T10:00:00Z",
+			want: FilterAnd{Operands: []Filter{
+				FilterNot{Operand: FilterOr{Operands: []Filter{
+					FilterTerm{Field: "tag", Operator: ":", Value: "a"},
+					FilterTerm{Field: "tag", Operator: ":", Value: "b"},
+				}}},
+				FilterTerm{Field: "updated", Operator: "<=", Value: "2023-01-01T10:00:00Z"},
+			}},
+		},
+		{
+			name:   "value closed by a parenthesis",
+			filter: "(broken:true)",
+			want:   FilterTerm{Field: "broken", Operator: ":", Value: "true"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFilter(tt.filter)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseFilter_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   string
+		position int
+		token    string
+	}{
+		{name: "empty", filter: "", position: 1},
+		{name: "unknown field", filter: "kind:paper AND color:red", position: 16, token: "color"},
+		{name: "missing operator", filter: "kind paper", position: 6, token: "paper"},
+		{name: "unsupported operator", filter: "title>a", position: 6, token: ">"},
+		{name: "unknown operator", filter: "title!a", position: 6, token: "!"},
+		{name: "missing value", filter: "kind: AND tag:a", position: 7, token: "AND"},
+		{name: "missing value at end", filter: "kind:", position: 6},
+		{name: "empty value", filter: `author:""`, position: 8, token: `""`},
+		{name: "blank value", filter: `title:" "`, position: 7, token: `" "`},
+		{name: "invalid date", filter: "created>yesterday", position: 9, token: "yesterday"},
+		{name: "invalid bool", filter: "broken:maybe", position: 8, token: "maybe"},
+		{name: "invalid owner", filter: "owner=me", position: 7, token: "me"},
+		{name: "unterminated string", filter: `author:"Lamport`, position: 8, token: `"Lamport`},
+		{name: "unclosed parenthesis", filter: "(tag:a OR tag:b", position: 16},
+		{name: "unexpected parenthesis", filter: "tag:a)", position: 6, token: ")"},
+		{name: "dangling operator", filter: "tag:a AND", position: 10},
+		{name: "dangling not", filter: "NOT", position: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseFilter(tt.filter)
+			var filterErr *FilterError
+			require.True(t, errors.As(err, &filterErr), "expected a filter error, got %v", err)
+			require.Equal(t, tt.position, filterErr.Position, filterErr.Error())
+			require.Equal(t, tt.token, filterErr.Token, filterErr.Error())
+		})
+	}
+}
+
+func TestDBCatalog_ListDocuments_Filter(t *testing.T) {
+	catalog, db := newTestCatalog(t)
+
+	documents := []Document{
+		{
+			Title:        strptr("Time, Clocks, and the Ordering of Events"),
+			Uri:          strptr("https://example.com/clocks"),
+			DocumentKind: DocumentKind{Name: "paper"},
+			Authors:      []DocumentAuthor{{Name: "Leslie", Surname: "Lamport"}},
+			Tags:         []DocumentTag{{Tag: "distributed/time"}},
+		},
+		{
+			Title:        strptr("Paxos Made Simple"),
+			Uri:          strptr("https://example.com/paxos"),
+			DocumentKind: DocumentKind{Name: "paper"},
+			Authors:      []DocumentAuthor{{Name: "Leslie", Surname: "Lamport"}},
+			Tags:         []DocumentTag{{Tag: "distributed/consensus"}, {Tag: "draft"}},
+		},
+		{
+			Title:        strptr("Designing Data-Intensive Applications"),
+			Uri:          strptr("https://example.com/ddia"),
+			DocumentKind: DocumentKind{Name: "book"},
+			Authors:      []DocumentAuthor{{Name: "Martin", Surname: "Kleppmann"}},
+			Tags:         []DocumentTag{{Tag: "distributed"}},
+		},
+	}
+	created := []time.Time{
+		time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC),
+		time.Date(2023, 1, 1, 8, 0, 0, 0, time.UTC),
+	}
+	for i, document := range documents {
+		require.NoError(t, catalog.InsertDocument(context.TODO(), InsertDocumentRequest{Document: document}))
+		require.NoError(t, db.Model(&Document{}).Where("id = ?", i+1).UpdateColumn("created_at", created[i]).Error)
+	}
+
+	list := func(filter string) []string {
+		parsed, err := ParseFilter(filter)
+		require.NoError(t, err)
+
+		response, err := catalog.ListDocuments(context.TODO(), ListDocumentsRequest{
+			Filter:     parsed,
+			Pagination: PaginationRequest{Page: 1, PageSize: 10},
+		})
+		require.NoError(t, err)
+		require.EqualValues(t, len(response.Items), response.Pagination.TotalElements)
+
+		titles := []string{}
+		for _, document := range response.Items {
+			titles = append(titles, *document.Title)
+		}
+		return titles
+	}
+
+	require.Equal(t, []string{"Time, Clocks, and the Ordering of Events"},
+		list(`kind:paper AND author:"Lamport" AND created<2023-01-01 AND NOT tag:draft`))
+	require.Equal(t, []string{"Paxos Made Simple"}, list(`kind:paper author:"Leslie Lamport" created>2023-01-01`))
+	require.Equal(t, []string{"Designing Data-Intensive Applications"}, list("created:2023-01-01"))
+	require.Equal(t, []string{"Designing Data-Intensive Applications", "Paxos Made Simple"}, list("created>=2023-01-01"))
+	require.Equal(t, []string{"Designing Data-Intensive Applications", "Paxos Made Simple", "Time, Clocks, and the Ordering of Events"}, list("tag:distributed"))
+	require.Equal(t, []string{"Designing Data-Intensive Applications", "Time, Clocks, and the Ordering of Events"}, list("tag:distributed/time OR kind=book"))
+	require.Equal(t, []string{"Paxos Made Simple"}, list("title:paxos OR uri=https://example.com/none"))
+	require.Equal(t, []string{"Designing Data-Intensive Applications"}, list("author!=Lamport"))
+	require.Empty(t, list("author=Lamp"))
+	require.Empty(t, list("broken:true"))
+	require.Empty(t, list("title:%"), "the wildcards are matched literally")
+	require.Empty(t, list("author:_"))
+	require.Equal(t, []string{"Designing Data-Intensive Applications"}, list("title:Data-Intensive"))
+}
@@ -0,0 +1,88 @@
+package data
+
+import (
+	"github.com/garugaru/knowledge/server/search"
+	"gorm.io/gorm"
+	"strings"
+)
+
+// fullTextIndex keeps a searchable copy of the document metadata next to the
+// catalog tables, using the full-text engine of the underlying database.
+type fullTextIndex interface {
+	// migrate creates the index storage, returning true when it did not exist yet
+	migrate(tx *gorm.DB) (bool, error)
+	index(tx *gorm.DB, document searchDocument) error
+	remove(tx *gorm.DB, documentID int) error
+	clear(tx *gorm.DB) error
+	// match restricts the query to the documents matching q
+	match(query *gorm.DB, q string) *gorm.DB
+	// rank selects the snippet and search_rank columns, higher rank is more relevant
+	rank(query *gorm.DB, q string) *gorm.DB
+	highlight(documents []Document, q string)
+}
+
+func newFullTextIndex(db *gorm.DB) fullTextIndex {
+	switch db.Dialector.Name() {
+	case "sqlite":
+		return &sqliteFullTextIndex{}
+	case "postgres":
+		return postgresFullTextIndex{}
+	case "mysql":
+		return mysqlFullTextIndex{}
+	default:
+		return nil
+	}
+}
+
+type searchDocument struct {
+	DocumentID int
+	Title      string
+	Authors    string
+	Tags       string
+	Kind       string
+	Body       string
+}
+
+func newSearchDocument(document Document) searchDocument {
+	authors := make([]string, 0, len(document.Authors))
+	for _, author := range document.Authors {
+		authors = append(authors, strings.TrimSpace(author.Name+" "+author.Surname))
+	}
+
+	tags := make([]string, 0, len(document.Tags))
+	for _, tag := range document.Tags {
+		tags = append(tags, tag.Tag)
+	}
+
+	var title string
+	if document.Title != nil {
+		title = *document.Title
+	}
+
+	var body string
+	if document.Text != nil {
+		body = document.Text.Text
+	}
+
+	return searchDocument{
+		DocumentID: document.ID,
+		Title:      title,
+		Authors:    strings.Join(authors, ", "),
+		Tags:       strings.Join(tags, " "),
+		Kind:       document.DocumentKind.Name,
+		Body:       body,
+	}
+}
+
+func (s searchDocument) indexDocument() search.Document {
+	return search.Document{
+		ID: s.DocumentID,
+		Fields: map[string]string{
+			"title":   s.Title,
+			"authors": s.Authors,
+			"tags":    s.Tags,
+			"kind":    s.Kind,
+			"body":    s.Body,
+		},
+	}
+}
@@ -0,0 +1,69 @@
+package data
+
+import (
+	"github.com/garugaru/knowledge/server/search"
+	"gorm.io/gorm"
+)
+
+const mysqlMatchColumns = "MATCH(document_search.title, document_search.authors, document_search.tags, document_search.kind, document_search.body)"
+
+// mysqlFullTextIndex relies on an InnoDB FULLTEXT index, since MySQL has no
+// highlighting function snippets are built from the indexed content.
+type mysqlFullTextIndex struct{}
+
+func (m mysqlFullTextIndex) migrate(tx *gorm.DB) (bool, error) {
+	if tx.Migrator().HasTable("document_search") {
+		if tx.Migrator().HasColumn("document_search", "body") {
+			return false, nil
+		}
+
+		// tables created before the body column are rebuilt
+		if err := tx.Migrator().DropTable("document_search"); err != nil {
+			return false, err
+		}
+	}
+
+	return true, tx.Exec(`CREATE TABLE document_search (
+		document_id BIGINT PRIMARY KEY,
+		title TEXT NOT NULL,
+		authors TEXT NOT NULL,
+		tags TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		body LONGTEXT NOT NULL,
+		FULLTEXT INDEX idx_document_search (title, authors, tags, kind, body)
+	) ENGINE=InnoDB`).Error
+}
+
+func (m mysqlFullTextIndex) index(tx *gorm.DB, document searchDocument) error {
+	return tx.Exec("REPLACE INTO document_search (document_id, title, authors, tags, kind, body) VALUES (?, ?, ?, ?, ?, ?)",
+		document.DocumentID, document.Title, document.Authors, document.Tags, document.Kind, document.Body).Error
+}
+
+func (m mysqlFullTextIndex) remove(tx *gorm.DB, documentID int) error {
+	return tx.Exec("DELETE FROM document_search WHERE document_id = ?", documentID).Error
+}
+
+func (m mysqlFullTextIndex) clear(tx *gorm.DB) error {
+	return tx.Exec("DELETE FROM document_search").Error
+}
+
+func (m mysqlFullTextIndex) match(query *gorm.DB, q string) *gorm.DB {
+	query = query.Joins("JOIN document_search ON document_search.document_id = documents.id")
+	return query.Where(mysqlMatchColumns+" AGAINST (? IN NATURAL LANGUAGE MODE)", q)
+}
+
+func (m mysqlFullTextIndex) rank(query *gorm.DB, q string) *gorm.DB {
+	return query.Select(
+		"documents.*, "+
+			"concat_ws(' ', document_search.title, document_search.authors, document_search.tags, document_search.kind, document_search.body) AS snippet, "+
+			mysqlMatchColumns+" AGAINST (? IN NATURAL LANGUAGE MODE) AS search_rank",
+		q,
+	)
+}
+
+func (m mysqlFullTextIndex) highlight(documents []Document, q string) {
+	terms := search.Tokenize(q)
+	for i := range documents {
+		documents[i].Snippet = search.Highlight(documents[i].Snippet, terms, search.SnippetWords)
+	}
+}
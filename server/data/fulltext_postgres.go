@@ -0,0 +1,87 @@
+package data
+
+import (
+	"fmt"
+	"github.com/garugaru/knowledge/server/search"
+	"gorm.io/gorm"
+)
+
+type postgresFullTextIndex struct{}
+
+func (p postgresFullTextIndex) migrate(tx *gorm.DB) (bool, error) {
+	if tx.Migrator().HasTable("document_search") {
+		if tx.Migrator().HasColumn("document_search", "body") {
+			return false, nil
+		}
+
+		// tables created before the body column are rebuilt
+		if err := tx.Migrator().DropTable("document_search"); err != nil {
+			return false, err
+		}
+	}
+
+	err := tx.Exec(`CREATE TABLE document_search (
+		document_id BIGINT PRIMARY KEY,
+		title TEXT NOT NULL,
+		authors TEXT NOT NULL,
+		tags TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		body TEXT NOT NULL,
+		document TSVECTOR NOT NULL
+	)`).Error
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Exec("CREATE INDEX idx_document_search_document ON document_search USING GIN (document)").Error
+}
+
+func (p postgresFullTextIndex) index(tx *gorm.DB, document searchDocument) error {
+	return tx.Exec(`INSERT INTO document_search (document_id, title, authors, tags, kind, body, document)
+		VALUES (@id, @title, @authors, @tags, @kind, @body,
+			setweight(to_tsvector('english', @title), 'A') ||
+			setweight(to_tsvector('english', @authors), 'B') ||
+			setweight(to_tsvector('english', @tags), 'C') ||
+			setweight(to_tsvector('english', @kind), 'D') ||
+			setweight(to_tsvector('english', @body), 'D'))
+		ON CONFLICT (document_id) DO UPDATE SET
+			title = EXCLUDED.title,
+			authors = EXCLUDED.authors,
+			tags = EXCLUDED.tags,
+			kind = EXCLUDED.kind,
+			body = EXCLUDED.body,
+			document = EXCLUDED.document`,
+		map[string]interface{}{
+			"id":      document.DocumentID,
+			"title":   document.Title,
+			"authors": document.Authors,
+			"tags":    document.Tags,
+			"kind":    document.Kind,
+			"body":    document.Body,
+		}).Error
+}
+
+func (p postgresFullTextIndex) remove(tx *gorm.DB, documentID int) error {
+	return tx.Exec("DELETE FROM document_search WHERE document_id = ?", documentID).Error
+}
+
+func (p postgresFullTextIndex) clear(tx *gorm.DB) error {
+	return tx.Exec("DELETE FROM document_search").Error
+}
+
+func (p postgresFullTextIndex) match(query *gorm.DB, q string) *gorm.DB {
+	query = query.Joins("JOIN document_search ON document_search.document_id = documents.id")
+	return query.Where("document_search.document @@ websearch_to_tsquery('english', ?)", q)
+}
+
+func (p postgresFullTextIndex) rank(query *gorm.DB, q string) *gorm.DB {
+	return query.Select(fmt.Sprintf(
+		"documents.*, "+
+			"ts_headline('english', concat_ws(' ', document_search.title, document_search.authors, document_search.tags, document_search.kind, document_search.body), "+
+			"websearch_to_tsquery('english', ?), 'StartSel=%s, StopSel=%s, MaxWords=%d, MinWords=%d') AS snippet, "+
+			"ts_rank(document_search.document, websearch_to_tsquery('english', ?)) AS search_rank",
+		search.HighlightStart, search.HighlightEnd, search.SnippetWords, search.SnippetWords/2,
+	), q, q)
+}
+
+func (p postgresFullTextIndex) highlight([]Document, string) {}
@@ -0,0 +1,104 @@
+package data
+
+import (
+	"fmt"
+	"github.com/garugaru/knowledge/server/search"
+	"gorm.io/gorm"
+	"strings"
+)
+
+// sqliteFullTextIndex uses FTS5 when the driver is built with the sqlite_fts5
+// tag, falling back to FTS4 which is always available.
+type sqliteFullTextIndex struct {
+	fts5 bool
+}
+
+func (s *sqliteFullTextIndex) migrate(tx *gorm.DB) (bool, error) {
+	var definition string
+	err := tx.Raw("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'document_search'").Scan(&definition).Error
+	if err != nil {
+		return false, err
+	}
+
+	if len(definition) != 0 {
+		s.fts5 = strings.Contains(strings.ToLower(definition), "fts5")
+		if strings.Contains(definition, "body") {
+			return false, nil
+		}
+
+		// tables created before the body column are rebuilt
+		if err := tx.Exec("DROP TABLE document_search").Error; err != nil {
+			return false, err
+		}
+	}
+
+	if err := tx.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&s.fts5).Error; err != nil {
+		return false, err
+	}
+
+	module := "fts4"
+	if s.fts5 {
+		module = "fts5"
+	}
+
+	return true, tx.Exec(fmt.Sprintf("CREATE VIRTUAL TABLE document_search USING %s(title, authors, tags, kind, body)", module)).Error
+}
+
+func (s *sqliteFullTextIndex) index(tx *gorm.DB, document searchDocument) error {
+	if err := s.remove(tx, document.DocumentID); err != nil {
+		return err
+	}
+
+	return tx.Exec("INSERT INTO document_search (rowid, title, authors, tags, kind, body) VALUES (?, ?, ?, ?, ?, ?)",
+		document.DocumentID, document.Title, document.Authors, document.Tags, document.Kind, document.Body).Error
+}
+
+func (s *sqliteFullTextIndex) remove(tx *gorm.DB, documentID int) error {
+	return tx.Exec("DELETE FROM document_search WHERE rowid = ?", documentID).Error
+}
+
+func (s *sqliteFullTextIndex) clear(tx *gorm.DB) error {
+	return tx.Exec("DELETE FROM document_search").Error
+}
+
+func (s *sqliteFullTextIndex) match(query *gorm.DB, q string) *gorm.DB {
+	query = query.Joins("JOIN document_search ON document_search.rowid = documents.id")
+
+	terms := search.Tokenize(q)
+	if len(terms) == 0 {
+		return query.Where("1 = 0")
+	}
+
+	expressions := make([]string, len(terms))
+	for i, term := range terms {
+		expressions[i] = fmt.Sprintf("\"%s\"*", term)
+	}
+
+	return query.Where("document_search MATCH ?", strings.Join(expressions, " "))
+}
+
+func (s *sqliteFullTextIndex) rank(query *gorm.DB, q string) *gorm.DB {
+	if s.fts5 {
+		return query.Select(fmt.Sprintf(
+			"documents.*, snippet(document_search, -1, '%s', '%s', '...', %d) AS snippet, -bm25(document_search, 10.0, 5.0, 2.0, 1.0, 1.0) AS search_rank",
+			search.HighlightStart, search.HighlightEnd, search.SnippetWords,
+		))
+	}
+
+	// FTS4 has no built-in ranking function: each match adds four integers to
+	// offsets(), and terms found in the title get a fixed boost
+	rank := "(length(offsets(document_search)) - length(replace(offsets(document_search), ' ', '')) + 1) / 4"
+	terms := search.Tokenize(q)
+	vars := make([]interface{}, len(terms))
+	for i, term := range terms {
+		rank += " + 10 * (instr(lower(document_search.title), ?) > 0)"
+		vars[i] = term
+	}
+
+	return query.Select(fmt.Sprintf(
+		"documents.*, snippet(document_search, '%s', '%s', '...', -1, %d) AS snippet, %s AS search_rank",
+		search.HighlightStart, search.HighlightEnd, search.SnippetWords, rank,
+	), vars...)
+}
+
+func (s *sqliteFullTextIndex) highlight([]Document, string) {}
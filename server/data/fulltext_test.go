@@ -0,0 +1,26 @@
+package data
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestNewSearchDocument(t *testing.T) {
+	document := newSearchDocument(Document{
+		ID:           1,
+		Title:        strptr("Paxos Made Simple"),
+		DocumentKind: DocumentKind{Name: "paper"},
+		Authors:      []DocumentAuthor{{Name: "Leslie", Surname: "Lamport"}, {Name: "Anonymous"}},
+		Tags:         []DocumentTag{{Tag: "consensus"}, {Tag: "distributed"}},
+		Text:         &DocumentText{DocumentID: 1, Text: "The Paxos algorithm, when presented in plain English, is very simple."},
+	})
+
+	require.Equal(t, searchDocument{
+		DocumentID: 1,
+		Title:      "Paxos Made Simple",
+		Authors:    "Leslie Lamport, Anonymous",
+		Tags:       "consensus distributed",
+		Kind:       "paper",
+		Body:       "The Paxos algorithm, when presented in plain English, is very simple.",
+	}, document)
+}
@@ -1,7 +1,11 @@
 package data
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"gorm.io/gorm"
+	"time"
 )
 
 type Document struct {
@@ -15,20 +19,300 @@ type Document struct {
 	Authors        []DocumentAuthor `gorm:"many2many:document_document_authors;" json:"authors,omitempty"`
 	Tags           []DocumentTag    `gorm:"many2many:document_document_tags;" json:"tags,omitempty"`
 	CreateTime     int              `gorm:"autoCreateTime" json:"createTime,omitempty"`
+	Content        DocumentContent  `gorm:"embedded;embeddedPrefix:content_" json:"content"`
+	Extraction     ExtractionState  `gorm:"embedded;embeddedPrefix:extraction_" json:"extraction"`
+	Text           *DocumentText    `gorm:"foreignKey:DocumentID" json:"-"`
+	Fetch          FetchState       `gorm:"embedded;embeddedPrefix:fetch_" json:"fetch"`
+	Link           LinkState        `gorm:"embedded;embeddedPrefix:link_" json:"link"`
+	OwnerID        uint             `gorm:"index" json:"owner_id,omitempty"`
+	Snippet        string           `gorm:"->;-:migration" json:"snippet,omitempty"`
+	SearchRank     float64          `gorm:"->;-:migration" json:"search_rank,omitempty"`
+	// UriKey identifies the normalized uri of the documents not deleted
+	UriKey *string `gorm:"size:64;index:,unique" json:"-"`
+}
+
+// DocumentContent describes the blob uploaded for a document, it is managed
+// by the catalog and cannot be set by clients.
+type DocumentContent struct {
+	Type   string `json:"type,omitempty"`
+	Size   int64  `json:"size,omitempty"`
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+type ExtractionStatus string
+
+const (
+	ExtractionPending ExtractionStatus = "pending"
+	ExtractionDone    ExtractionStatus = "done"
+	ExtractionFailed  ExtractionStatus = "failed"
+)
+
+// ExtractionState tracks the text extraction of the document content, it is
+// managed by the catalog and cannot be set by clients.
+type ExtractionState struct {
+	Status ExtractionStatus `json:"status,omitempty"`
+	Error  string           `json:"error,omitempty"`
+}
+
+type FetchStatus string
+
+const (
+	FetchDone   FetchStatus = "done"
+	FetchFailed FetchStatus = "failed"
+)
+
+// FetchState records the last time the document uri was fetched to read its
+// metadata, it is managed by the catalog and cannot be set by clients.
+type FetchState struct {
+	Status     FetchStatus `json:"status,omitempty"`
+	StatusCode int         `json:"status_code,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	Time       int         `json:"time,omitempty"`
+}
+
+// LinkState is the result of the last check of the document uri, it is
+// managed by the catalog and cannot be set by clients.
+type LinkState struct {
+	CheckTime   int    `json:"check_time,omitempty"`
+	StatusCode  int    `json:"status_code,omitempty"`
+	RedirectUri string `json:"redirect_uri,omitempty"`
+	Error       string `json:"error,omitempty"`
+	Broken      bool   `gorm:"index;default:false" json:"broken"`
+}
+
+// DocumentText is the plain text extracted from the document content.
+type DocumentText struct {
+	DocumentID int `gorm:"primaryKey;autoIncrement:false"`
+	Text       string
 }
 
 type DocumentKind struct {
 	gorm.Model
-	Name string `json:"name,omitempty"`
+	Name string `gorm:"index:,unique" json:"name,omitempty"`
+	// DocumentCount is the number of documents of the kind, only set listing kinds
+	DocumentCount int64 `gorm:"->;-:migration" json:"document_count,omitempty"`
+}
+
+func (k DocumentKind) isZero() bool {
+	return k.ID == 0 && len(k.Name) == 0
 }
 
+// DocumentTag is a node of the tag hierarchy, Tag is its full path like
+// databases/postgres/replication.
 type DocumentTag struct {
 	gorm.Model
-	Tag string `json:"tag,omitempty"`
+	Tag      string `gorm:"index:,unique" json:"tag,omitempty"`
+	ParentID *uint  `gorm:"index" json:"parent_id,omitempty"`
+	// DocumentCount is the number of documents with the tag, only set listing tags
+	DocumentCount int64 `gorm:"->;-:migration" json:"document_count,omitempty"`
+	// Aliases are the spellings of the tags merged into this one, only set listing tags
+	Aliases []string `gorm:"-" json:"aliases,omitempty"`
+	// Children are the tags below this one, only set reading the tag tree
+	Children []DocumentTag `gorm:"-" json:"children,omitempty"`
+}
+
+// TagClosure links every tag to each of its ancestors, including itself at
+// depth zero, to query a whole subtree at once.
+type TagClosure struct {
+	AncestorID   uint `gorm:"primaryKey;autoIncrement:false"`
+	DescendantID uint `gorm:"primaryKey;autoIncrement:false;index"`
+	Depth        int  `gorm:"not null"`
+}
+
+// TagAlias resolves the spelling of a merged tag to the tag it was merged into.
+type TagAlias struct {
+	Alias string `gorm:"primaryKey"`
+	TagID uint   `gorm:"index;not null"`
 }
 
 type DocumentAuthor struct {
 	gorm.Model
+	Name    string `gorm:"index:idx_document_authors_full_name,unique" json:"name,omitempty"`
+	Surname string `gorm:"index:idx_document_authors_full_name,unique" json:"surname,omitempty"`
+	// DocumentCount is the number of documents of the author, only set listing authors
+	DocumentCount int64 `gorm:"->;-:migration" json:"document_count,omitempty"`
+	// Aliases are the spellings of the authors merged into this one, only set listing authors
+	Aliases []AuthorAlias `gorm:"-" json:"aliases,omitempty"`
+}
+
+// AuthorAlias resolves the spelling of a merged author to the author it was merged into.
+type AuthorAlias struct {
+	Name     string `gorm:"primaryKey" json:"name,omitempty"`
+	Surname  string `gorm:"primaryKey" json:"surname,omitempty"`
+	AuthorID uint   `gorm:"index;not null" json:"-"`
+}
+
+// DuplicateCandidate is a pair of similar documents found by the last
+// duplicate detection, DocumentID is the lowest of the two ids.
+type DuplicateCandidate struct {
+	DocumentID  int     `gorm:"primaryKey;autoIncrement:false" json:"document_id"`
+	DuplicateID int     `gorm:"primaryKey;autoIncrement:false;index" json:"duplicate_id"`
+	Score       float64 `json:"score"`
+	TitleScore  float64 `json:"title_score"`
+	// AuthorScore is only set when both documents have authors
+	AuthorScore *float64 `json:"author_score,omitempty"`
+}
+
+type RevisionAction string
+
+const (
+	RevisionInsert   RevisionAction = "insert"
+	RevisionUpdate   RevisionAction = "update"
+	RevisionRefresh  RevisionAction = "refresh"
+	RevisionContent  RevisionAction = "content"
+	RevisionDelete   RevisionAction = "delete"
+	RevisionRestore  RevisionAction = "restore"
+	RevisionUndelete RevisionAction = "undelete"
+)
+
+// DocumentRevision is a snapshot of the metadata of a document taken after
+// each of its changes, UserID is the user who made it.
+type DocumentRevision struct {
+	ID            uint            `gorm:"primaryKey" json:"ID"`
+	DocumentID    int             `gorm:"index" json:"document_id"`
+	Action        RevisionAction  `json:"action"`
+	UserID        uint            `json:"user_id,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	Title         string          `json:"title"`
+	Uri           string          `json:"uri"`
+	Kind          string          `json:"kind,omitempty"`
+	Tags          RevisionTags    `gorm:"type:text" json:"tags"`
+	Authors       RevisionAuthors `gorm:"type:text" json:"authors"`
+	ContentSHA256 string          `json:"content_sha256,omitempty"`
+}
+
+type EventType string
+
+const (
+	EventDocumentCreated EventType = "document.created"
+	EventDocumentUpdated EventType = "document.updated"
+	EventDocumentDeleted EventType = "document.deleted"
+)
+
+// CatalogEvent is a change of a document, stored in an outbox table by the
+// transaction making the change. The relay numbers the committed events in
+// sequence, the consumers resume after the sequence of the last one.
+type CatalogEvent struct {
+	ID         uint      `gorm:"primaryKey" json:"ID"`
+	Sequence   *uint     `gorm:"uniqueIndex" json:"sequence,omitempty"`
+	Type       EventType `gorm:"size:32" json:"type"`
+	DocumentID int       `json:"document_id"`
+	// RevisionID is the revision recorded by the change, if any
+	RevisionID uint      `json:"revision_id,omitempty"`
+	UserID     uint      `json:"user_id,omitempty"`
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+}
+
+type RevisionAuthor struct {
 	Name    string `json:"name,omitempty"`
 	Surname string `json:"surname,omitempty"`
 }
+
+// RevisionTags is stored as a json array.
+type RevisionTags []string
+
+func (t RevisionTags) Value() (driver.Value, error) {
+	return jsonValue(t)
+}
+
+func (t *RevisionTags) Scan(value interface{}) error {
+	return scanJSON(value, t)
+}
+
+// RevisionAuthors is stored as a json array.
+type RevisionAuthors []RevisionAuthor
+
+func (a RevisionAuthors) Value() (driver.Value, error) {
+	return jsonValue(a)
+}
+
+func (a *RevisionAuthors) Scan(value interface{}) error {
+	return scanJSON(value, a)
+}
+
+func jsonValue(value interface{}) (driver.Value, error) {
+	encoded, err := json.Marshal(value)
+	return string(encoded), err
+}
+
+func scanJSON(value interface{}, target interface{}) error {
+	switch value := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return json.Unmarshal([]byte(value), target)
+	case []byte:
+		return json.Unmarshal(value, target)
+	default:
+		return errors.New("unsupported json column value")
+	}
+}
+
+type Role string
+
+const (
+	RoleReader Role = "reader"
+	RoleEditor Role = "editor"
+	RoleAdmin  Role = "admin"
+)
+
+var roleRanks = map[Role]int{RoleReader: 1, RoleEditor: 2, RoleAdmin: 3}
+
+func (r Role) Valid() bool {
+	_, valid := roleRanks[r]
+	return valid
+}
+
+// Includes reports whether the role grants the permissions of other, every
+// role includes the ones below it.
+func (r Role) Includes(other Role) bool {
+	return roleRanks[r] >= roleRanks[other]
+}
+
+// User is the catalog account of an authenticated subject.
+type User struct {
+	gorm.Model
+	Subject string `gorm:"index:,unique;not null" json:"subject"`
+	Name    string `json:"name,omitempty"`
+	Role    Role   `gorm:"not null" json:"role"`
+}
+
+type Visibility string
+
+const (
+	// VisibilityPrivate collections are only visible to their owner
+	VisibilityPrivate Visibility = "private"
+	// VisibilityShared collections are visible to the owner and the users they are shared with
+	VisibilityShared Visibility = "shared"
+	// VisibilityPublic collections are visible to everyone
+	VisibilityPublic Visibility = "public"
+)
+
+func (v Visibility) Valid() bool {
+	return v == VisibilityPrivate || v == VisibilityShared || v == VisibilityPublic
+}
+
+// Collection is a named and ordered list of documents, like a reading list.
+type Collection struct {
+	gorm.Model
+	Name        string            `gorm:"not null" json:"name"`
+	Description string            `json:"description,omitempty"`
+	OwnerID     uint              `gorm:"index" json:"owner_id,omitempty"`
+	Visibility  Visibility        `gorm:"not null" json:"visibility"`
+	Items       []CollectionItem  `json:"items,omitempty"`
+	SharedWith  []CollectionShare `json:"shared_with,omitempty"`
+}
+
+// CollectionItem is a document of a collection, items are sorted by Position.
+type CollectionItem struct {
+	CollectionID uint   `gorm:"primaryKey;autoIncrement:false" json:"-"`
+	DocumentID   int    `gorm:"primaryKey;autoIncrement:false;index" json:"document_id"`
+	Position     int    `json:"position"`
+	Note         string `json:"note,omitempty"`
+}
+
+// CollectionShare grants a user access to a shared collection.
+type CollectionShare struct {
+	CollectionID uint `gorm:"primaryKey;autoIncrement:false" json:"-"`
+	UserID       uint `gorm:"primaryKey;autoIncrement:false;index" json:"user_id"`
+}
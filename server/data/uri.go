@@ -0,0 +1,132 @@
+package data
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"net/url"
+	"strings"
+)
+
+// trackingParams are the query parameters added to links for analytics,
+// they never change the resource a uri points to.
+var trackingParams = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"dclid":   true,
+	"msclkid": true,
+	"yclid":   true,
+	"igshid":  true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"_ga":     true,
+}
+
+// DuplicateDocumentError is returned inserting or updating a document whose
+// uri is already used by DocumentID once normalized.
+type DuplicateDocumentError struct {
+	DocumentID int
+	Uri        string
+}
+
+func (e *DuplicateDocumentError) Error() string {
+	return fmt.Sprintf("document %d already has uri %s", e.DocumentID, e.Uri)
+}
+
+// normalizeUri returns the canonical form of uri, used to detect duplicates.
+// Scheme and host are lowercased, default ports, fragments, tracking
+// parameters and trailing slashes are removed and the query is sorted.
+func normalizeUri(uri string) string {
+	uri = strings.TrimSpace(uri)
+
+	parsed, err := url.Parse(uri)
+	if err != nil || len(parsed.Scheme) == 0 {
+		return uri
+	}
+
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	parsed.Fragment = ""
+	parsed.RawFragment = ""
+	if len(parsed.Opaque) != 0 {
+		return parsed.String()
+	}
+
+	host := strings.ToLower(parsed.Host)
+	if (parsed.Scheme == "http" && strings.HasSuffix(host, ":80")) ||
+		(parsed.Scheme == "https" && strings.HasSuffix(host, ":443")) {
+		host = host[:strings.LastIndex(host, ":")]
+	}
+	parsed.Host = host
+
+	parsed.Path = strings.TrimRight(parsed.Path, "/")
+	parsed.RawPath = strings.TrimRight(parsed.RawPath, "/")
+
+	query := parsed.Query()
+	for param := range query {
+		if trackingParams[strings.ToLower(param)] || strings.HasPrefix(strings.ToLower(param), "utm_") {
+			query.Del(param)
+		}
+	}
+	parsed.RawQuery = query.Encode()
+	parsed.ForceQuery = false
+
+	return parsed.String()
+}
+
+// uriKey is the value of the unique index on the normalized uris, hashed to
+// fit the index size limits of every database.
+func uriKey(uri *string) *string {
+	// documents without uri never conflict
+	if uri == nil || len(strings.TrimSpace(*uri)) == 0 {
+		return nil
+	}
+
+	sum := sha256.Sum256([]byte(normalizeUri(*uri)))
+	key := hex.EncodeToString(sum[:])
+	return &key
+}
+
+// checkDuplicateUri fails with a DuplicateDocumentError when a document other
+// than documentID already uses uri.
+func checkDuplicateUri(tx *gorm.DB, uri *string, documentID int) error {
+	key := uriKey(uri)
+	if key == nil {
+		return nil
+	}
+
+	var ids []int
+	err := tx.Model(&Document{}).Where("uri_key = ? AND id <> ?", *key, documentID).Limit(1).Pluck("id", &ids).Error
+	if err != nil {
+		return err
+	}
+	if len(ids) != 0 {
+		return &DuplicateDocumentError{DocumentID: ids[0], Uri: *uri}
+	}
+	return nil
+}
+
+// initUriKeys fills the uri keys of the documents created by older versions,
+// the duplicates of an older document are left without a key.
+func initUriKeys(db *gorm.DB) error {
+	var documents []Document
+	err := db.Select("id", "uri").Where("uri_key IS NULL").FindInBatches(&documents, 500, func(tx *gorm.DB, batch int) error {
+		for _, document := range documents {
+			if err := checkDuplicateUri(db, document.Uri, document.ID); err != nil {
+				var duplicate *DuplicateDocumentError
+				if errors.As(err, &duplicate) {
+					continue
+				}
+				return err
+			}
+
+			err := db.Model(&Document{}).Where("id = ?", document.ID).UpdateColumn("uri_key", uriKey(document.Uri)).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+	return err
+}
@@ -0,0 +1,26 @@
+package data
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestNormalizeUri(t *testing.T) {
+	tests := []struct {
+		uri        string
+		normalized string
+	}{
+		{uri: "HTTPS://Example.COM/Path/", normalized: "https://example.com/Path"},
+		{uri: "https://example.com:443/", normalized: "https://example.com"},
+		{uri: "http://example.com:8080/a", normalized: "http://example.com:8080/a"},
+		{uri: "https://example.com/a?utm_source=x&b=2&a=1&fbclid=y#section", normalized: "https://example.com/a?a=1&b=2"},
+		{uri: "https://example.com/a?", normalized: "https://example.com/a"},
+		{uri: " file://notes.txt ", normalized: "file://notes.txt"},
+		{uri: "MAILTO:someone@example.com", normalized: "mailto:someone@example.com"},
+		{uri: "relative/path/", normalized: "relative/path/"},
+	}
+
+	for _, test := range tests {
+		require.Equal(t, test.normalized, normalizeUri(test.uri), test.uri)
+	}
+}
@@ -0,0 +1,84 @@
+package extract
+
+import (
+	"context"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"io"
+	"strings"
+)
+
+var htmlSkipped = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Template: true,
+	atom.Svg:      true,
+	atom.Iframe:   true,
+}
+
+var htmlBlocks = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Aside: true, atom.Blockquote: true,
+	atom.Br: true, atom.Dd: true, atom.Div: true, atom.Dl: true, atom.Dt: true,
+	atom.Figcaption: true, atom.Footer: true, atom.Form: true, atom.H1: true,
+	atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Header: true, atom.Hr: true, atom.Li: true, atom.Main: true, atom.Nav: true,
+	atom.Ol: true, atom.P: true, atom.Pre: true, atom.Section: true, atom.Table: true,
+	atom.Td: true, atom.Th: true, atom.Title: true, atom.Tr: true, atom.Ul: true,
+}
+
+// HTML extracts the visible text of a page, separating block elements with new lines.
+func HTML(ctx context.Context, content io.Reader) (string, error) {
+	tokenizer := html.NewTokenizer(limitReader(ctx, content))
+
+	var (
+		lines   []string
+		line    []string
+		skipped int
+	)
+
+	flush := func() {
+		if len(line) != 0 {
+			lines = append(lines, strings.Join(line, " "))
+			line = nil
+		}
+	}
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			if err := tokenizer.Err(); err != io.EOF {
+				return "", err
+			}
+			flush()
+			return strings.Join(lines, "\n"), nil
+		case html.StartTagToken:
+			name, _ := tokenizer.TagName()
+			tag := atom.Lookup(name)
+			if htmlSkipped[tag] {
+				skipped++
+			}
+			if htmlBlocks[tag] {
+				flush()
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			tag := atom.Lookup(name)
+			if htmlSkipped[tag] && skipped > 0 {
+				skipped--
+			}
+			if htmlBlocks[tag] {
+				flush()
+			}
+		case html.SelfClosingTagToken:
+			name, _ := tokenizer.TagName()
+			if htmlBlocks[atom.Lookup(name)] {
+				flush()
+			}
+		case html.TextToken:
+			if skipped == 0 {
+				line = append(line, strings.Fields(string(tokenizer.Text()))...)
+			}
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package extract
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestHTML(t *testing.T) {
+	page := `<!DOCTYPE html>
+<html>
+<head>
+	<title>Time, Clocks</title>
+	<style>body { color: red; }</style>
+	<script>console.log("hidden")</script>
+</head>
+<body>
+	<h1>Time,   Clocks and the <em>Ordering</em> of Events</h1>
+	<p>The concept of time<br/>is fundamental.</p>
+	<ul><li>one</li><li>two</li></ul>
+	<noscript>enable javascript</noscript>
+</body>
+</html>`
+
+	text, err := HTML(context.TODO(), strings.NewReader(page))
+	require.NoError(t, err)
+	require.Equal(t, "Time, Clocks\n"+
+		"Time, Clocks and the Ordering of Events\n"+
+		"The concept of time\n"+
+		"is fundamental.\n"+
+		"one\n"+
+		"two", text)
+}
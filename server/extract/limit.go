@@ -0,0 +1,61 @@
+package extract
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// DefaultMaxSize is the maximum size of the content read by the extractors
+// when none is configured, see Registry.SetMaxSize.
+const DefaultMaxSize = 64 << 20
+
+var ErrContentTooLarge = errors.New("content exceeds the maximum extraction size")
+
+type maxSizeKey struct{}
+
+// WithMaxSize bounds the data read by the extractors called with the
+// returned context, decompressed data included.
+func WithMaxSize(ctx context.Context, size int64) context.Context {
+	return context.WithValue(ctx, maxSizeKey{}, size)
+}
+
+func maxSize(ctx context.Context) int64 {
+	if size, ok := ctx.Value(maxSizeKey{}).(int64); ok && size > 0 {
+		return size
+	}
+	return DefaultMaxSize
+}
+
+// sizeLimitedReader fails with ErrContentTooLarge once more than remaining
+// bytes are read, unlike io.LimitReader which silently truncates.
+type sizeLimitedReader struct {
+	reader    io.Reader
+	remaining int64
+}
+
+func (l *sizeLimitedReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, ErrContentTooLarge
+	}
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+
+	n, err := l.reader.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n, ErrContentTooLarge
+	}
+	return n, err
+}
+
+// limitReader bounds content to the maximum size of ctx.
+func limitReader(ctx context.Context, content io.Reader) *sizeLimitedReader {
+	return &sizeLimitedReader{reader: content, remaining: maxSize(ctx)}
+}
+
+// readAll reads the content, failing past the maximum size of ctx.
+func readAll(ctx context.Context, content io.Reader) ([]byte, error) {
+	return io.ReadAll(limitReader(ctx, content))
+}
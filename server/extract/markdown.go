@@ -0,0 +1,51 @@
+package extract
+
+import (
+	"context"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var (
+	markdownImage    = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	markdownLink     = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	markdownRefLink  = regexp.MustCompile(`\[([^\]]*)\]\[[^\]]*\]`)
+	markdownLinkDef  = regexp.MustCompile(`^\s{0,3}\[[^\]]+\]:\s+\S+.*$`)
+	markdownEmphasis = regexp.MustCompile("(\\*{1,3}|_{1,3}|~~|`+)")
+	markdownHeading  = regexp.MustCompile(`^\s{0,3}#{1,6}\s+`)
+	markdownList     = regexp.MustCompile(`^\s*([-*+]|\d+[.)])\s+`)
+	markdownQuote    = regexp.MustCompile(`^\s*(>\s?)+`)
+	markdownRule     = regexp.MustCompile(`^\s{0,3}([-*_]\s*){3,}$`)
+	markdownFence    = regexp.MustCompile("^\\s{0,3}(```|~~~)")
+)
+
+// Markdown strips the Markdown syntax, keeping the text of links and images.
+func Markdown(ctx context.Context, content io.Reader) (string, error) {
+	text, err := Text(ctx, content)
+	if err != nil {
+		return "", err
+	}
+
+	lines := strings.Split(text, "\n")
+	result := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.TrimRight(line, "\r")
+
+		if markdownFence.MatchString(line) || markdownRule.MatchString(line) || markdownLinkDef.MatchString(line) {
+			continue
+		}
+
+		line = markdownHeading.ReplaceAllString(line, "")
+		line = markdownQuote.ReplaceAllString(line, "")
+		line = markdownList.ReplaceAllString(line, "")
+		line = markdownImage.ReplaceAllString(line, "$1")
+		line = markdownLink.ReplaceAllString(line, "$1")
+		line = markdownRefLink.ReplaceAllString(line, "$1")
+		line = markdownEmphasis.ReplaceAllString(line, "")
+
+		result = append(result, strings.TrimSpace(line))
+	}
+
+	return strings.TrimSpace(strings.Join(result, "\n")), nil
+}
@@ -0,0 +1,31 @@
+package extract
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestMarkdown(t *testing.T) {
+	markdown := "# Time, Clocks\n" +
+		"\n" +
+		"> The concept of *time* is **fundamental**.\n" +
+		"\n" +
+		"- see [the paper](https://lamport.azurewebsites.net/pubs/time-clocks.pdf)\n" +
+		"1. ![diagram](diagram.png) of `happened before`\n" +
+		"\n" +
+		"---\n" +
+		"```go\n" +
+		"fmt.Println(clock)\n" +
+		"```\n" +
+		"[ref]: https://example.com\n"
+
+	text, err := Markdown(context.TODO(), strings.NewReader(markdown))
+	require.NoError(t, err)
+	require.Equal(t, "Time, Clocks\n\n"+
+		"The concept of time is fundamental.\n\n"+
+		"see the paper\n"+
+		"diagram of happened before\n\n"+
+		"fmt.Println(clock)", text)
+}
@@ -0,0 +1,154 @@
+package extract
+
+import (
+	"context"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"io"
+	"strings"
+)
+
+// Metadata is the bibliographic information declared by a web page.
+type Metadata struct {
+	Title   string
+	Authors []string
+	Tags    []string
+}
+
+// htmlTitleSources lists the meta tags holding the title, by decreasing priority.
+var htmlTitleSources = []string{"citation_title", "dc.title", "dcterms.title", "og:title", "twitter:title"}
+
+var htmlAuthorSources = map[string]bool{
+	"citation_author": true,
+	"dc.creator":      true,
+	"dcterms.creator": true,
+	"author":          true,
+	"article:author":  true,
+}
+
+var htmlTagSources = map[string]bool{
+	"citation_keywords": true,
+	"dc.subject":        true,
+	"dcterms.subject":   true,
+	"article:tag":       true,
+	"keywords":          true,
+}
+
+// HTMLMetadata reads the title, authors and tags of a page from its <title>,
+// OpenGraph, Highwire Press (citation_*) and Dublin Core meta tags.
+func HTMLMetadata(_ context.Context, content io.Reader) (Metadata, error) {
+	tokenizer := html.NewTokenizer(content)
+
+	var (
+		metadata Metadata
+		titles   = make(map[string]string)
+		inTitle  bool
+		title    strings.Builder
+	)
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			if err := tokenizer.Err(); err != io.EOF {
+				return Metadata{}, err
+			}
+
+			titles["title"] = strings.Join(strings.Fields(title.String()), " ")
+			for _, source := range append(htmlTitleSources, "title") {
+				if len(titles[source]) != 0 {
+					metadata.Title = titles[source]
+					break
+				}
+			}
+			metadata.Authors = uniqueValues(metadata.Authors)
+			metadata.Tags = uniqueValues(metadata.Tags)
+			return metadata, nil
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttributes := tokenizer.TagName()
+			switch atom.Lookup(name) {
+			case atom.Title:
+				inTitle = true
+			case atom.Meta:
+				if !hasAttributes {
+					continue
+				}
+				key, value := htmlMetaAttributes(tokenizer)
+				value = strings.Join(strings.Fields(value), " ")
+				if len(value) == 0 {
+					continue
+				}
+
+				switch {
+				case htmlAuthorSources[key]:
+					// article:author usually links to a profile page
+					if !strings.HasPrefix(value, "http://") && !strings.HasPrefix(value, "https://") {
+						metadata.Authors = append(metadata.Authors, value)
+					}
+				case htmlTagSources[key]:
+					metadata.Tags = append(metadata.Tags, splitKeywords(value)...)
+				default:
+					if _, present := titles[key]; !present {
+						titles[key] = value
+					}
+				}
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			if atom.Lookup(name) == atom.Title {
+				inTitle = false
+			}
+		case html.TextToken:
+			if inTitle && title.Len() == 0 {
+				title.Write(tokenizer.Text())
+			}
+		}
+	}
+}
+
+// htmlMetaAttributes returns the lower case name (or property) and the content of a meta tag.
+func htmlMetaAttributes(tokenizer *html.Tokenizer) (string, string) {
+	var key, value string
+	for {
+		name, attributeValue, more := tokenizer.TagAttr()
+		switch string(name) {
+		case "name", "property":
+			if len(key) == 0 {
+				key = strings.ToLower(strings.TrimSpace(string(attributeValue)))
+			}
+		case "content":
+			value = string(attributeValue)
+		}
+		if !more {
+			return key, value
+		}
+	}
+}
+
+func splitKeywords(value string) []string {
+	separator := ","
+	if strings.Contains(value, ";") {
+		separator = ";"
+	}
+
+	var keywords []string
+	for _, keyword := range strings.Split(value, separator) {
+		if keyword = strings.TrimSpace(keyword); len(keyword) != 0 {
+			keywords = append(keywords, keyword)
+		}
+	}
+	return keywords
+}
+
+func uniqueValues(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var unique []string
+	for _, value := range values {
+		key := strings.ToLower(value)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, value)
+	}
+	return unique
+}
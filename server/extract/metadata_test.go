@@ -0,0 +1,45 @@
+package extract
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestHTMLMetadata(t *testing.T) {
+	page := `<!DOCTYPE html>
+<html>
+<head>
+	<title>
+		Paxos Made Simple | Site
+	</title>
+	<meta property="og:title" content="Paxos Made Simple (OpenGraph)">
+	<meta name="citation_title" content="Paxos Made Simple">
+	<meta name="citation_author" content="Lamport, Leslie">
+	<meta name="DC.Creator" content="lamport, leslie">
+	<meta property="article:author" content="https://example.com/lamport">
+	<meta name="citation_keywords" content="consensus; distributed systems">
+	<meta property="article:tag" content="Consensus">
+	<meta name="keywords" content="paxos, fault tolerance">
+	<meta name="description" content="">
+</head>
+<body><h1>Paxos Made Simple</h1></body>
+</html>`
+
+	metadata, err := HTMLMetadata(context.TODO(), strings.NewReader(page))
+	require.NoError(t, err)
+	require.Equal(t, Metadata{
+		Title:   "Paxos Made Simple",
+		Authors: []string{"Lamport, Leslie"},
+		Tags:    []string{"consensus", "distributed systems", "paxos", "fault tolerance"},
+	}, metadata)
+
+	metadata, err = HTMLMetadata(context.TODO(), strings.NewReader(`<html><head><title>Only  a title</title><meta property="og:title" content="OpenGraph title"></head></html>`))
+	require.NoError(t, err)
+	require.Equal(t, Metadata{Title: "OpenGraph title"}, metadata)
+
+	metadata, err = HTMLMetadata(context.TODO(), strings.NewReader(`<html><head><title>Only  a title</title></head></html>`))
+	require.NoError(t, err)
+	require.Equal(t, Metadata{Title: "Only a title"}, metadata)
+}
@@ -0,0 +1,443 @@
+package extract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	pdfStream        = regexp.MustCompile(`>>\s*stream\r?\n`)
+	pdfFlateFilter   = regexp.MustCompile(`/Filter\s*(\[\s*)?/FlateDecode`)
+	pdfSkippedStream = regexp.MustCompile(`/Subtype\s*/Image|/Type\s*/(XRef|ObjStm|Metadata|XObject)|/Length[123]\b`)
+	pdfOtherFilter   = regexp.MustCompile(`/Filter\s*(\[\s*)?/(DCTDecode|JPXDecode|CCITTFaxDecode|JBIG2Decode|LZWDecode|RunLengthDecode|ASCII85Decode|ASCIIHexDecode)`)
+)
+
+// pdfMaxNesting is the maximum depth of the arrays of a content stream, the
+// documents seen in practice never nest them
+const pdfMaxNesting = 32
+
+var (
+	errNotPDF     = errors.New("content is not a pdf document")
+	errPDFNesting = errors.New("pdf content stream arrays are nested too deeply")
+)
+
+// PDF extracts the text drawn by the content streams of a document. Only
+// uncompressed and FlateDecode streams using simple font encodings are
+// supported, which covers the documents produced by most tools.
+func PDF(ctx context.Context, content io.Reader) (string, error) {
+	data, err := readAll(ctx, content)
+	if err != nil {
+		return "", err
+	}
+
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("%PDF-")) {
+		return "", errNotPDF
+	}
+
+	var text []string
+	// the streams share the size limit, their inflated size is unrelated to the document size
+	inflated := maxSize(ctx)
+	for _, match := range pdfStream.FindAllIndex(data, -1) {
+		// the dictionary of the stream starts after the object header
+		dictionary := data[:match[0]]
+		if header := bytes.LastIndex(dictionary, []byte("obj")); header != -1 {
+			dictionary = dictionary[header:]
+		}
+		start := match[1]
+		end := bytes.Index(data[start:], []byte("endstream"))
+		if end == -1 {
+			continue
+		}
+		stream := data[start : start+end]
+
+		if pdfSkippedStream.Match(dictionary) || pdfOtherFilter.Match(dictionary) {
+			continue
+		}
+
+		if pdfFlateFilter.Match(dictionary) {
+			reader, err := zlib.NewReader(bytes.NewReader(stream))
+			if err != nil {
+				continue
+			}
+			limited := &sizeLimitedReader{reader: reader, remaining: inflated}
+			// truncated streams still yield the content decoded so far
+			stream, err = io.ReadAll(limited)
+			reader.Close()
+			if errors.Is(err, ErrContentTooLarge) {
+				return "", err
+			}
+			inflated = limited.remaining
+		}
+
+		extracted, err := pdfContentText(stream)
+		if err != nil {
+			return "", err
+		}
+		if extracted = strings.TrimSpace(extracted); len(extracted) != 0 {
+			text = append(text, extracted)
+		}
+	}
+
+	return strings.Join(text, "\n"), nil
+}
+
+// pdfContentText interprets the text showing operators of a content stream.
+func pdfContentText(stream []byte) (string, error) {
+	var (
+		text     strings.Builder
+		operands []interface{}
+		scanner  = pdfScanner{data: stream}
+	)
+
+	for {
+		token, ok := scanner.next()
+		if scanner.err != nil {
+			return "", scanner.err
+		}
+		if !ok {
+			break
+		}
+
+		operator, isOperator := token.(pdfOperator)
+		if !isOperator {
+			operands = append(operands, token)
+			continue
+		}
+
+		switch operator {
+		case "Tj":
+			writePDFOperands(&text, operands)
+		case "'", "\"":
+			text.WriteString("\n")
+			writePDFOperands(&text, operands)
+		case "TJ":
+			for _, operand := range operands {
+				array, ok := operand.([]interface{})
+				if !ok {
+					continue
+				}
+				for _, element := range array {
+					switch value := element.(type) {
+					case string:
+						text.WriteString(value)
+					case float64:
+						// large negative adjustments are used as word spacing
+						if value < -200 {
+							text.WriteString(" ")
+						}
+					}
+				}
+			}
+		case "Td", "TD":
+			if len(operands) == 2 {
+				if ty, ok := operands[1].(float64); ok && ty != 0 {
+					text.WriteString("\n")
+					break
+				}
+			}
+			text.WriteString(" ")
+		case "T*", "ET":
+			text.WriteString("\n")
+		case "Tm":
+			text.WriteString(" ")
+		case "BI":
+			scanner.skipInlineImage()
+		}
+
+		operands = operands[:0]
+	}
+
+	lines := strings.Split(text.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func writePDFOperands(text *strings.Builder, operands []interface{}) {
+	for _, operand := range operands {
+		if value, ok := operand.(string); ok {
+			text.WriteString(value)
+		}
+	}
+}
+
+type pdfOperator string
+
+type pdfName string
+
+type pdfScanner struct {
+	data     []byte
+	position int
+	// depth is the number of arrays being read
+	depth int
+	err   error
+}
+
+func (s *pdfScanner) skipSpaces() {
+	for s.position < len(s.data) {
+		c := s.data[s.position]
+		switch {
+		case c == '%':
+			for s.position < len(s.data) && s.data[s.position] != '\n' && s.data[s.position] != '\r' {
+				s.position++
+			}
+		case isPDFSpace(c):
+			s.position++
+		default:
+			return
+		}
+	}
+}
+
+func (s *pdfScanner) skipInlineImage() {
+	end := bytes.Index(s.data[s.position:], []byte("EI"))
+	if end == -1 {
+		s.position = len(s.data)
+		return
+	}
+	s.position += end + 2
+}
+
+// next returns the next object of the stream: strings, numbers, names,
+// arrays or operators, dictionaries are skipped.
+func (s *pdfScanner) next() (interface{}, bool) {
+	for {
+		s.skipSpaces()
+		if s.position >= len(s.data) {
+			return nil, false
+		}
+
+		c := s.data[s.position]
+		switch {
+		case c == '(':
+			return s.literalString(), true
+		case c == '<' && s.position+1 < len(s.data) && s.data[s.position+1] == '<':
+			s.skipDictionary()
+		case c == '<':
+			return s.hexString(), true
+		case c == '[':
+			return s.array()
+		case c == '/':
+			s.position++
+			return pdfName(s.regular()), true
+		case c == ']' || c == '>' || c == ')' || c == '{' || c == '}':
+			s.position++
+		default:
+			token := s.regular()
+			if len(token) == 0 {
+				s.position++
+				continue
+			}
+			if number, err := strconv.ParseFloat(token, 64); err == nil {
+				return number, true
+			}
+			return pdfOperator(token), true
+		}
+	}
+}
+
+// array reads the elements of an array, failing past pdfMaxNesting nested
+// arrays to bound the recursion.
+func (s *pdfScanner) array() (interface{}, bool) {
+	if s.depth >= pdfMaxNesting {
+		s.err = errPDFNesting
+		return nil, false
+	}
+	s.depth++
+	defer func() {
+		s.depth--
+	}()
+
+	s.position++
+	var array []interface{}
+	for {
+		s.skipSpaces()
+		if s.position >= len(s.data) {
+			return array, true
+		}
+		if s.data[s.position] == ']' {
+			s.position++
+			return array, true
+		}
+		element, ok := s.next()
+		if !ok {
+			return array, s.err == nil
+		}
+		array = append(array, element)
+	}
+}
+
+func (s *pdfScanner) regular() string {
+	start := s.position
+	for s.position < len(s.data) && !isPDFSpace(s.data[s.position]) && !isPDFDelimiter(s.data[s.position]) {
+		s.position++
+	}
+	return string(s.data[start:s.position])
+}
+
+func (s *pdfScanner) skipDictionary() {
+	depth := 0
+	for s.position+1 < len(s.data) {
+		switch {
+		case s.data[s.position] == '<' && s.data[s.position+1] == '<':
+			depth++
+			s.position += 2
+		case s.data[s.position] == '>' && s.data[s.position+1] == '>':
+			depth--
+			s.position += 2
+			if depth == 0 {
+				return
+			}
+		case s.data[s.position] == '(':
+			s.literalString()
+		default:
+			s.position++
+		}
+	}
+	s.position = len(s.data)
+}
+
+func (s *pdfScanner) literalString() string {
+	s.position++
+	var (
+		value []byte
+		depth = 1
+	)
+
+	for s.position < len(s.data) {
+		c := s.data[s.position]
+		s.position++
+
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return pdfDecode(value)
+			}
+		case '\\':
+			if s.position >= len(s.data) {
+				continue
+			}
+			escaped := s.data[s.position]
+			s.position++
+			switch escaped {
+			case 'n':
+				value = append(value, '\n')
+			case 'r':
+				value = append(value, '\r')
+			case 't':
+				value = append(value, '\t')
+			case 'b':
+				value = append(value, '\b')
+			case 'f':
+				value = append(value, '\f')
+			case '\r', '\n':
+				// line continuation
+				if escaped == '\r' && s.position < len(s.data) && s.data[s.position] == '\n' {
+					s.position++
+				}
+			default:
+				if '0' <= escaped && escaped <= '7' {
+					octal := int(escaped - '0')
+					for i := 0; i < 2 && s.position < len(s.data) && '0' <= s.data[s.position] && s.data[s.position] <= '7'; i++ {
+						octal = octal*8 + int(s.data[s.position]-'0')
+						s.position++
+					}
+					value = append(value, byte(octal))
+				} else {
+					value = append(value, escaped)
+				}
+			}
+			continue
+		}
+
+		value = append(value, c)
+	}
+
+	return pdfDecode(value)
+}
+
+func (s *pdfScanner) hexString() string {
+	s.position++
+	var digits []byte
+	for s.position < len(s.data) && s.data[s.position] != '>' {
+		if c := s.data[s.position]; !isPDFSpace(c) {
+			digits = append(digits, c)
+		}
+		s.position++
+	}
+	s.position++
+
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+
+	value := make([]byte, 0, len(digits)/2)
+	for i := 0; i < len(digits); i += 2 {
+		b, err := strconv.ParseUint(string(digits[i:i+2]), 16, 8)
+		if err != nil {
+			return ""
+		}
+		value = append(value, byte(b))
+	}
+
+	return pdfDecode(value)
+}
+
+// pdfDecode converts a string to UTF-8, handling UTF-16BE text and
+// falling back to Latin-1 which matches the common simple font encodings.
+func pdfDecode(value []byte) string {
+	if len(value) >= 2 && value[0] == 0xfe && value[1] == 0xff {
+		return decodeUTF16BE(value[2:])
+	}
+
+	if len(value) >= 2 && len(value)%2 == 0 {
+		wide := true
+		for i := 0; i < len(value); i += 2 {
+			if value[i] != 0 {
+				wide = false
+				break
+			}
+		}
+		if wide {
+			return decodeUTF16BE(value)
+		}
+	}
+
+	runes := make([]rune, len(value))
+	for i, b := range value {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+func decodeUTF16BE(value []byte) string {
+	runes := make([]rune, 0, len(value)/2)
+	for i := 0; i+1 < len(value); i += 2 {
+		r := rune(value[i])<<8 | rune(value[i+1])
+		if 0xd800 <= r && r < 0xdc00 && i+3 < len(value) {
+			low := rune(value[i+2])<<8 | rune(value[i+3])
+			r = 0x10000 + (r-0xd800)<<10 + (low - 0xdc00)
+			i += 2
+		}
+		runes = append(runes, r)
+	}
+	return string(runes)
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) != -1
+}
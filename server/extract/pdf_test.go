@@ -0,0 +1,81 @@
+package extract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+// testPDF builds a document with one object for each of the given streams
+func testPDF(t *testing.T, streams ...string) []byte {
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n")
+	pdf.WriteString("1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	for i, stream := range streams {
+		fmt.Fprintf(&pdf, "%d 0 obj\n%s\nendstream\nendobj\n", i+3, stream)
+	}
+	pdf.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return pdf.Bytes()
+}
+
+func flate(t *testing.T, content string) string {
+	var compressed bytes.Buffer
+	writer := zlib.NewWriter(&compressed)
+	_, err := writer.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	return compressed.String()
+}
+
+func TestPDF(t *testing.T) {
+	plain := "BT /F1 12 Tf 72 712 Td (Time, Clocks \\(1978\\)) Tj 0 -14 Td [(Leslie)-250(Lamport)] TJ ET"
+	compressed := flate(t, "BT /F1 12 Tf 72 700 Td <FEFF004500760065006E0074> Tj T* (caf\\351) Tj ET")
+
+	document := testPDF(t,
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s", len(plain), plain),
+		fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s", len(compressed), compressed),
+		"<< /Type /XObject /Subtype /Image /Length 10 >>\nstream\n(hidden) Tj",
+		"<< /Length 12 /Length1 10 >>\nstream\n(font) Tj",
+	)
+
+	text, err := PDF(context.TODO(), bytes.NewReader(document))
+	require.NoError(t, err)
+	require.Equal(t, "Time, Clocks (1978)\nLeslie Lamport\nEvent\ncafé", text)
+
+	_, err = PDF(context.TODO(), strings.NewReader("not a pdf"))
+	require.Error(t, err)
+}
+
+func TestPDF_Nesting(t *testing.T) {
+	nested := flate(t, strings.Repeat("[", 1<<20))
+	document := testPDF(t, fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s", len(nested), nested))
+
+	_, err := PDF(context.TODO(), bytes.NewReader(document))
+	require.ErrorIs(t, err, errPDFNesting)
+
+	// the skipped tokens must not grow the stack either
+	skipped := flate(t, strings.Repeat("]", 1<<20)+"(end) Tj")
+	document = testPDF(t, fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s", len(skipped), skipped))
+
+	text, err := PDF(context.TODO(), bytes.NewReader(document))
+	require.NoError(t, err)
+	require.Equal(t, "end", text)
+}
+
+func TestPDF_MaxSize(t *testing.T) {
+	bomb := flate(t, strings.Repeat("(a) Tj ", 1<<16))
+	document := testPDF(t, fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s", len(bomb), bomb))
+
+	_, err := PDF(WithMaxSize(context.TODO(), int64(len(document))+1024), bytes.NewReader(document))
+	require.ErrorIs(t, err, ErrContentTooLarge, "the inflated streams must be bounded")
+
+	_, err = PDF(WithMaxSize(context.TODO(), 16), bytes.NewReader(document))
+	require.ErrorIs(t, err, ErrContentTooLarge)
+
+	_, err = PDF(context.TODO(), bytes.NewReader(document))
+	require.NoError(t, err)
+}
@@ -0,0 +1,71 @@
+package extract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+)
+
+var ErrUnsupportedType = errors.New("unsupported content type")
+
+// Extractor turns the content of a document into plain text.
+type Extractor interface {
+	Extract(ctx context.Context, content io.Reader) (string, error)
+}
+
+type ExtractorFunc func(ctx context.Context, content io.Reader) (string, error)
+
+func (f ExtractorFunc) Extract(ctx context.Context, content io.Reader) (string, error) {
+	return f(ctx, content)
+}
+
+// Registry resolves the extractor for a MIME type, ignoring its parameters.
+type Registry struct {
+	extractors map[string]Extractor
+	maxSize    int64
+}
+
+func NewRegistry() *Registry {
+	return &Registry{extractors: make(map[string]Extractor), maxSize: DefaultMaxSize}
+}
+
+// SetMaxSize bounds the data read by the extractors, they fail with
+// ErrContentTooLarge past size bytes.
+func (r *Registry) SetMaxSize(size int64) {
+	r.maxSize = size
+}
+
+// DefaultRegistry returns a registry supporting plain text, Markdown, HTML and PDF.
+func DefaultRegistry() *Registry {
+	registry := NewRegistry()
+	registry.Register("text/plain", ExtractorFunc(Text))
+	registry.Register("text/markdown", ExtractorFunc(Markdown))
+	registry.Register("text/x-markdown", ExtractorFunc(Markdown))
+	registry.Register("text/html", ExtractorFunc(HTML))
+	registry.Register("application/xhtml+xml", ExtractorFunc(HTML))
+	registry.Register("application/pdf", ExtractorFunc(PDF))
+	return registry
+}
+
+func (r *Registry) Register(mimeType string, extractor Extractor) {
+	r.extractors[strings.ToLower(mimeType)] = extractor
+}
+
+func (r *Registry) Lookup(contentType string) (Extractor, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %s", ErrUnsupportedType, contentType, err)
+	}
+
+	extractor, present := r.extractors[mediaType]
+	if !present {
+		return nil, fmt.Errorf("%w %q", ErrUnsupportedType, mediaType)
+	}
+
+	return ExtractorFunc(func(ctx context.Context, content io.Reader) (string, error) {
+		return extractor.Extract(WithMaxSize(ctx, r.maxSize), content)
+	}), nil
+}
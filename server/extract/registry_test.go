@@ -0,0 +1,55 @@
+package extract
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRegistry_Lookup(t *testing.T) {
+	registry := DefaultRegistry()
+
+	for _, contentType := range []string{
+		"text/plain",
+		"text/plain; charset=utf-8",
+		"TEXT/HTML; charset=ISO-8859-1",
+		"text/markdown",
+		"application/pdf",
+	} {
+		_, err := registry.Lookup(contentType)
+		require.NoError(t, err, contentType)
+	}
+
+	_, err := registry.Lookup("image/png")
+	require.ErrorIs(t, err, ErrUnsupportedType)
+
+	_, err = registry.Lookup("")
+	require.ErrorIs(t, err, ErrUnsupportedType)
+
+	registry.Register("image/png", ExtractorFunc(func(ctx context.Context, content io.Reader) (string, error) {
+		return "png", nil
+	}))
+
+	extractor, err := registry.Lookup("image/png")
+	require.NoError(t, err)
+	text, err := extractor.Extract(context.TODO(), strings.NewReader(""))
+	require.NoError(t, err)
+	require.Equal(t, "png", text)
+
+	registry.SetMaxSize(4)
+	extractor, err = registry.Lookup("text/plain")
+	require.NoError(t, err)
+	_, err = extractor.Extract(context.TODO(), strings.NewReader("hello"))
+	require.ErrorIs(t, err, ErrContentTooLarge)
+	text, err = extractor.Extract(context.TODO(), strings.NewReader("hell"))
+	require.NoError(t, err)
+	require.Equal(t, "hell", text)
+}
+
+func TestText(t *testing.T) {
+	text, err := Text(context.TODO(), strings.NewReader("hello \xffworld"))
+	require.NoError(t, err)
+	require.Equal(t, "hello �world", text)
+}
@@ -0,0 +1,17 @@
+package extract
+
+import (
+	"context"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+func Text(ctx context.Context, content io.Reader) (string, error) {
+	data, err := readAll(ctx, content)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.ToValidUTF8(string(data), string(utf8.RuneError)), nil
+}
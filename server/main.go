@@ -6,8 +6,11 @@ import (
 	"flag"
 	"fmt"
 	"github.com/garugaru/knowledge/server/api"
+	"github.com/garugaru/knowledge/server/auth"
 	"github.com/garugaru/knowledge/server/conf"
 	"github.com/garugaru/knowledge/server/data"
+	"github.com/garugaru/knowledge/server/extract"
+	"github.com/garugaru/knowledge/server/storage"
 	"github.com/sirupsen/logrus"
 	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
@@ -17,6 +20,8 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 )
@@ -26,11 +31,17 @@ func main() {
 		gracefulTimeout time.Duration
 		confPath        string
 		profile         bool
+		reindex         bool
+		exportPath      string
+		restorePath     string
 	)
 
 	flag.DurationVar(&gracefulTimeout, "graceful-timeout", time.Second*15, "the duration for which the server gracefully wait for existing connections to finish - e.g. 15s or 1m")
 	flag.StringVar(&confPath, "config", "config.yml", "configuration path default ./config.yml")
 	flag.BoolVar(&profile, "profile", false, "enable pprof server")
+	flag.BoolVar(&reindex, "reindex", false, "rebuild the catalog search index and exit")
+	flag.StringVar(&exportPath, "export", "", "export the catalog to the given .ndjson or .tar.gz file and exit")
+	flag.StringVar(&restorePath, "restore", "", "restore an empty catalog from the given .ndjson or .tar.gz export and exit")
 	flag.Parse()
 
 	ctx := context.Background()
@@ -40,7 +51,7 @@ func main() {
 		log.Fatal(err)
 	}
 
-	catalog, err := createCatalog(config.Catalog)
+	catalog, err := createCatalog(config)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -49,12 +60,113 @@ func main() {
 		log.Fatal(err)
 	}
 
+	if reindex {
+		if err := catalog.Reindex(ctx); err != nil {
+			log.Fatal(err)
+		}
+		log.Println("search index rebuilt")
+		return
+	}
+
+	if len(exportPath) != 0 {
+		if err := exportCatalog(ctx, catalog, exportPath); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("catalog exported to %s", exportPath)
+		return
+	}
+
+	if len(restorePath) != 0 {
+		restored, err := restoreCatalog(ctx, catalog, restorePath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("catalog restored from %s: %d documents, %d kinds, %d tags, %d authors, %d contents",
+			restorePath, restored.Documents, restored.Kinds, restored.Tags, restored.Authors, restored.Contents)
+		return
+	}
+
+	authenticator, err := createAuthenticator(config.Auth)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if role := data.Role(config.Auth.DefaultRole); len(role) != 0 && !role.Valid() {
+		log.Fatalf("invalid default role %s", role)
+	}
+
 	apiService := api.New(api.Config{
 		EnableMetrics: true,
+		Authenticator: authenticator,
+		DefaultRole:   data.Role(config.Auth.DefaultRole),
+		Admins:        config.Auth.Admins,
+		MaxUploadSize: config.Storage.MaxSize,
+		UploadTimeout: config.Storage.Timeout,
+		ExportTimeout: config.Catalog.Export.Timeout,
+		ImportTimeout: config.Catalog.Import.Timeout,
+		StreamTimeout: config.Catalog.Events.StreamTimeout,
 	}, catalog)
 
 	apiServer := apiService.Server(api.ServeOpts{})
 
+	backgroundCtx, stopBackground := context.WithCancel(ctx)
+	var background sync.WaitGroup
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		catalog.RunSearchIndexSave(backgroundCtx, data.SearchIndexOptions{Interval: config.Catalog.Search.SaveInterval})
+	}()
+
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		catalog.RunExtraction(backgroundCtx, config.Catalog.Extraction.Workers)
+	}()
+
+	if linkCheck := config.Catalog.LinkCheck; linkCheck.Interval > 0 {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			catalog.RunLinkChecker(backgroundCtx, data.LinkCheckOptions{
+				Interval:  linkCheck.Interval,
+				Workers:   linkCheck.Workers,
+				HostDelay: linkCheck.HostDelay,
+			})
+		}()
+	}
+
+	if duplicates := config.Catalog.Duplicates; duplicates.Interval > 0 {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			catalog.RunDuplicateDetection(backgroundCtx, data.DuplicateOptions{
+				Interval:  duplicates.Interval,
+				Threshold: duplicates.Threshold,
+			})
+		}()
+	}
+
+	if trash := config.Catalog.Trash; trash.Interval > 0 {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			catalog.RunTrashPurge(backgroundCtx, data.TrashOptions{
+				Retention: trash.Retention,
+				Interval:  trash.Interval,
+			})
+		}()
+	}
+
+	// the events are only streamed once sequenced by the relay
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		catalog.RunEventRelay(backgroundCtx, data.EventOptions{
+			Interval:  config.Catalog.Events.Interval,
+			Retention: config.Catalog.Events.Retention,
+		})
+	}()
+
 	go func() {
 		if err := apiServer.ListenAndServe(); err != nil {
 			log.Println(err)
@@ -78,14 +190,91 @@ func main() {
 	if err := apiServer.Shutdown(ctx); err != nil {
 		logrus.Warn(err)
 	}
+
+	stopBackground()
+	background.Wait()
+}
+
+// exportFormat picks the export format from the file extension.
+func exportFormat(path string) data.ExportFormat {
+	if strings.HasSuffix(path, ".tar.gz") || strings.HasSuffix(path, ".tgz") {
+		return data.ExportTarGz
+	}
+	return data.ExportNDJSON
+}
+
+func exportCatalog(ctx context.Context, catalog *data.DBCatalog, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = catalog.ExportCatalog(ctx, data.ExportCatalogRequest{Writer: file, Format: exportFormat(path)})
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func restoreCatalog(ctx context.Context, catalog *data.DBCatalog, path string) (data.RestoreCatalogResponse, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return data.RestoreCatalogResponse{}, err
+	}
+	defer file.Close()
+
+	return catalog.RestoreCatalog(ctx, data.RestoreCatalogRequest{Reader: file, Format: exportFormat(path)})
 }
 
-func createCatalog(catalog conf.Catalog) (data.Catalog, error) {
-	db, err := createDB(catalog.Database)
+func createCatalog(config conf.Conf) (*data.DBCatalog, error) {
+	db, err := createDB(config.Catalog.Database)
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := createSearchOpts(config.Catalog.Search)
+	if err != nil {
+		return nil, err
+	}
+
+	blobStore, err := createBlobStore(config.Storage)
 	if err != nil {
 		return nil, err
 	}
-	return data.NewDBCatalog(db), nil
+
+	if blobStore != nil {
+		opts = append(opts, data.WithBlobStore(blobStore))
+	}
+
+	if config.Catalog.Fetch.AllowPrivate {
+		opts = append(opts, data.WithPrivateFetch())
+	}
+
+	if config.Catalog.Extraction.Workers > 0 {
+		registry := extract.DefaultRegistry()
+		if config.Catalog.Extraction.MaxSize > 0 {
+			registry.SetMaxSize(config.Catalog.Extraction.MaxSize)
+		}
+		opts = append(opts, data.WithExtraction(registry))
+	}
+
+	return data.NewDBCatalog(db, opts...), nil
+}
+
+func createSearchOpts(search conf.Search) ([]data.DBCatalogOption, error) {
+	switch search.Type {
+	case "", conf.SearchTypeDatabase:
+		return nil, nil
+	case conf.SearchTypeEmbedded:
+		indexPath, present := search.Params["index_path"]
+		if !present {
+			return nil, errors.New("index_path parameter must be defined using embedded search")
+		}
+		return []data.DBCatalogOption{data.WithSearchIndex(indexPath.(string))}, nil
+	default:
+		return nil, fmt.Errorf("unknown search type %s", search.Type)
+	}
 }
 
 func createDB(database conf.Database) (*gorm.DB, error) {
@@ -113,6 +302,121 @@ func createDB(database conf.Database) (*gorm.DB, error) {
 	}
 }
 
+func createBlobStore(config conf.Storage) (storage.BlobStore, error) {
+	switch config.Type {
+	case "":
+		return nil, nil
+	case conf.StorageTypeLocal:
+		root, present := config.Params["path"]
+		if !present {
+			return nil, errors.New("path parameter must be defined using local storage")
+		}
+		return storage.NewLocalBlobStore(root.(string)), nil
+	case conf.StorageTypeS3:
+		var s3 storage.S3Config
+		required := map[string]*string{
+			"endpoint":          &s3.Endpoint,
+			"bucket":            &s3.Bucket,
+			"access_key_id":     &s3.AccessKeyID,
+			"secret_access_key": &s3.SecretAccessKey,
+		}
+		for name, value := range required {
+			param, present := config.Params[name]
+			if !present {
+				return nil, fmt.Errorf("%s parameter must be defined using s3 storage", name)
+			}
+			*value = param.(string)
+		}
+		s3.Region, _ = config.Params["region"].(string)
+		s3.PathStyle, _ = config.Params["path_style"].(bool)
+		return storage.NewS3BlobStore(s3)
+	default:
+		return nil, fmt.Errorf("unknown storage type %s", config.Type)
+	}
+}
+
+func createAuthenticator(config conf.Auth) (auth.Authenticator, error) {
+	if len(config.Providers) == 0 {
+		return nil, nil
+	}
+
+	var chain auth.Chain
+	for _, provider := range config.Providers {
+		switch provider.Type {
+		case conf.AuthProviderStatic:
+			tokens, present := provider.Params["tokens"].([]interface{})
+			if !present {
+				return nil, errors.New("tokens parameter must be defined using static auth")
+			}
+
+			var static []auth.StaticToken
+			for _, token := range tokens {
+				params, _ := token.(map[string]interface{})
+				value, _ := params["token"].(string)
+				subject, _ := params["subject"].(string)
+				if len(value) == 0 || len(subject) == 0 {
+					return nil, errors.New("static auth tokens must define token and subject")
+				}
+				// the tokens are granted nothing without scopes, full access must be explicit
+				scopes := stringList(params["scopes"])
+				if len(scopes) == 0 {
+					return nil, fmt.Errorf("static auth token of %s must define scopes", subject)
+				}
+				static = append(static, auth.StaticToken{Token: value, Subject: subject, Scopes: scopes})
+			}
+			chain = append(chain, auth.NewStaticTokens(static...))
+		case conf.AuthProviderJWT:
+			jwtConfig := auth.JWTConfig{Algorithm: auth.AlgorithmHS256}
+			if algorithm, present := provider.Params["algorithm"].(string); present {
+				jwtConfig.Algorithm = algorithm
+			}
+			if secret, present := provider.Params["secret"].(string); present {
+				jwtConfig.Secret = []byte(secret)
+			}
+			jwtConfig.Issuer, _ = provider.Params["issuer"].(string)
+			jwtConfig.Audience, _ = provider.Params["audience"].(string)
+			jwtConfig.DefaultScopes = stringList(provider.Params["default_scopes"])
+			if leeway, present := provider.Params["leeway"].(string); present {
+				var err error
+				if jwtConfig.Leeway, err = time.ParseDuration(leeway); err != nil {
+					return nil, fmt.Errorf("invalid leeway parameter: %w", err)
+				}
+			}
+
+			if jwksFile, present := provider.Params["jwks_file"].(string); present {
+				keys, err := auth.LoadJWKSFile(jwksFile)
+				if err != nil {
+					return nil, err
+				}
+				jwtConfig.Keys = keys
+			} else if jwksURL, present := provider.Params["jwks_url"].(string); present {
+				jwtConfig.Keys = auth.NewRemoteKeySet(jwksURL)
+			}
+
+			jwt, err := auth.NewJWT(jwtConfig)
+			if err != nil {
+				return nil, err
+			}
+			chain = append(chain, jwt)
+		default:
+			return nil, fmt.Errorf("unknown auth provider type %s", provider.Type)
+		}
+	}
+
+	return chain, nil
+}
+
+func stringList(value interface{}) []string {
+	values, _ := value.([]interface{})
+	list := make([]string, 0, len(values))
+	for _, value := range values {
+		if s, ok := value.(string); ok {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
 func serveProfiler() {
 	go func() {
 		log.Println(http.ListenAndServe("localhost:6060", nil))
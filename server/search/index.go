@@ -0,0 +1,423 @@
+package search
+
+import (
+	"encoding/gob"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// ErrFieldsMismatch is returned by Load when the persisted index was built
+// with different fields and must be rebuilt.
+var ErrFieldsMismatch = errors.New("index fields do not match the persisted index")
+
+// ErrStale is returned by Load when the changes made after the last save were
+// lost, the persisted index must be rebuilt.
+var ErrStale = errors.New("the persisted index is missing changes")
+
+type Field struct {
+	Name   string
+	Weight float64
+}
+
+type Document struct {
+	ID     int
+	Fields map[string]string
+}
+
+type Hit struct {
+	ID      int
+	Score   float64
+	Snippet string
+}
+
+// Index is an in-memory inverted index scored with BM25F, it can be
+// persisted to and loaded from a single file. A marker file next to it
+// records the changes not saved yet, see ErrStale.
+type Index struct {
+	mu     sync.RWMutex
+	path   string
+	fields []Field
+	data   indexData
+	// terms is the sorted vocabulary, used for prefix lookups
+	terms []string
+
+	// version counts the changes, saved is the version last saved
+	version uint64
+	saved   uint64
+	// marked is set once the marker of the changes is written
+	marked bool
+	saveMu sync.Mutex
+}
+
+type indexData struct {
+	// Fields are the names of the indexed fields, in the order of the frequencies
+	Fields []string
+	// Postings maps every term to the documents containing it and the
+	// term frequency for each field
+	Postings  map[string]map[int][]int
+	Documents map[int]indexedDocument
+	// TotalLength is the sum of the weighted lengths of all the documents
+	TotalLength float64
+}
+
+type indexedDocument struct {
+	Length  float64
+	Terms   []string
+	Content string
+}
+
+func NewIndex(path string, fields ...Field) *Index {
+	index := &Index{path: path, fields: fields}
+	index.reset()
+	return index
+}
+
+func (i *Index) Reset() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.reset()
+	i.changed()
+}
+
+func (i *Index) reset() {
+	fields := make([]string, len(i.fields))
+	for f, field := range i.fields {
+		fields[f] = field.Name
+	}
+
+	i.data = indexData{
+		Fields:    fields,
+		Postings:  make(map[string]map[int][]int),
+		Documents: make(map[int]indexedDocument),
+	}
+	i.terms = nil
+}
+
+func (i *Index) markerPath() string {
+	return i.path + ".dirty"
+}
+
+// changed records a change not saved yet, the marker is written on the first
+// one and retried on the next ones when it fails.
+func (i *Index) changed() {
+	i.version++
+	if !i.marked {
+		i.marked = os.WriteFile(i.markerPath(), nil, 0o644) == nil
+	}
+}
+
+func (i *Index) Len() int {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return len(i.data.Documents)
+}
+
+func (i *Index) Add(document Document) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.remove(document.ID)
+
+	frequencies := make(map[string][]int)
+	var (
+		length  float64
+		content []string
+	)
+	for f, field := range i.fields {
+		text := document.Fields[field.Name]
+		if len(text) == 0 {
+			continue
+		}
+		content = append(content, text)
+
+		terms := Tokenize(text)
+		length += field.Weight * float64(len(terms))
+		for _, term := range terms {
+			if _, present := frequencies[term]; !present {
+				frequencies[term] = make([]int, len(i.fields))
+			}
+			frequencies[term][f]++
+		}
+	}
+
+	terms := make([]string, 0, len(frequencies))
+	for term, frequency := range frequencies {
+		postings, present := i.data.Postings[term]
+		if !present {
+			postings = make(map[int][]int)
+			i.data.Postings[term] = postings
+			i.insertTerm(term)
+		}
+		postings[document.ID] = frequency
+		terms = append(terms, term)
+	}
+
+	i.data.Documents[document.ID] = indexedDocument{
+		Length:  length,
+		Terms:   terms,
+		Content: strings.Join(content, " "),
+	}
+	i.data.TotalLength += length
+	i.changed()
+}
+
+func (i *Index) Remove(id int) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.remove(id)
+	i.changed()
+}
+
+func (i *Index) remove(id int) {
+	document, present := i.data.Documents[id]
+	if !present {
+		return
+	}
+
+	for _, term := range document.Terms {
+		postings := i.data.Postings[term]
+		delete(postings, id)
+		if len(postings) == 0 {
+			delete(i.data.Postings, term)
+			i.deleteTerm(term)
+		}
+	}
+
+	delete(i.data.Documents, id)
+	i.data.TotalLength -= document.Length
+}
+
+func (i *Index) insertTerm(term string) {
+	position := sort.SearchStrings(i.terms, term)
+	i.terms = append(i.terms, "")
+	copy(i.terms[position+1:], i.terms[position:])
+	i.terms[position] = term
+}
+
+func (i *Index) deleteTerm(term string) {
+	position := sort.SearchStrings(i.terms, term)
+	if position < len(i.terms) && i.terms[position] == term {
+		i.terms = append(i.terms[:position], i.terms[position+1:]...)
+	}
+}
+
+// Search returns the documents containing every term of the query, matching
+// terms by prefix, sorted by descending score. The snippets are left empty,
+// see Snippets.
+func (i *Index) Search(q string) []Hit {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	terms := Tokenize(q)
+	if len(terms) == 0 || len(i.data.Documents) == 0 {
+		return nil
+	}
+
+	var (
+		count         = float64(len(i.data.Documents))
+		averageLength = i.data.TotalLength / count
+		scores        map[int]float64
+	)
+
+	for _, term := range terms {
+		termScores := make(map[int]float64)
+		for _, expansion := range i.expand(term) {
+			postings := i.data.Postings[expansion]
+			idf := math.Log(1 + (count-float64(len(postings))+0.5)/(float64(len(postings))+0.5))
+			for id, frequency := range postings {
+				var weighted float64
+				for f, field := range i.fields {
+					weighted += field.Weight * float64(frequency[f])
+				}
+				norm := 1 - bm25B
+				if averageLength > 0 {
+					norm += bm25B * i.data.Documents[id].Length / averageLength
+				}
+				termScores[id] += idf * weighted * (bm25K1 + 1) / (weighted + bm25K1*norm)
+			}
+		}
+
+		if scores == nil {
+			scores = termScores
+			continue
+		}
+
+		for id, score := range scores {
+			termScore, present := termScores[id]
+			if !present {
+				delete(scores, id)
+				continue
+			}
+			scores[id] = score + termScore
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{ID: id, Score: score})
+	}
+
+	sort.Slice(hits, func(a, b int) bool {
+		if hits[a].Score != hits[b].Score {
+			return hits[a].Score > hits[b].Score
+		}
+		return hits[a].ID < hits[b].ID
+	})
+
+	return hits
+}
+
+// Snippets fills the snippets of hits highlighting the terms of the query,
+// meant for the page of hits returned rather than every match.
+func (i *Index) Snippets(hits []Hit, q string) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	terms := Tokenize(q)
+	for h := range hits {
+		if document, present := i.data.Documents[hits[h].ID]; present {
+			hits[h].Snippet = Highlight(document.Content, terms, SnippetWords)
+		}
+	}
+}
+
+func (i *Index) expand(prefix string) []string {
+	var expansions []string
+	for position := sort.SearchStrings(i.terms, prefix); position < len(i.terms); position++ {
+		if !strings.HasPrefix(i.terms[position], prefix) {
+			break
+		}
+		expansions = append(expansions, i.terms[position])
+	}
+	return expansions
+}
+
+// Load replaces the index content with the one persisted on disk, returning
+// an error satisfying os.IsNotExist when the index was never saved and
+// ErrStale when changes were not saved before exiting.
+func (i *Index) Load() error {
+	file, err := os.Open(i.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := os.Stat(i.markerPath()); err == nil {
+		return ErrStale
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	var data indexData
+	if err := gob.NewDecoder(file).Decode(&data); err != nil {
+		return err
+	}
+
+	if !i.matchFields(data.Fields) {
+		return ErrFieldsMismatch
+	}
+
+	if data.Postings == nil {
+		data.Postings = make(map[string]map[int][]int)
+	}
+	if data.Documents == nil {
+		data.Documents = make(map[int]indexedDocument)
+	}
+
+	terms := make([]string, 0, len(data.Postings))
+	for term := range data.Postings {
+		terms = append(terms, term)
+	}
+	sort.Strings(terms)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.data = data
+	i.terms = terms
+	i.saved = i.version
+
+	return nil
+}
+
+func (i *Index) matchFields(names []string) bool {
+	if len(names) != len(i.fields) {
+		return false
+	}
+	for f, field := range i.fields {
+		if names[f] != field.Name {
+			return false
+		}
+	}
+	return true
+}
+
+// Save atomically writes the index to disk.
+func (i *Index) Save() error {
+	i.saveMu.Lock()
+	defer i.saveMu.Unlock()
+
+	version, err := i.write()
+	if err != nil {
+		return err
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.saved = version
+	// the changes made while writing are left for the next save
+	if i.version == version && i.marked {
+		if err := os.Remove(i.markerPath()); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		i.marked = false
+	}
+
+	return nil
+}
+
+// Flush saves the index when it changed since the last save.
+func (i *Index) Flush() error {
+	i.mu.RLock()
+	unchanged := i.version == i.saved
+	i.mu.RUnlock()
+
+	if unchanged {
+		return nil
+	}
+	return i.Save()
+}
+
+// write writes the index to disk, returning the version written.
+func (i *Index) write() (uint64, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	tmp, err := os.CreateTemp(filepath.Dir(i.path), filepath.Base(i.path)+".*.tmp")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := gob.NewEncoder(tmp).Encode(i.data); err != nil {
+		tmp.Close()
+		return 0, err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return 0, err
+	}
+
+	return i.version, os.Rename(tmp.Name(), i.path)
+}
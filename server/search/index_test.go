@@ -0,0 +1,122 @@
+package search
+
+import (
+	"github.com/stretchr/testify/require"
+	"os"
+	"path"
+	"testing"
+)
+
+var testFields = []Field{
+	{Name: "title", Weight: 10},
+	{Name: "authors", Weight: 5},
+	{Name: "tags", Weight: 2},
+}
+
+func testIndex(t *testing.T) *Index {
+	index := NewIndex(path.Join(t.TempDir(), "search.idx"), testFields...)
+	index.Add(Document{ID: 1, Fields: map[string]string{
+		"title":   "Time, Clocks, and the Ordering of Events in a Distributed System",
+		"authors": "Leslie Lamport",
+		"tags":    "distributed",
+	}})
+	index.Add(Document{ID: 2, Fields: map[string]string{
+		"title":   "Paxos Made Simple",
+		"authors": "Leslie Lamport",
+		"tags":    "consensus clocks",
+	}})
+	index.Add(Document{ID: 3, Fields: map[string]string{
+		"title":   "The Go Programming Language",
+		"authors": "Alan Donovan",
+	}})
+	return index
+}
+
+func hitIDs(hits []Hit) []int {
+	ids := make([]int, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+	return ids
+}
+
+func TestIndex_Search(t *testing.T) {
+	index := testIndex(t)
+	require.Equal(t, 3, index.Len())
+
+	require.Equal(t, []int{1}, hitIDs(index.Search("lamport clocks ordering")))
+	require.Equal(t, []int{1, 2}, hitIDs(index.Search("clocks")), "title matches must rank first")
+	require.ElementsMatch(t, []int{1, 2}, hitIDs(index.Search("lamp")), "terms must match by prefix")
+	require.Empty(t, index.Search("lamport golang"))
+	require.Empty(t, index.Search("\"*"))
+
+	hits := index.Search("paxos")
+	require.Len(t, hits, 1)
+	require.Empty(t, hits[0].Snippet, "the snippets are built for the returned hits only")
+	index.Snippets(hits, "paxos")
+	require.Equal(t, "<mark>Paxos</mark> Made Simple Leslie Lamport consensus clocks", hits[0].Snippet)
+	require.Greater(t, hits[0].Score, 0.0)
+}
+
+func TestIndex_AddRemove(t *testing.T) {
+	index := testIndex(t)
+
+	index.Add(Document{ID: 3, Fields: map[string]string{"title": "The Rust Programming Language"}})
+	require.Equal(t, 3, index.Len())
+	require.Empty(t, index.Search("go"))
+	require.Equal(t, []int{3}, hitIDs(index.Search("rust")))
+
+	index.Remove(1)
+	require.Equal(t, 2, index.Len())
+	require.Equal(t, []int{2}, hitIDs(index.Search("clocks")))
+	require.Empty(t, index.Search("ordering"))
+
+	index.Remove(1)
+	require.Equal(t, 2, index.Len())
+
+	index.Reset()
+	require.Equal(t, 0, index.Len())
+	require.Empty(t, index.Search("rust"))
+}
+
+func TestIndex_SaveLoad(t *testing.T) {
+	index := testIndex(t)
+
+	loaded := NewIndex(index.path, testFields...)
+	err := loaded.Load()
+	require.True(t, os.IsNotExist(err))
+
+	require.NoError(t, index.Save())
+	require.NoError(t, loaded.Load())
+
+	require.Equal(t, index.Len(), loaded.Len())
+	require.Equal(t, index.Search("lamport"), loaded.Search("lamport"))
+	require.Equal(t, hitIDs(index.Search("lamp")), hitIDs(loaded.Search("lamp")))
+
+	outdated := NewIndex(index.path, testFields[:2]...)
+	require.ErrorIs(t, outdated.Load(), ErrFieldsMismatch)
+}
+
+func TestIndex_Flush(t *testing.T) {
+	index := testIndex(t)
+	require.NoError(t, index.Flush())
+
+	loaded := NewIndex(index.path, testFields...)
+	require.NoError(t, loaded.Load())
+	require.NoError(t, loaded.Flush())
+	require.Equal(t, index.Len(), loaded.Len())
+
+	index.Remove(1)
+	require.ErrorIs(t, loaded.Load(), ErrStale, "the changes not saved are detected")
+
+	require.NoError(t, index.Flush())
+	require.NoError(t, loaded.Load())
+	require.Equal(t, 2, loaded.Len())
+
+	info, err := os.Stat(index.path)
+	require.NoError(t, err)
+	require.NoError(t, index.Flush())
+	flushed, err := os.Stat(index.path)
+	require.NoError(t, err)
+	require.Equal(t, info.ModTime(), flushed.ModTime(), "the unchanged index is not saved again")
+}
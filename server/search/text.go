@@ -0,0 +1,70 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	HighlightStart = "<mark>"
+	HighlightEnd   = "</mark>"
+	SnippetWords   = 16
+)
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
+
+// Tokenize splits text into lower case terms made of letters and numbers.
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), isSeparator)
+}
+
+// Highlight extracts a window of size words around the first word matching
+// one of the terms, wrapping every matching word in highlight marks.
+func Highlight(text string, terms []string, size int) string {
+	words := strings.Fields(text)
+
+	matches := make([]bool, len(words))
+	first := -1
+	for i, word := range words {
+		normalized := strings.ToLower(strings.TrimFunc(word, isSeparator))
+		for _, term := range terms {
+			if len(normalized) != 0 && strings.HasPrefix(normalized, term) {
+				matches[i] = true
+				break
+			}
+		}
+		if matches[i] && first == -1 {
+			first = i
+		}
+	}
+
+	start := 0
+	if first > size/2 {
+		start = first - size/2
+	}
+	end := start + size
+	if end > len(words) {
+		end = len(words)
+	}
+
+	snippet := make([]string, 0, end-start)
+	for i := start; i < end; i++ {
+		if matches[i] {
+			snippet = append(snippet, HighlightStart+words[i]+HighlightEnd)
+		} else {
+			snippet = append(snippet, words[i])
+		}
+	}
+
+	result := strings.Join(snippet, " ")
+	if start > 0 {
+		result = "..." + result
+	}
+	if end < len(words) {
+		result += "..."
+	}
+
+	return result
+}
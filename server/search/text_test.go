@@ -0,0 +1,48 @@
+package search
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	require.Equal(t, []string{"lamport", "clocks", "2"}, Tokenize(`"Lamport" clocks-2`))
+	require.Empty(t, Tokenize(`"*`))
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		terms []string
+		size  int
+		want  string
+	}{
+		{
+			name:  "highlight matching words",
+			text:  "Time, Clocks, and the Ordering of Events",
+			terms: []string{"clock", "events"},
+			size:  16,
+			want:  "Time, <mark>Clocks,</mark> and the Ordering of <mark>Events</mark>",
+		},
+		{
+			name:  "window around first match",
+			text:  "one two three four five six seven eight",
+			terms: []string{"six"},
+			size:  4,
+			want:  "...four five <mark>six</mark> seven...",
+		},
+		{
+			name:  "no match keeps the beginning",
+			text:  "one two three",
+			terms: []string{"four"},
+			size:  2,
+			want:  "one two...",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, Highlight(tt.text, tt.terms, tt.size))
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLocalBlobStore(t *testing.T) {
+	store := NewLocalBlobStore(t.TempDir())
+	ctx := context.TODO()
+
+	_, err := store.Get(ctx, "documents/1/content")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	const content = "hello world"
+	err = store.Put(ctx, "documents/1/content", strings.NewReader(content), int64(len(content)))
+	require.NoError(t, err)
+
+	blob, err := store.Get(ctx, "documents/1/content")
+	require.NoError(t, err)
+
+	_, err = blob.Seek(6, io.SeekStart)
+	require.NoError(t, err)
+	read, err := io.ReadAll(blob)
+	require.NoError(t, err)
+	require.Equal(t, "world", string(read))
+	require.NoError(t, blob.Close())
+
+	err = store.Delete(ctx, "documents/1/content")
+	require.NoError(t, err)
+
+	err = store.Delete(ctx, "documents/1/content")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	err = store.Put(ctx, "../escape", strings.NewReader(content), int64(len(content)))
+	require.Error(t, err)
+}